@@ -0,0 +1,396 @@
+// Command dfg records and verifies tests without running the dfgapi server. It
+// is meant to be used in CI pipelines: a driver command executes the use case
+// between start and stop of the recording or verification run and the exit
+// code of verify tells whether all expectations were fulfilled.
+//
+// Usage:
+//
+//	dfg [-config file] record [-ordering strict|table] [-collapse] [-driver cmd] name
+//	dfg [-config file] verify [-driver cmd] [-format json|junit|markdown|text] name
+//	dfg [-config file] report [-format json|junit|markdown|text] name
+//	dfg [-config file] list
+//	dfg [-config file] show name
+//	dfg [-config file] delete name
+//	dfg [-config file] migrate
+//
+// Without driver command the run lasts until dfg receives SIGINT or SIGTERM.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/export"
+	"github.com/rwirdemann/datafrog/pkg/file"
+	"github.com/rwirdemann/datafrog/pkg/formats"
+	"github.com/rwirdemann/datafrog/pkg/proxy"
+	"github.com/rwirdemann/datafrog/pkg/record"
+	"github.com/rwirdemann/datafrog/pkg/rest"
+	"github.com/rwirdemann/datafrog/pkg/storage"
+	"github.com/rwirdemann/datafrog/pkg/verify"
+)
+
+// Exit codes of dfg.
+const (
+	exitOK     = 0
+	exitFailed = 1 // verification finished with unfulfilled expectations
+	exitError  = 2 // invalid usage or runtime error
+)
+
+// errFailed signals a verification run that didn't pass.
+var errFailed = errors.New("verification failed")
+
+// command represents a dfg subcommand.
+type command struct {
+	usage string
+	run   func(env env, args []string) error
+}
+
+// env bundles the dependencies of all subcommands.
+type env struct {
+	config     df.Config
+	formats    *df.Registry
+	repository df.TestRepository
+	stdout     io.Writer
+	stderr     io.Writer // progress and output of the driver command
+}
+
+var commands = map[string]command{
+	"record":  {usage: "record [-ordering strict|table] [-collapse] [-driver cmd] [-grace duration] name", run: recordTest},
+	"verify":  {usage: "verify [-driver cmd] [-grace duration] [-format json|junit|markdown|text] name", run: verifyTest},
+	"report":  {usage: "report [-format json|junit|markdown|text] name", run: reportTest},
+	"list":    {usage: "list", run: listTests},
+	"show":    {usage: "show name", run: showTest},
+	"delete":  {usage: "delete name", run: deleteTest},
+	"migrate": {usage: "migrate", run: migrateTests},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand given by args and returns the exit code of dfg.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dfg", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "", "config file (default: config.json or config/config.json)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: dfg [-config file] <command> [flags] [args]")
+		for _, name := range []string{"record", "verify", "report", "list", "show", "delete", "migrate"} {
+			_, _ = fmt.Fprintf(stderr, "  dfg %s\n", commands[name].usage)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitError
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "dfg: unknown command '%s'\n", flags.Arg(0))
+		flags.Usage()
+		return exitError
+	}
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "dfg: %v\n", err)
+		return exitError
+	}
+	registry, repository, err := setup(config)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "dfg: %v\n", err)
+		return exitError
+	}
+	if err := registry.Validate(config.Channels); err != nil {
+		_, _ = fmt.Fprintf(stderr, "dfg: %v\n", err)
+		return exitError
+	}
+	e := env{config: config, formats: registry, repository: repository, stdout: stdout, stderr: stderr}
+	if err := cmd.run(e, flags.Args()[1:]); err != nil {
+		if errors.Is(err, errFailed) {
+			return exitFailed
+		}
+		_, _ = fmt.Fprintf(stderr, "dfg: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// setup creates the channel formats and the test repository of config. Tests
+// replace it to run dfg with mocks.
+var setup = func(config df.Config) (*df.Registry, df.TestRepository, error) {
+	repository, err := storage.NewTestRepository(config.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return formats.NewRegistry(), repository, nil
+}
+
+func loadConfig(filename string) (df.Config, error) {
+	if filename == "" {
+		return df.NewDefaultConfig()
+	}
+	return df.NewConfig(filename)
+}
+
+// recordTest records a new test while the driver command runs.
+func recordTest(e env, args []string) error {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	ordering := flags.String("ordering", "", "enforce recorded order during verification: strict | table")
+	collapse := flags.Bool("collapse", false, "record repeated statements that only differ in their values as one expectation")
+	driver := flags.String("driver", "", "command that executes the use case, e.g. \"npx playwright test\"")
+	grace := flags.Duration("grace", time.Second, "time to wait for late log entries after the driver finished")
+	testname, err := parseName(flags, args)
+	if err != nil {
+		return err
+	}
+
+	o, err := df.ParseOrdering(*ordering)
+	if err != nil {
+		return err
+	}
+	if e.repository.Exists(testname) {
+		return fmt.Errorf("test '%s' already exists", testname)
+	}
+	sources, err := e.formats.Sources(e.config.Channels)
+	if err != nil {
+		return err
+	}
+
+	if err := startProxies(e.config.Channels); err != nil {
+		return err
+	}
+
+	runner := record.NewRunner(testname, sources, o, *collapse, e.repository)
+	if err := runner.Start(); err != nil {
+		return err
+	}
+	driverErr := drive(e.stderr, *driver, *grace)
+	if err := runner.Stop(); err != nil {
+		return err
+	}
+	if driverErr != nil {
+		return fmt.Errorf("driver failed: %w", driverErr)
+	}
+	_, err = fmt.Fprintf(e.stdout, "recorded %d expectations\n", len(runner.Testcase().Expectations))
+	return err
+}
+
+// verifyTest verifies an existing test while the driver command runs. Writes
+// the verification report to stdout and returns errFailed if the verification
+// didn't pass.
+func verifyTest(e env, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	driver := flags.String("driver", "", "command that executes the use case, e.g. \"npx playwright test\"")
+	grace := flags.Duration("grace", time.Second, "time to wait for late log entries after the driver finished")
+	format := flags.String("format", "json", "report format: json | junit | markdown | text")
+	testname, err := parseName(flags, args)
+	if err != nil {
+		return err
+	}
+	f, err := export.Lookup(*format)
+	if err != nil {
+		return err
+	}
+
+	if !e.repository.Exists(testname) {
+		return fmt.Errorf("test '%s' not found", testname)
+	}
+	sources, err := e.formats.Sources(e.config.Channels)
+	if err != nil {
+		return err
+	}
+
+	if err := startProxies(e.config.Channels); err != nil {
+		return err
+	}
+
+	runner := verify.NewRunner(testname, sources, e.config, e.repository)
+	if err := runner.Start(); err != nil {
+		return err
+	}
+	driverErr := drive(e.stderr, *driver, *grace)
+	if *driver != "" {
+		outcome := df.DriverPassed
+		if driverErr != nil {
+			outcome = df.DriverFailed
+		}
+		runner.SetDriverOutcome(outcome)
+	}
+	if err := runner.Stop(); err != nil {
+		return err
+	}
+
+	report := runner.Report()
+	if err := f.Write(e.stdout, report); err != nil {
+		return err
+	}
+	if !report.Passed || driverErr != nil {
+		return errFailed
+	}
+	return nil
+}
+
+// reportTest writes the report of the last verification run to stdout and
+// returns errFailed if this run didn't pass.
+func reportTest(e env, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := flags.String("format", "json", "report format: json | junit | markdown | text")
+	testname, err := parseName(flags, args)
+	if err != nil {
+		return err
+	}
+	f, err := export.Lookup(*format)
+	if err != nil {
+		return err
+	}
+	tc, err := e.repository.Get(testname)
+	if err != nil {
+		return err
+	}
+	report := df.NewReport(tc)
+	if err := f.Write(e.stdout, report); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return errFailed
+	}
+	return nil
+}
+
+// listTests writes a table of all tests to stdout.
+func listTests(e env, args []string) error {
+	if len(args) > 0 {
+		return errors.New("list takes no arguments")
+	}
+	all, err := e.repository.All()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tEXPECTATIONS\tFULFILLED\tVERIFICATIONS\tLAST EXECUTION")
+	for _, tc := range all {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", tc.Name, len(tc.Expectations), len(tc.Fulfilled()),
+			tc.Verifications, tc.LastExecution.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// showTest writes the json-encoded test to stdout.
+func showTest(e env, args []string) error {
+	testname, err := parseName(flag.NewFlagSet("show", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	tc, err := e.repository.Get(testname)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tc)
+}
+
+// deleteTest deletes the test.
+func deleteTest(e env, args []string) error {
+	testname, err := parseName(flag.NewFlagSet("delete", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if !e.repository.Exists(testname) {
+		return fmt.Errorf("test '%s' not found", testname)
+	}
+	return e.repository.Delete(testname)
+}
+
+// migrateTests copies the tests stored as json files in the working directory
+// into the configured storage. Tests that already exist in the storage and
+// files that can't be parsed are skipped. The json files are kept untouched.
+func migrateTests(e env, args []string) error {
+	if len(args) > 0 {
+		return errors.New("migrate takes no arguments")
+	}
+	if e.config.Storage.Dir == "" && (e.config.Storage.Type == "" || e.config.Storage.Type == df.StorageJSON) {
+		return errors.New("migrate requires a storage dir or type sqlite in the config")
+	}
+	source := file.JSONTestRepository{}
+	files, err := source.Files()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		tc, err := source.Get(name)
+		if err != nil {
+			_, _ = fmt.Fprintf(e.stdout, "skipped %s: %v\n", name, err)
+			continue
+		}
+		if e.repository.Exists(tc.Name) {
+			_, _ = fmt.Fprintf(e.stdout, "skipped %s: already exists\n", tc.Name)
+			continue
+		}
+		// the revisions start over within the new storage
+		tc.Revision, tc.Change = 0, df.ChangeMigration
+		if err := e.repository.Write(tc.Name, tc); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(e.stdout, "migrated %s\n", tc.Name)
+	}
+	return nil
+}
+
+// parseName parses the flags of a subcommand and returns its only argument, the
+// name of the test.
+func parseName(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if flags.NArg() != 1 {
+		return "", fmt.Errorf("%s requires exactly one test name", flags.Name())
+	}
+	return flags.Arg(0), nil
+}
+
+// startProxies starts the proxies of the proxy and rest channels. They must be
+// running before the driver lets the SUT connect to its database or call other
+// services.
+func startProxies(channels []df.Channel) error {
+	if err := proxy.StartAll(channels); err != nil {
+		return err
+	}
+	return rest.StartAll(channels)
+}
+
+// drive runs the driver command via sh and waits grace afterward to give the
+// channels time to log the last statements. The output of the driver is written
+// to stderr, thus stdout only contains the report. Without driver command drive
+// blocks until SIGINT or SIGTERM is received.
+func drive(stderr io.Writer, driver string, grace time.Duration) error {
+	if driver == "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		_, _ = fmt.Fprintln(stderr, "running, press Ctrl+C to stop...")
+		<-ctx.Done()
+		return nil
+	}
+
+	cmd := exec.Command("sh", "-c", driver)
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	time.Sleep(grace)
+	return err
+}
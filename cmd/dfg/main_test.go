@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/file"
+	"github.com/rwirdemann/datafrog/pkg/mocks"
+	"github.com/rwirdemann/datafrog/pkg/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	insertJob = "2099-01-01T10:00:00.000000Z\t 7 Query\tinsert into job (id, title) values (1, 'Hello')"
+	selectJob = "2099-01-01T10:00:01.000000Z\t 7 Query\tselect * from job where id=1"
+)
+
+// memLogs creates mem logs that return lines.
+type memLogs struct {
+	lines []string
+}
+
+func (f memLogs) Create(df.Channel) (df.Log, error) {
+	return mocks.NewMemSQLLog(f.lines, nil), nil
+}
+
+// createJob returns a test expecting the statements insertJob and selectJob.
+// The expectations are fulfilled if the last verification run passed.
+func createJob(passed bool) df.Testcase {
+	expectation := func(uuid, s, pattern string) df.Expectation {
+		return df.Expectation{Uuid: uuid, Tokens: df.Tokenize(s), IgnoreDiffs: []int{}, Pattern: pattern,
+			Channel: "mysql", Fulfilled: passed}
+	}
+	return df.Testcase{
+		Name:          "create-job",
+		Verifications: 1,
+		Expectations: []df.Expectation{
+			expectation("e1", "insert into job (id, title) values (1, 'Hello')", "insert into job"),
+			expectation("e2", "select * from job where id=1", "select"),
+		},
+	}
+}
+
+// dfg runs dfg with args against repository and a mysql channel that logs
+// lines. Returns the exit code and the output written to stdout.
+func dfg(t *testing.T, repository df.TestRepository, lines []string, args ...string) (int, string) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"channels": [{"name": "mysql", "format": "mem", "patterns": ["insert into job", "select"]}]}`), 0644))
+
+	setup = func(df.Config) (*df.Registry, df.TestRepository, error) {
+		registry := df.NewRegistry()
+		registry.Register("mem", df.Format{LogFactory: memLogs{lines: lines}, Tokenizer: mysql.Tokenizer{}})
+		return registry, repository, nil
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"-config", config}, args...), &stdout, &stderr)
+	t.Log(stderr.String())
+	return code, stdout.String()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		desc   string
+		tests  []df.Testcase
+		lines  []string
+		args   []string
+		code   int
+		stdout string // expected substring of stdout
+	}{
+		{desc: "no command", code: exitError},
+		{desc: "unknown command", args: []string{"run", "create-job"}, code: exitError},
+		{desc: "unknown flag", args: []string{"verify", "-fast", "create-job"}, code: exitError},
+		{desc: "missing name", args: []string{"record", "-driver", "true"}, code: exitError},
+		{desc: "too many names", args: []string{"show", "a", "b"}, code: exitError},
+		{desc: "invalid ordering", args: []string{"record", "-ordering", "random", "create-job"}, code: exitError},
+		{desc: "invalid format", tests: []df.Testcase{createJob(true)}, args: []string{"report", "-format", "html", "create-job"}, code: exitError},
+
+		{desc: "record", lines: []string{insertJob, selectJob}, args: []string{"record", "-driver", "true", "-grace", "100ms", "create-job"},
+			code: exitOK, stdout: "recorded 2 expectations"},
+		{desc: "record existing test", tests: []df.Testcase{createJob(true)}, args: []string{"record", "-driver", "true", "create-job"},
+			code: exitError},
+		{desc: "record with failing driver", args: []string{"record", "-driver", "false", "-grace", "0s", "create-job"},
+			code: exitError},
+
+		{desc: "verify passed", tests: []df.Testcase{createJob(false)}, lines: []string{insertJob, selectJob},
+			args: []string{"verify", "-driver", "true", "-grace", "100ms", "create-job"}, code: exitOK, stdout: `"passed": true`},
+		{desc: "verify unfulfilled", tests: []df.Testcase{createJob(false)}, lines: []string{insertJob},
+			args: []string{"verify", "-driver", "true", "-grace", "100ms", "create-job"}, code: exitFailed, stdout: `"passed": false`},
+		{desc: "verify with failing driver", tests: []df.Testcase{createJob(false)}, lines: []string{insertJob, selectJob},
+			args: []string{"verify", "-driver", "false", "-grace", "100ms", "create-job"}, code: exitFailed},
+		{desc: "verify as junit", tests: []df.Testcase{createJob(false)}, lines: []string{insertJob, selectJob},
+			args: []string{"verify", "-driver", "true", "-grace", "100ms", "-format", "junit", "create-job"}, code: exitOK, stdout: "<testsuite"},
+		{desc: "verify unknown test", args: []string{"verify", "-driver", "true", "create-job"}, code: exitError},
+
+		{desc: "report passed", tests: []df.Testcase{createJob(true)}, args: []string{"report", "create-job"}, code: exitOK, stdout: `"passed": true`},
+		{desc: "report failed", tests: []df.Testcase{createJob(false)}, args: []string{"report", "-format", "text", "create-job"},
+			code: exitFailed, stdout: "Passed: false"},
+		{desc: "report unknown test", args: []string{"report", "create-job"}, code: exitError},
+
+		{desc: "list", tests: []df.Testcase{createJob(true)}, args: []string{"list"}, code: exitOK, stdout: "create-job"},
+		{desc: "list with argument", args: []string{"list", "create-job"}, code: exitError},
+		{desc: "show", tests: []df.Testcase{createJob(true)}, args: []string{"show", "create-job"}, code: exitOK, stdout: `"name": "create-job"`},
+		{desc: "show unknown test", args: []string{"show", "create-job"}, code: exitError},
+		{desc: "delete", tests: []df.Testcase{createJob(true)}, args: []string{"delete", "create-job"}, code: exitOK},
+		{desc: "delete unknown test", args: []string{"delete", "create-job"}, code: exitError},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			repository := &mocks.TestRepository{Testcases: test.tests}
+			code, stdout := dfg(t, repository, test.lines, test.args...)
+			assert.Equal(t, test.code, code)
+			assert.Contains(t, stdout, test.stdout)
+		})
+	}
+}
+
+func TestRunRecordsAndVerifies(t *testing.T) {
+	repository := &mocks.TestRepository{}
+	code, _ := dfg(t, repository, []string{insertJob, selectJob}, "record", "-ordering", "strict", "-driver", "true", "-grace", "100ms", "create-job")
+	require.Equal(t, exitOK, code)
+	tc, err := repository.Get("create-job")
+	require.NoError(t, err)
+	assert.Equal(t, df.OrderingStrict, tc.Ordering)
+	assert.Len(t, tc.Expectations, 2)
+
+	code, stdout := dfg(t, repository, []string{insertJob, selectJob}, "verify", "-driver", "true", "-grace", "100ms", "create-job")
+	assert.Equal(t, exitOK, code)
+	var report df.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	assert.True(t, report.Passed)
+	assert.Equal(t, 1, report.Verifications)
+
+	// wrong order
+	code, _ = dfg(t, repository, []string{selectJob, insertJob}, "verify", "-driver", "true", "-grace", "100ms", "create-job")
+	assert.Equal(t, exitFailed, code)
+
+	code, _ = dfg(t, repository, nil, "delete", "create-job")
+	assert.Equal(t, exitOK, code)
+	assert.False(t, repository.Exists("create-job"))
+}
+
+func TestMigrate(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer func() { _ = os.Chdir(wd) }()
+
+	// json tests of the working directory have been written before, thus their
+	// revision is at least 1
+	require.NoError(t, file.JSONTestRepository{}.Write("create-job", createJob(true)))
+	require.NoError(t, os.WriteFile("broken.json", []byte("{"), 0644))
+	target, err := file.NewJSONTestRepository("tests")
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	e := env{config: df.Config{Storage: df.StorageConfig{Dir: "tests"}}, repository: target, stdout: &stdout}
+	require.NoError(t, migrateTests(e, nil))
+	assert.Equal(t, "skipped broken.json: json: invalid data\nmigrated create-job\n", stdout.String())
+	assert.FileExists(t, "broken.json", "files that can't be parsed aren't quarantined")
+	tc, err := target.Get("create-job")
+	require.NoError(t, err)
+	assert.Equal(t, 1, tc.Revision)
+	assert.Equal(t, df.ChangeMigration, tc.Change)
+
+	stdout.Reset()
+	require.NoError(t, migrateTests(e, nil))
+	assert.Equal(t, "skipped broken.json: json: invalid data\nskipped create-job: already exists\n", stdout.String())
+}
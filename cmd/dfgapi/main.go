@@ -5,7 +5,10 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/rwirdemann/datafrog/pkg/api"
 	"github.com/rwirdemann/datafrog/pkg/df"
-	"github.com/rwirdemann/datafrog/pkg/file"
+	"github.com/rwirdemann/datafrog/pkg/formats"
+	"github.com/rwirdemann/datafrog/pkg/proxy"
+	"github.com/rwirdemann/datafrog/pkg/rest"
+	"github.com/rwirdemann/datafrog/pkg/storage"
 	"log"
 	"net/http"
 )
@@ -16,8 +19,24 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	testRepository := file.JSONTestRepository{}
-	api.RegisterHandler(config, router, testRepository)
+	registry := formats.NewRegistry()
+	if err := registry.Validate(config.Channels); err != nil {
+		log.Fatal(err)
+	}
+
+	// proxies must be running before the SUT connects to its database or calls
+	// other services
+	if err := proxy.StartAll(config.Channels); err != nil {
+		log.Fatal(err)
+	}
+	if err := rest.StartAll(config.Channels); err != nil {
+		log.Fatal(err)
+	}
+	testRepository, err := storage.NewTestRepository(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	api.RegisterHandler(config, router, testRepository, registry)
 	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		tpl, _ := route.GetPathTemplate()
 		met, _ := route.GetMethods()
@@ -2,14 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/rwirdemann/datafrog/pkg/df"
-	"github.com/rwirdemann/datafrog/pkg/mysql"
+	"github.com/rwirdemann/datafrog/pkg/export"
 	"github.com/rwirdemann/datafrog/pkg/record"
 	"github.com/rwirdemann/datafrog/pkg/verify"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,60 +30,109 @@ func (i invalidStateError) Error() string {
 var verifier *verify.Verifier
 var config df.Config
 
-var runners = make(map[string]*record.Runner)
-var verifyRunners = make(map[string]*verify.Runner)
-
-// RegisterHandler registers http handler to record and verify testcases.
-func RegisterHandler(c df.Config, router *mux.Router, testRepository df.TestRepository) {
+// RegisterHandler registers http handler to record and verify testcases. The
+// channel logs are read according to the channel formats registered in formats.
+// All recording and verification sessions are owned by a single Manager.
+func RegisterHandler(c df.Config, router *mux.Router, testRepository df.TestRepository, formats *df.Registry) {
 	config = c
+	manager := NewManager()
 
 	// get all tests
 	router.HandleFunc("/tests", AllTests(testRepository)).Methods("GET")
 
 	// create new test and start recording
 	router.HandleFunc("/tests/{name}/recordings",
-		StartRecording(mysql.LogFactory{}, testRepository)).Methods("POST")
+		StartRecording(formats, testRepository, manager)).Methods("POST")
 
 	// stop recording
-	router.HandleFunc("/tests/{name}/recordings", StopRecording()).Methods("DELETE")
+	router.HandleFunc("/tests/{name}/recordings", StopRecording(manager)).Methods("DELETE")
 
 	// delete test
-	router.HandleFunc("/tests/{name}", DeleteTest(testRepository)).Methods("DELETE")
+	router.HandleFunc("/tests/{name}", DeleteTest(testRepository, manager)).Methods("DELETE")
 
 	// get test
 	router.HandleFunc("/tests/{name}", GetTest(testRepository)).Methods("GET")
 
 	// get recording progress
-	router.HandleFunc("/tests/{name}/recordings/progress", GetRecordingProgress()).Methods("GET")
+	router.HandleFunc("/tests/{name}/recordings/progress", GetRecordingProgress(manager)).Methods("GET")
 
 	// get verification progress
-	router.HandleFunc("/tests/{name}/verifications/progress", GetVerificationProgress()).Methods("GET")
+	router.HandleFunc("/tests/{name}/verifications/progress", GetVerificationProgress(manager)).Methods("GET")
 
 	// start verify
-	router.HandleFunc("/tests/{name}/verifications", StartVerification(mysql.LogFactory{}, testRepository)).Methods("PUT")
+	router.HandleFunc("/tests/{name}/verifications", StartVerification(formats, testRepository, manager)).Methods("PUT")
 
 	// stop verify
-	router.HandleFunc("/tests/{name}/verifications", StopVerify()).Methods("DELETE")
+	router.HandleFunc("/tests/{name}/verifications", StopVerify(manager)).Methods("DELETE")
+
+	// set outcome of the UI driver
+	router.HandleFunc("/tests/{name}/verifications/driver", SetDriverOutcome(testRepository, manager)).Methods("PUT")
+
+	// get report of the last verification run
+	router.HandleFunc("/tests/{name}/report", GetReport(testRepository)).Methods("GET")
+
+	// get verification run history
+	router.HandleFunc("/tests/{name}/runs", GetRuns(testRepository)).Methods("GET")
+
+	// forbid statements
+	router.HandleFunc("/tests/{name}/forbidden", AddForbidden(testRepository, manager)).Methods("POST")
+
+	// allow formerly forbidden statements
+	router.HandleFunc("/tests/{name}/forbidden", RemoveForbidden(testRepository, manager)).Methods("DELETE")
+
+	// add expectation, e.g. promote an additional statement
+	router.HandleFunc("/tests/{name}/expectations", AddExpectation(testRepository, manager)).Methods("POST")
+
+	// reorder expectations
+	router.HandleFunc("/tests/{name}/expectations/order", ReorderExpectations(testRepository, manager)).Methods("PUT")
+
+	// edit expectation
+	router.HandleFunc("/tests/{name}/expectations/{uuid}", EditExpectation(testRepository, manager)).Methods("PATCH")
+
+	// remove expectation
+	router.HandleFunc("/tests/{name}/expectations/{uuid}", RemoveExpectation(testRepository, manager)).Methods("DELETE")
+
+	// accept statements of the last run as new baseline
+	router.HandleFunc("/tests/{name}/baselines", Rebaseline(testRepository, manager)).Methods("POST")
+
+	// restore the former baseline
+	router.HandleFunc("/tests/{name}/baselines/rollback", RollbackBaseline(testRepository, manager)).Methods("POST")
+
+	// list revisions
+	router.HandleFunc("/tests/{name}/revisions", GetRevisions(testRepository)).Methods("GET")
+
+	// get revision
+	router.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}", GetRevision(testRepository)).Methods("GET")
+
+	// diff revision with another or the current revision
+	router.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}/diff", DiffRevision(testRepository)).Methods("GET")
+
+	// roll back to revision
+	router.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}/rollback", RollbackRevision(testRepository, manager)).Methods("POST")
+
+	// list running sessions
+	router.HandleFunc("/sessions", GetSessions(manager)).Methods("GET")
 
 	// channel health
-	router.HandleFunc("/channels/{name}/health", ChannelHealth(mysql.LogFactory{})).Methods("GET")
+	router.HandleFunc("/channels/{name}/health", ChannelHealth(formats)).Methods("GET")
 }
 
-func GetRecordingProgress() http.HandlerFunc {
+func GetRecordingProgress(manager *Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		runner, ok := runners[mux.Vars(r)["name"]]
+		runner, ok := manager.Recording(mux.Vars(r)["name"])
 		if !ok {
 			http.Error(w, invalidStateError{}.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		tc := runner.Testcase()
+		tc := runner.Snapshot()
 		b, err := json.Marshal(tc)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("ETag", etag(tc.Revision))
 		if _, err := w.Write(b); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -88,21 +140,22 @@ func GetRecordingProgress() http.HandlerFunc {
 	}
 }
 
-func GetVerificationProgress() http.HandlerFunc {
+func GetVerificationProgress(manager *Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		runner, ok := verifyRunners[mux.Vars(r)["name"]]
+		runner, ok := manager.Verification(mux.Vars(r)["name"])
 		if !ok {
 			http.Error(w, invalidStateError{}.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		tc := runner.Testcase()
+		tc := runner.Snapshot()
 		b, err := json.Marshal(tc)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("ETag", etag(tc.Revision))
 		if _, err := w.Write(b); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -118,7 +171,7 @@ func GetTest(repository df.TestRepository) http.HandlerFunc {
 		}
 		tc, err := repository.Get(mux.Vars(r)["name"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 		b, err := json.Marshal(tc)
@@ -127,6 +180,7 @@ func GetTest(repository df.TestRepository) http.HandlerFunc {
 			return
 		}
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("ETag", etag(tc.Revision))
 		if _, err := w.Write(b); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -135,13 +189,17 @@ func GetTest(repository df.TestRepository) http.HandlerFunc {
 }
 
 // DeleteTest returns a http handler to delete the test given in the request
-// param "name".
-func DeleteTest(repository df.TestRepository) http.HandlerFunc {
+// param "name". Tests that are being recorded or verified can't be deleted.
+func DeleteTest(repository df.TestRepository, manager *Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if len(mux.Vars(r)["name"]) == 0 {
 			http.Error(w, "name is required", http.StatusBadRequest)
 			return
 		}
+		if state := manager.State(mux.Vars(r)["name"]); state != StateIdle {
+			http.Error(w, fmt.Sprintf("test '%s' is %s", mux.Vars(r)["name"], state), http.StatusConflict)
+			return
+		}
 		if err := repository.Delete(mux.Vars(r)["name"]); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -149,8 +207,12 @@ func DeleteTest(repository df.TestRepository) http.HandlerFunc {
 	}
 }
 
-// StartRecording starts recording of test given the request param "name".
-func StartRecording(logFactory df.LogFactory, repository df.TestRepository) http.HandlerFunc {
+// StartRecording starts recording of test given the request param "name". All
+// configured channels are recorded simultaneously. The optional query param
+// "ordering" (strict | table) enforces the recorded statement order during
+// verification, "collapse=true" records repeated statements that only differ
+// in their literal values as one expectation.
+func StartRecording(formats *df.Registry, repository df.TestRepository, manager *Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if len(mux.Vars(r)["name"]) == 0 {
 			http.Error(w, "name is required", http.StatusBadRequest)
@@ -168,11 +230,29 @@ func StartRecording(logFactory df.LogFactory, repository df.TestRepository) http
 			return
 		}
 
-		runners[testname] = record.NewRunner(testname, config.Channels[0], repository, logFactory)
+		ordering, err := df.ParseOrdering(r.URL.Query().Get("ordering"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		collapse := false
+		if c := r.URL.Query().Get("collapse"); c != "" {
+			if collapse, err = strconv.ParseBool(c); err != nil {
+				http.Error(w, fmt.Sprintf("invalid collapse '%s'", c), http.StatusBadRequest)
+				return
+			}
+		}
+
+		sources, err := formats.Sources(config.Channels)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusFailedDependency)
+			return
+		}
 
 		// Start creates a new go routine
-		if err := runners[testname].Start(); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		runner := record.NewRunner(testname, sources, ordering, collapse, repository)
+		if err := manager.StartRecording(testname, runner); err != nil {
+			writeError(w, err)
 			return
 		}
 
@@ -183,29 +263,17 @@ func StartRecording(logFactory df.LogFactory, repository df.TestRepository) http
 
 // StopRecording returns a http handler to stop the recording of the test given
 // by the request param "name".
-func StopRecording() http.HandlerFunc {
+func StopRecording(manager *Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Println("Recovered:", r)
-				w.WriteHeader(http.StatusNotFound)
-				return
-			}
-		}()
-
 		if len(mux.Vars(r)["name"]) == 0 {
 			http.Error(w, "name is required", http.StatusBadRequest)
 			return
 		}
 
-		testname := mux.Vars(r)["name"]
-		runner, ok := runners[testname]
-		if !ok {
-			http.Error(w, "test is not being recorded", http.StatusNotFound)
+		if err := manager.StopRecording(mux.Vars(r)["name"]); err != nil {
+			writeError(w, err)
 			return
 		}
-		delete(runners, testname)
-		runner.Stop()
 	}
 }
 
@@ -231,8 +299,9 @@ func AllTests(repository df.TestRepository) http.HandlerFunc {
 }
 
 // StartVerification returns a http handler that starts a verification run of the test
-// given in the request param "name".
-func StartVerification(logFactory df.LogFactory, repository df.TestRepository) http.HandlerFunc {
+// given in the request param "name". The expectations of each channel are
+// verified against the log of their channel.
+func StartVerification(formats *df.Registry, repository df.TestRepository, manager *Manager) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		if len(mux.Vars(request)["name"]) == 0 {
 			http.Error(writer, "name is required", http.StatusBadRequest)
@@ -245,11 +314,21 @@ func StartVerification(logFactory df.LogFactory, repository df.TestRepository) h
 		}
 
 		testname := mux.Vars(request)["name"]
-		verifyRunners[testname] = verify.NewRunner(testname, config.Channels[0], config, logFactory, repository)
+		if !repository.Exists(testname) {
+			http.Error(writer, fmt.Sprintf("test '%s' not found", testname), http.StatusNotFound)
+			return
+		}
+
+		sources, err := formats.Sources(config.Channels)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusFailedDependency)
+			return
+		}
 
 		// Start creates a new go routine
-		if err := verifyRunners[testname].Start(); err != nil {
-			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		runner := verify.NewRunner(testname, sources, config, repository)
+		if err := manager.StartVerification(testname, runner); err != nil {
+			writeError(writer, err)
 			return
 		}
 
@@ -259,29 +338,508 @@ func StartVerification(logFactory df.LogFactory, repository df.TestRepository) h
 }
 
 // StopVerify returns a http handler to stop the verification run of the test
-// given in the request param "name".
-func StopVerify() http.HandlerFunc {
+// given in the request param "name". Responds with the json-encoded report of
+// the verification run.
+func StopVerify(manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		runner, err := manager.StopVerification(mux.Vars(request)["name"])
+		if err != nil {
+			writeError(writer, err)
+			return
+		}
+
+		b, err := json.Marshal(runner.Report())
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(b)
+	}
+}
+
+// SetDriverOutcome returns a http handler that sets the outcome of the UI driver
+// that executed the use case of the test given in the request param "name". The
+// outcome is given as json-encoded request body, e.g. {"outcome": "passed"}. It
+// is stored with the running verification or, if the verification has already
+// been stopped, with the latest run of the test.
+func SetDriverOutcome(repository df.TestRepository, manager *Manager) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
+		var body struct {
+			Outcome string `json:"outcome"`
+		}
+		if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body.Outcome != df.DriverPassed && body.Outcome != df.DriverFailed {
+			http.Error(writer, fmt.Sprintf("unknown driver outcome '%s'", body.Outcome), http.StatusBadRequest)
+			return
+		}
+
 		testname := mux.Vars(request)["name"]
-		runner, ok := verifyRunners[testname]
-		if !ok {
-			http.Error(writer, "test is not being verified", http.StatusNotFound)
+		if runner, ok := manager.Verification(testname); ok {
+			runner.SetDriverOutcome(body.Outcome)
+			writer.WriteHeader(http.StatusNoContent)
 			return
 		}
-		delete(verifyRunners, testname)
-		if err := runner.Stop(); err != nil {
+
+		tc, err := repository.Get(testname)
+		if err != nil {
 			http.Error(writer, err.Error(), http.StatusNotFound)
 			return
 		}
-
+		if len(tc.Runs) == 0 {
+			http.Error(writer, "test has not been verified yet", http.StatusNotFound)
+			return
+		}
+		tc.Runs[len(tc.Runs)-1].Driver = body.Outcome
+		tc.Change = df.ChangeEdit
+		if err := repository.Write(tc.Name, tc); err != nil {
+			writeError(writer, err)
+			return
+		}
 		writer.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// GetReport returns a http handler that responds with the report of the last
+// verification run of the test given in the request param "name". The optional
+// query param "format" selects the report format, e.g. junit. Defaults to json.
+func GetReport(repository df.TestRepository) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name := request.URL.Query().Get("format")
+		if name == "" {
+			name = "json"
+		}
+		format, err := export.Lookup(name)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tc, err := repository.Get(mux.Vars(request)["name"])
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		writer.Header().Set("Content-Type", format.ContentType)
+		if err := format.Write(writer, df.NewReport(tc)); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetRuns returns a http handler that responds with the verification run
+// history of the test given in the request param "name" together with the
+// history of each of its expectations.
+func GetRuns(repository df.TestRepository) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		tc, err := repository.Get(mux.Vars(request)["name"])
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		runs := struct {
+			Runs         []df.Run                `json:"runs"`
+			Expectations []df.ExpectationHistory `json:"expectations"`
+		}{Runs: tc.Runs, Expectations: tc.History()}
+		b, err := json.Marshal(runs)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(b)
+	}
+}
+
+// AddForbidden returns a http handler that forbids the statements given by the
+// json-encoded [df.Forbidden] request body for the test given in the request
+// param "name".
+func AddForbidden(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var f df.Forbidden
+		if err := json.NewDecoder(request.Body).Decode(&f); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(f.Pattern) == 0 {
+			http.Error(writer, "pattern is required", http.StatusBadRequest)
+			return
+		}
+		updateForbidden(writer, request, repository, manager, func(tc *df.Testcase) bool {
+			tc.Forbid(f)
+			return true
+		})
+	}
+}
+
+// RemoveForbidden returns a http handler that allows the formerly forbidden
+// statements given by the request params "pattern" and "channel" for the test
+// given in the request param "name".
+func RemoveForbidden(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		f := df.Forbidden{
+			Pattern: request.URL.Query().Get("pattern"),
+			Channel: request.URL.Query().Get("channel"),
+		}
+		updateForbidden(writer, request, repository, manager, func(tc *df.Testcase) bool {
+			return tc.Allow(f)
+		})
+	}
+}
+
+// updateForbidden applies update to the test given in the request param "name"
+// and writes the test back. Tests that are being recorded or verified can't be
+// edited, because the run writes the test when it stops. Responds with the
+// json-encoded list of forbidden statements or 404 if update returns false.
+func updateForbidden(writer http.ResponseWriter, request *http.Request, repository df.TestRepository, manager *Manager, update func(tc *df.Testcase) bool) {
+	testname := mux.Vars(request)["name"]
+	if state := manager.State(testname); state != StateIdle {
+		http.Error(writer, fmt.Sprintf("test '%s' is %s", testname, state), http.StatusConflict)
+		return
+	}
+	if !repository.Exists(testname) {
+		http.Error(writer, fmt.Sprintf("test '%s' not found", testname), http.StatusNotFound)
+		return
+	}
+	tc, err := repository.Get(testname)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := checkIfMatch(request, tc); err != nil {
+		writeError(writer, err)
+		return
+	}
+	if !update(&tc) {
+		http.Error(writer, "statement is not forbidden", http.StatusNotFound)
+		return
+	}
+	tc.Change = df.ChangeEdit
+	if err := repository.Write(tc.Name, tc); err != nil {
+		writeError(writer, err)
+		return
+	}
+	b, err := json.Marshal(tc.Forbidden)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("ETag", etag(tc.Revision+1))
+	_, _ = writer.Write(b)
+}
+
+// AddExpectation returns a http handler that adds the json-encoded
+// [df.Expectation] request body to the test given in the request param "name".
+// The expectation gets a new uuid. Promoting an additional statement of the
+// last verification run removes it from the test's additional expectations.
+// Responds with 201 and the json-encoded expectations of the test.
+func AddExpectation(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var e df.Expectation
+		if err := json.NewDecoder(request.Body).Decode(&e); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e.Uuid = df.GoogleUUIDProvider{}.NewString()
+		e.Fulfilled, e.Verified, e.Occurrences, e.Closest = false, 0, 0, ""
+		updateExpectations(writer, request, repository, manager, http.StatusCreated, func(tc *df.Testcase) error {
+			if err := tc.AddExpectation(e); err != nil {
+				return err
+			}
+			for i, a := range tc.AdditionalExpectations {
+				if a.Channel == e.Channel && a.String() == e.String() {
+					tc.AdditionalExpectations = append(tc.AdditionalExpectations[:i], tc.AdditionalExpectations[i+1:]...)
+					break
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// EditExpectation returns a http handler that applies the json-encoded
+// [df.ExpectationEdit] request body to the expectation given in the request
+// param "uuid", e.g. to disable it or to change its pattern or IgnoreDiffs.
+func EditExpectation(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var edit df.ExpectationEdit
+		if err := json.NewDecoder(request.Body).Decode(&edit); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updateExpectations(writer, request, repository, manager, http.StatusOK, func(tc *df.Testcase) error {
+			return tc.EditExpectation(mux.Vars(request)["uuid"], edit)
+		})
+	}
+}
+
+// RemoveExpectation returns a http handler that removes the expectation given
+// in the request param "uuid" together with its correlations.
+func RemoveExpectation(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		uuid := mux.Vars(request)["uuid"]
+		updateExpectations(writer, request, repository, manager, http.StatusOK, func(tc *df.Testcase) error {
+			if !tc.RemoveExpectation(uuid) {
+				return fmt.Errorf("%w: %s", df.ErrExpectationNotFound, uuid)
+			}
+			return nil
+		})
+	}
+}
+
+// ReorderExpectations returns a http handler that arranges the expectations in
+// the order of the json-encoded request body {"uuids": [...]}, which must list
+// each expectation exactly once.
+func ReorderExpectations(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var order struct {
+			Uuids []string `json:"uuids"`
+		}
+		if err := json.NewDecoder(request.Body).Decode(&order); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updateExpectations(writer, request, repository, manager, http.StatusOK, func(tc *df.Testcase) error {
+			return tc.Reorder(order.Uuids)
+		})
+	}
+}
+
+// Rebaseline returns a http handler that accepts the statements of the last
+// verification run selected by the json-encoded [df.Rebaseline] request body
+// as new baseline of the test given in the request param "name". The former
+// baseline remains available as revision for rollback.
+func Rebaseline(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var rebaseline df.Rebaseline
+		if err := json.NewDecoder(request.Body).Decode(&rebaseline); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updateExpectations(writer, request, repository, manager, http.StatusCreated, func(tc *df.Testcase) error {
+			tc.Change = df.ChangeRebaseline
+			return tc.Rebaseline(rebaseline, df.GoogleUUIDProvider{}.NewString)
+		})
+	}
+}
+
+// RollbackBaseline returns a http handler that restores the former baseline of
+// the test given in the request param "name" from the test's revisions.
+func RollbackBaseline(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		updateExpectations(writer, request, repository, manager, http.StatusOK, func(tc *df.Testcase) error {
+			if tc.FormerBaseline == 0 {
+				return fmt.Errorf("%w: test '%s' has no former baseline", df.ErrInvalidEdit, tc.Name)
+			}
+			former, err := repository.GetRevision(tc.Name, tc.FormerBaseline)
+			if err != nil {
+				return err
+			}
+			tc.Change = df.ChangeRebaseline
+			return tc.RollbackBaseline(former)
+		})
+	}
+}
+
+// GetRevisions returns a http handler that responds with the json-encoded list
+// of kept revisions of the test given in the request param "name".
+func GetRevisions(repository df.TestRepository) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		revisions, err := repository.Revisions(mux.Vars(request)["name"])
+		if err != nil {
+			writeError(writer, err)
+			return
+		}
+		writeJSON(writer, struct {
+			Revisions []df.Revision `json:"revisions"`
+		}{Revisions: revisions})
+	}
+}
+
+// GetRevision returns a http handler that responds with the json-encoded test
+// given in the request param "name" as it was written in the request param
+// "revision".
+func GetRevision(repository df.TestRepository) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		revision, _ := strconv.Atoi(mux.Vars(request)["revision"])
+		tc, err := repository.GetRevision(mux.Vars(request)["name"], revision)
+		if err != nil {
+			writeError(writer, err)
+			return
+		}
+		writeJSON(writer, tc)
+	}
+}
+
+// DiffRevision returns a http handler that responds with the json-encoded
+// [df.RevisionDiff] between the request param "revision" of the test given in
+// the request param "name" and the revision given by the optional query param
+// "to", which defaults to the current revision.
+func DiffRevision(repository df.TestRepository) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		testname := mux.Vars(request)["name"]
+		revision, _ := strconv.Atoi(mux.Vars(request)["revision"])
+		from, err := repository.GetRevision(testname, revision)
+		if err != nil {
+			writeError(writer, err)
+			return
+		}
+		var to df.Testcase
+		if s := request.URL.Query().Get("to"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid revision '%s'", s), http.StatusBadRequest)
+				return
+			}
+			to, err = repository.GetRevision(testname, n)
+		} else {
+			to, err = repository.Get(testname)
+		}
+		if err != nil {
+			writeError(writer, err)
+			return
+		}
+		writeJSON(writer, df.Diff(from, to))
+	}
+}
+
+// RollbackRevision returns a http handler that restores the request param
+// "revision" of the test given in the request param "name", see df.Rollback.
+// The rollback is written as new revision, thus it can be undone as well.
+func RollbackRevision(repository df.TestRepository, manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		revision, _ := strconv.Atoi(mux.Vars(request)["revision"])
+		updateExpectations(writer, request, repository, manager, http.StatusOK, func(tc *df.Testcase) error {
+			old, err := repository.GetRevision(tc.Name, revision)
+			if err != nil {
+				return err
+			}
+			*tc = df.Rollback(*tc, old)
+			return nil
+		})
+	}
+}
+
+// writeJSON writes the json-encoded v.
+func writeJSON(writer http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write(b)
+}
+
+// updateExpectations applies update to the test given in the request param
+// "name" and writes the test back as df.ChangeEdit unless update sets another
+// change. Tests that are being recorded or verified can't be edited. Responds
+// with status and the json-encoded expectations.
+func updateExpectations(writer http.ResponseWriter, request *http.Request, repository df.TestRepository, manager *Manager, status int, update func(tc *df.Testcase) error) {
+	testname := mux.Vars(request)["name"]
+	if state := manager.State(testname); state != StateIdle {
+		http.Error(writer, fmt.Sprintf("test '%s' is %s", testname, state), http.StatusConflict)
+		return
+	}
+	tc, err := repository.Get(testname)
+	if err != nil {
+		writeError(writer, err)
+		return
+	}
+	if err := checkIfMatch(request, tc); err != nil {
+		writeError(writer, err)
+		return
+	}
+	tc.Change = df.ChangeEdit
+	if err := update(&tc); err != nil {
+		writeError(writer, err)
+		return
+	}
+	if err := repository.Write(tc.Name, tc); err != nil {
+		writeError(writer, err)
+		return
+	}
+	b, err := json.Marshal(tc.Expectations)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("ETag", etag(tc.Revision+1))
+	writer.WriteHeader(status)
+	_, _ = writer.Write(b)
+}
+
+// checkIfMatch returns an ErrConflict if the request header If-Match names
+// another revision than the revision of tc. Clients send the revision they
+// have shown to the user, thus edits based on a stale view aren't applied.
+// Requests without If-Match edit the latest revision.
+func checkIfMatch(request *http.Request, tc df.Testcase) error {
+	match := request.Header.Get("If-Match")
+	if match == "" || match == "*" {
+		return nil
+	}
+	revision, err := strconv.Atoi(strings.Trim(match, `"`))
+	if err != nil {
+		return fmt.Errorf("%w: invalid If-Match header '%s'", df.ErrInvalidEdit, match)
+	}
+	if revision != tc.Revision {
+		return fmt.Errorf("%w: test '%s' has revision %d, expected %d", df.ErrConflict, tc.Name, tc.Revision, revision)
+	}
+	return nil
+}
+
+// etag returns the entity tag of a test of the given revision.
+func etag(revision int) string {
+	return strconv.Quote(strconv.Itoa(revision))
+}
+
+// GetSessions returns a http handler that responds with the json-encoded list
+// of running recording and verification sessions.
+func GetSessions(manager *Manager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		sessions := struct {
+			Sessions []Session `json:"sessions"`
+		}{Sessions: manager.Sessions()}
+		b, err := json.Marshal(sessions)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(b)
+	}
+}
+
+// writeError writes err as http error. Unknown tests, expectations and
+// revisions are reported as not found, invalid edits as bad request, illegal
+// state transitions and concurrent modifications as conflict and failing
+// channel logs as failed dependency.
+func writeError(w http.ResponseWriter, err error) {
+	var illegal IllegalTransitionError
+	var channel df.ChannelError
+	switch {
+	case errors.Is(err, df.ErrTestNotFound), errors.Is(err, df.ErrExpectationNotFound), errors.Is(err, df.ErrRevisionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, df.ErrInvalidEdit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &illegal), errors.Is(err, df.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, &channel):
+		http.Error(w, err.Error(), http.StatusFailedDependency)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // ChannelHealth checks the health of the channel "name" by tailing the
 // associated log file, triggering the SUT to force a log update and ensures that
 // the log file was updated.
-func ChannelHealth(lf df.LogFactory) http.HandlerFunc {
+func ChannelHealth(formats *df.Registry) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		if len(mux.Vars(request)["name"]) == 0 {
 			http.Error(writer, "name is required", http.StatusBadRequest)
@@ -294,12 +852,22 @@ func ChannelHealth(lf df.LogFactory) http.HandlerFunc {
 			return
 		}
 
-		clog := lf.Create(ch.Log)
+		format, err := formats.Lookup(ch)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusFailedDependency)
+			return
+		}
+		clog, err := format.LogFactory.Create(ch)
+		if err != nil {
+			http.Error(writer, df.ChannelError{Channel: ch.Name, Err: err}.Error(), http.StatusFailedDependency)
+			return
+		}
+		defer clog.Close()
 
 		// jump to logfile end
-		err := clog.Tail()
+		err = clog.Tail()
 		if err != nil {
-			writer.WriteHeader(http.StatusInternalServerError)
+			http.Error(writer, df.ChannelError{Channel: ch.Name, Err: err}.Error(), http.StatusFailedDependency)
 			return
 		}
 
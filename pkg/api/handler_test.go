@@ -1,38 +1,45 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/rwirdemann/datafrog/pkg/df"
 	"github.com/rwirdemann/datafrog/pkg/mocks"
+	"github.com/rwirdemann/datafrog/pkg/mysql"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 )
 
 var testname string
+var formats *df.Registry
 
 func init() {
 	testname = "create-job"
+	formats = df.NewRegistry()
+	formats.Register("mock", df.Format{LogFactory: mocks.LogFactory{}, Tokenizer: mysql.Tokenizer{}})
 }
 
 func TestStartRecordingNoChannels(t *testing.T) {
-	logFactory := mocks.LogFactory{}
 	repository := &mocks.TestRepository{}
-	rr := startRecording(t, logFactory, repository)
+	rr := startRecording(t, repository, NewManager())
 	assert.Equal(t, http.StatusFailedDependency, rr.Code)
 }
 
 func TestRecording(t *testing.T) {
-	config.Channels = append(config.Channels, df.Channel{})
-	logFactory := mocks.LogFactory{}
+	config.Channels = append(config.Channels, df.Channel{Format: "mock"})
 	repository := &mocks.TestRepository{}
-	rr := startRecording(t, logFactory, repository)
+	manager := NewManager()
+	rr := startRecording(t, repository, manager)
 	assert.Equal(t, http.StatusAccepted, rr.Code)
-	runner, ok := runners[testname]
-	assert.True(t, ok)
-	runner.Stop()
+	assert.Equal(t, StateRecording, manager.State(testname))
+	assert.NoError(t, manager.StopRecording(testname))
+	assert.Equal(t, StateIdle, manager.State(testname))
 	tc, err := repository.Get(testname)
 	if err != nil {
 		t.Fatal(err)
@@ -41,37 +48,373 @@ func TestRecording(t *testing.T) {
 }
 
 func TestVerification(t *testing.T) {
-	config.Channels = append(config.Channels, df.Channel{})
-	logFactory := mocks.LogFactory{}
+	config.Channels = append(config.Channels, df.Channel{Format: "mock"})
 	repository := &mocks.TestRepository{Testcases: []df.Testcase{{Name: testname}}}
-	rr := startVerification(t, logFactory, repository)
+	manager := NewManager()
+	rr := startVerification(t, repository, manager)
 	assert.Equal(t, http.StatusAccepted, rr.Code)
-	runner, ok := verifyRunners[testname]
-	assert.True(t, ok)
-	err := runner.Stop()
+	assert.Equal(t, StateVerifying, manager.State(testname))
+
+	// a running verification can't be started twice
+	rr = startVerification(t, repository, manager)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// the forbidden statements of a running verification can't be edited
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/forbidden", AddForbidden(repository, manager)).Methods("POST")
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/tests/%s/forbidden", testname), strings.NewReader(`{"pattern": "delete"}`))
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	_, err = manager.StopVerification(testname)
 	assert.NoError(t, err)
+	assert.Equal(t, StateIdle, manager.State(testname))
 }
 
-func startRecording(t *testing.T, logFactory df.LogFactory, repository df.TestRepository) *httptest.ResponseRecorder {
+func startRecording(t *testing.T, repository df.TestRepository, manager *Manager) *httptest.ResponseRecorder {
 	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/tests/%s/recordings", testname), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
 	r := mux.NewRouter()
-	r.HandleFunc("/tests/{name}/recordings", StartRecording(logFactory, repository)).Methods("POST")
+	r.HandleFunc("/tests/{name}/recordings", StartRecording(formats, repository, manager)).Methods("POST")
 	r.ServeHTTP(rr, req)
 	return rr
 }
 
-func startVerification(t *testing.T, logFactory df.LogFactory, repository df.TestRepository) *httptest.ResponseRecorder {
+func startVerification(t *testing.T, repository df.TestRepository, manager *Manager) *httptest.ResponseRecorder {
 	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/tests/%s/verifications", testname), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
 	r := mux.NewRouter()
-	r.HandleFunc("/tests/{name}/verifications", StartVerification(logFactory, repository)).Methods("PUT")
+	r.HandleFunc("/tests/{name}/verifications", StartVerification(formats, repository, manager)).Methods("PUT")
 	r.ServeHTTP(rr, req)
 	return rr
 }
+
+func TestForbidden(t *testing.T) {
+	repository := &mocks.TestRepository{Testcases: []df.Testcase{{Name: testname}}}
+	manager := NewManager()
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/forbidden", AddForbidden(repository, manager)).Methods("POST")
+	r.HandleFunc("/tests/{name}/forbidden", RemoveForbidden(repository, manager)).Methods("DELETE")
+
+	body := strings.NewReader(`{"pattern": "delete from job", "channel": "mysql"}`)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/tests/%s/forbidden", testname), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	tc, _ := repository.Get(testname)
+	assert.Equal(t, []df.Forbidden{{Pattern: "delete from job", Channel: "mysql"}}, tc.Forbidden)
+
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/tests/%s/forbidden?pattern=delete+from+job", testname), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req, err = http.NewRequest(http.MethodDelete, fmt.Sprintf("/tests/%s/forbidden?pattern=delete+from+job&channel=mysql", testname), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	tc, _ = repository.Get(testname)
+	assert.Empty(t, tc.Forbidden)
+}
+
+func TestEditIfMatch(t *testing.T) {
+	repository := &mocks.TestRepository{Testcases: []df.Testcase{{
+		Name:         testname,
+		Revision:     4,
+		Expectations: []df.Expectation{{Uuid: "e1", Tokens: df.Tokenize("insert into job values (1)"), Pattern: "insert", IgnoreDiffs: []int{}}},
+	}}}
+	manager := NewManager()
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}", GetTest(repository)).Methods("GET")
+	r.HandleFunc("/tests/{name}/forbidden", AddForbidden(repository, manager)).Methods("POST")
+	r.HandleFunc("/tests/{name}/expectations/{uuid}", EditExpectation(repository, manager)).Methods("PATCH")
+	do := func(method, path, body, ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, fmt.Sprintf("/tests/%s%s", testname, path), strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, `"4"`, do(http.MethodGet, "", "", "").Header().Get("ETag"))
+
+	// edits based on a stale revision are rejected
+	assert.Equal(t, http.StatusConflict, do(http.MethodPatch, "/expectations/e1", `{"disabled": true}`, `"3"`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/forbidden", `{"pattern": "delete"}`, `"3"`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/expectations/e1", `{"disabled": true}`, "latest").Code)
+	tc, _ := repository.Get(testname)
+	assert.False(t, tc.Expectations[0].Disabled)
+	assert.Empty(t, tc.Forbidden)
+
+	rr := do(http.MethodPatch, "/expectations/e1", `{"disabled": true}`, `"4"`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"5"`, rr.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/forbidden", `{"pattern": "delete"}`, "*").Code)
+	tc, _ = repository.Get(testname)
+	assert.True(t, tc.Expectations[0].Disabled)
+	assert.Len(t, tc.Forbidden, 1)
+}
+
+func TestEditExpectations(t *testing.T) {
+	repository := &mocks.TestRepository{Testcases: []df.Testcase{{
+		Name: testname,
+		Expectations: []df.Expectation{
+			{Uuid: "e1", Tokens: df.Tokenize("insert into job values (1)"), Pattern: "insert", IgnoreDiffs: []int{}},
+			{Uuid: "e2", Tokens: df.Tokenize("update job"), Pattern: "update", IgnoreDiffs: []int{}},
+		},
+		AdditionalExpectations: []df.Expectation{{Tokens: df.Tokenize("delete from job"), Pattern: "delete", Channel: "mysql"}},
+	}}}
+	manager := NewManager()
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/expectations", AddExpectation(repository, manager)).Methods("POST")
+	r.HandleFunc("/tests/{name}/expectations/order", ReorderExpectations(repository, manager)).Methods("PUT")
+	r.HandleFunc("/tests/{name}/expectations/{uuid}", EditExpectation(repository, manager)).Methods("PATCH")
+	r.HandleFunc("/tests/{name}/expectations/{uuid}", RemoveExpectation(repository, manager)).Methods("DELETE")
+	do := func(method, path, body string) int {
+		req, err := http.NewRequest(method, fmt.Sprintf("/tests/%s%s", testname, path), strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPatch, "/expectations/e1", `{"disabled": true, "ignoreDiffs": [4]}`))
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPatch, "/expectations/e1", `{"ignoreDiffs": [9]}`))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPatch, "/expectations/e9", `{"disabled": true}`))
+	tc, _ := repository.Get(testname)
+	assert.True(t, tc.Expectations[0].Disabled)
+	assert.Equal(t, []int{4}, tc.Expectations[0].IgnoreDiffs)
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/expectations",
+		`{"tokens": ["delete", "from", "job"], "pattern": "delete", "channel": "mysql"}`))
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/expectations",
+		`{"tokens": ["x"], "pattern": "x", "ignoreDiffs": [5]}`))
+	tc, _ = repository.Get(testname)
+	assert.Len(t, tc.Expectations, 3)
+	assert.NotEmpty(t, tc.Expectations[2].Uuid)
+	assert.Equal(t, "delete", tc.Expectations[2].Pattern)
+	assert.Empty(t, tc.AdditionalExpectations)
+
+	order := fmt.Sprintf(`{"uuids": ["%s", "e2", "e1"]}`, tc.Expectations[2].Uuid)
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, "/expectations/order", order))
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/expectations/order", `{"uuids": ["e1"]}`))
+	tc, _ = repository.Get(testname)
+	assert.Equal(t, "e2", tc.Expectations[1].Uuid)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/expectations/e2", ""))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/expectations/e2", ""))
+	tc, _ = repository.Get(testname)
+	assert.Len(t, tc.Expectations, 2)
+}
+
+func TestRebaseline(t *testing.T) {
+	repository := &mocks.TestRepository{}
+	assert.NoError(t, repository.Write(testname, df.Testcase{
+		Name:                   testname,
+		Expectations:           []df.Expectation{{Uuid: "e1", Tokens: df.Tokenize("update job"), Pattern: "update"}},
+		AdditionalExpectations: []df.Expectation{{Tokens: df.Tokenize("update job set name='a'"), Pattern: "update"}},
+	}))
+	manager := NewManager()
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/baselines", Rebaseline(repository, manager)).Methods("POST")
+	r.HandleFunc("/tests/{name}/baselines/rollback", RollbackBaseline(repository, manager)).Methods("POST")
+	do := func(path, body string) int {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/tests/%s%s", testname, path), strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("/baselines", `{"revision": 1}`))
+	// the additional statements have been replaced since revision 0
+	assert.Equal(t, http.StatusConflict, do("/baselines", `{"revision": 0, "replace": [{"uuid": "e1", "additional": 0}]}`))
+	assert.Equal(t, http.StatusCreated, do("/baselines", `{"revision": 1, "replace": [{"uuid": "e1", "additional": 0}]}`))
+	tc, _ := repository.Get(testname)
+	assert.Equal(t, 2, tc.Revision)
+	assert.Equal(t, 1, tc.Baseline)
+	assert.Equal(t, "update job set name=a", tc.Expectations[0].String())
+	assert.Empty(t, tc.AdditionalExpectations)
+
+	assert.Equal(t, http.StatusOK, do("/baselines/rollback", ""))
+	tc, _ = repository.Get(testname)
+	assert.Equal(t, 0, tc.Baseline)
+	assert.Equal(t, "e1", tc.Expectations[0].Uuid)
+	assert.Equal(t, http.StatusBadRequest, do("/baselines/rollback", ""))
+}
+
+func TestRevisions(t *testing.T) {
+	repository := &mocks.TestRepository{}
+	tc := df.Testcase{Name: testname, Change: df.ChangeRecording,
+		Expectations: []df.Expectation{{Uuid: "e1", Tokens: df.Tokenize("update job where id=1"), Pattern: "update", IgnoreDiffs: []int{}}}}
+	assert.NoError(t, repository.Write(tc.Name, tc))
+	tc.Change = df.ChangeVerification
+	tc.Expectations = []df.Expectation{{Uuid: "e1", Tokens: df.Tokenize("update job where id=1"), Pattern: "update", IgnoreDiffs: []int{0, 1, 2}}}
+	tc.Runs = []df.Run{{Passed: true}}
+	assert.NoError(t, repository.Write(tc.Name, tc))
+
+	manager := NewManager()
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/revisions", GetRevisions(repository)).Methods("GET")
+	r.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}", GetRevision(repository)).Methods("GET")
+	r.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}/diff", DiffRevision(repository)).Methods("GET")
+	r.HandleFunc("/tests/{name}/revisions/{revision:[0-9]+}/rollback", RollbackRevision(repository, manager)).Methods("POST")
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, fmt.Sprintf("/tests/%s%s", testname, path), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodGet, "/revisions")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var revisions struct {
+		Revisions []df.Revision `json:"revisions"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &revisions))
+	assert.Len(t, revisions.Revisions, 2)
+	assert.Equal(t, df.ChangeRecording, revisions.Revisions[0].Change)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/revisions/1").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/revisions/7").Code)
+
+	rr = do(http.MethodGet, "/revisions/1/diff")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var diff df.RevisionDiff
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &diff))
+	assert.Len(t, diff.Changed, 1)
+	assert.Equal(t, []string{"ignoreDiffs"}, diff.Changed[0].Fields)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/revisions/1/diff?to=x").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/revisions/1/rollback").Code)
+	restored, _ := repository.Get(testname)
+	assert.Equal(t, df.ChangeRollback, restored.Change)
+	assert.Empty(t, restored.Expectations[0].IgnoreDiffs)
+	assert.Len(t, restored.Runs, 1)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/revisions/9/rollback").Code)
+}
+
+func TestRuns(t *testing.T) {
+	repository := &mocks.TestRepository{Testcases: []df.Testcase{{
+		Name:         testname,
+		Expectations: []df.Expectation{{Uuid: "e1"}},
+		Runs:         []df.Run{{Fulfilled: []string{"e1"}, Passed: true}},
+	}}}
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/runs", GetRuns(repository)).Methods("GET")
+	r.HandleFunc("/tests/{name}/verifications/driver", SetDriverOutcome(repository, NewManager())).Methods("PUT")
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/tests/%s/verifications/driver", testname), strings.NewReader(`{"outcome": "broken"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req, err = http.NewRequest(http.MethodPut, fmt.Sprintf("/tests/%s/verifications/driver", testname), strings.NewReader(`{"outcome": "failed"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/tests/%s/runs", testname), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var runs struct {
+		Runs         []df.Run                `json:"runs"`
+		Expectations []df.ExpectationHistory `json:"expectations"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &runs))
+	assert.Len(t, runs.Runs, 1)
+	assert.Equal(t, df.DriverFailed, runs.Runs[0].Driver)
+	assert.Equal(t, 1, runs.Expectations[0].Fulfilled)
+}
+
+func TestGetReport(t *testing.T) {
+	repository := &mocks.TestRepository{Testcases: []df.Testcase{{
+		Name:         testname,
+		Expectations: []df.Expectation{{Uuid: "e1", Tokens: df.Tokenize("insert into job"), Fulfilled: true}},
+	}}}
+	r := mux.NewRouter()
+	r.HandleFunc("/tests/{name}/report", GetReport(repository)).Methods("GET")
+
+	tests := []struct {
+		format      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"junit", http.StatusOK, "application/xml"},
+		{"markdown", http.StatusOK, "text/markdown; charset=utf-8"},
+		{"html", http.StatusBadRequest, "text/plain; charset=utf-8"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/tests/%s/report?format=%s", testname, test.format), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			assert.Equal(t, test.status, rr.Code)
+			assert.Equal(t, test.contentType, rr.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: t1", df.ErrTestNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: e1", df.ErrExpectationNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: t1 revision 3", df.ErrRevisionNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: pattern must not be empty", df.ErrInvalidEdit), http.StatusBadRequest},
+		{IllegalTransitionError{Testname: "t1", From: StateRecording, To: StateVerifying}, http.StatusConflict},
+		{fmt.Errorf("%w: test 't1' has revision 3, expected 2", df.ErrConflict), http.StatusConflict},
+		{errors.Join(df.ChannelError{Channel: "mysql", Err: os.ErrNotExist}), http.StatusFailedDependency},
+		{errors.New("disk full"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		writeError(rr, test.err)
+		assert.Equal(t, test.status, rr.Code, test.err.Error())
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/record"
+	"github.com/rwirdemann/datafrog/pkg/verify"
+)
+
+// State represents the state of a test within the Manager.
+type State string
+
+// A test is idle until its recording or verification starts and becomes idle
+// again as soon as the recording or verification has been stopped. Stopping
+// lasts until the runner has written the test.
+const (
+	StateIdle      State = "idle"
+	StateRecording State = "recording"
+	StateVerifying State = "verifying"
+	StateStopping  State = "stopping"
+)
+
+// IllegalTransitionError is returned by the Manager if a test is requested to
+// change into a state that isn't reachable from its current state.
+type IllegalTransitionError struct {
+	Testname string
+	From     State
+	To       State
+}
+
+func (e IllegalTransitionError) Error() string {
+	return fmt.Sprintf("test '%s' is %s and can't change to %s", e.Testname, e.From, e.To)
+}
+
+// Session describes a running recording or verification.
+type Session struct {
+	Testname string    `json:"testname"`
+	State    State     `json:"state"`
+	Started  time.Time `json:"started"`
+}
+
+// Manager owns the runners of all active recording and verification sessions.
+// It enforces the state machine idle -> recording -> idle -> verifying -> idle
+// per test and is safe for concurrent use.
+type Manager struct {
+	mu        sync.Mutex
+	sessions  map[string]Session
+	recorders map[string]*record.Runner
+	verifiers map[string]*verify.Runner
+}
+
+// NewManager creates a new Manager without sessions.
+func NewManager() *Manager {
+	return &Manager{
+		sessions:  make(map[string]Session),
+		recorders: make(map[string]*record.Runner),
+		verifiers: make(map[string]*verify.Runner),
+	}
+}
+
+// State returns the current state of test testname.
+func (m *Manager) State(testname string) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state(testname)
+}
+
+func (m *Manager) state(testname string) State {
+	if s, ok := m.sessions[testname]; ok {
+		return s.State
+	}
+	return StateIdle
+}
+
+// transition changes the state of test testname from "from" to "to". Returns
+// an IllegalTransitionError if the test isn't in state "from".
+func (m *Manager) transition(testname string, from, to State) error {
+	current := m.state(testname)
+	if current != from {
+		return IllegalTransitionError{Testname: testname, From: current, To: to}
+	}
+	if to == StateIdle {
+		delete(m.sessions, testname)
+		return nil
+	}
+	s := m.sessions[testname]
+	if from == StateIdle {
+		s = Session{Testname: testname, Started: time.Now()}
+	}
+	s.State = to
+	m.sessions[testname] = s
+	return nil
+}
+
+// StartRecording starts runner as recording session of test testname. The
+// channel logs of runner are closed if the session can't be started.
+func (m *Manager) StartRecording(testname string, runner *record.Runner) error {
+	m.mu.Lock()
+	if err := m.transition(testname, StateIdle, StateRecording); err != nil {
+		m.mu.Unlock()
+		_ = runner.Discard()
+		return err
+	}
+	m.recorders[testname] = runner
+	m.mu.Unlock()
+
+	if err := runner.Start(); err != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.recorders, testname)
+		_ = m.transition(testname, StateRecording, StateIdle)
+		return err
+	}
+	return nil
+}
+
+// StopRecording stops the recording session of test testname and waits until
+// the recorded test has been written. Returns the error of the runner if the
+// recording failed.
+func (m *Manager) StopRecording(testname string) error {
+	m.mu.Lock()
+	if err := m.transition(testname, StateRecording, StateStopping); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	runner := m.recorders[testname]
+	m.mu.Unlock()
+
+	err := runner.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.recorders, testname)
+	if terr := m.transition(testname, StateStopping, StateIdle); terr != nil {
+		return terr
+	}
+	return err
+}
+
+// Recording returns the runner of the recording session of test testname.
+func (m *Manager) Recording(testname string) (*record.Runner, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.recorders[testname]
+	return r, ok
+}
+
+// StartVerification starts runner as verification session of test testname.
+// The channel logs of runner are closed if the session can't be started.
+func (m *Manager) StartVerification(testname string, runner *verify.Runner) error {
+	m.mu.Lock()
+	if err := m.transition(testname, StateIdle, StateVerifying); err != nil {
+		m.mu.Unlock()
+		_ = runner.Discard()
+		return err
+	}
+	m.verifiers[testname] = runner
+	m.mu.Unlock()
+
+	if err := runner.Start(); err != nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.verifiers, testname)
+		_ = m.transition(testname, StateVerifying, StateIdle)
+		return err
+	}
+	return nil
+}
+
+// StopVerification stops the verification session of test testname, waits
+// until the verified test has been written and returns the stopped runner.
+func (m *Manager) StopVerification(testname string) (*verify.Runner, error) {
+	m.mu.Lock()
+	if err := m.transition(testname, StateVerifying, StateStopping); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	runner := m.verifiers[testname]
+	m.mu.Unlock()
+
+	err := runner.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.verifiers, testname)
+	if terr := m.transition(testname, StateStopping, StateIdle); terr != nil {
+		return nil, terr
+	}
+	return runner, err
+}
+
+// Verification returns the runner of the verification session of test
+// testname.
+func (m *Manager) Verification(testname string) (*verify.Runner, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.verifiers[testname]
+	return r, ok
+}
+
+// Sessions returns all active sessions sorted by test name.
+func (m *Manager) Sessions() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := []Session{}
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Testname < sessions[j].Testname })
+	return sessions
+}
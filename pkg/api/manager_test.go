@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/mocks"
+	"github.com/rwirdemann/datafrog/pkg/mysql"
+	"github.com/rwirdemann/datafrog/pkg/record"
+	"github.com/rwirdemann/datafrog/pkg/verify"
+	"github.com/stretchr/testify/assert"
+)
+
+func sources() []df.Source {
+	return []df.Source{{Channel: df.Channel{Name: "mock"}, Log: &mocks.SQLLog{}, Tokenizer: mysql.Tokenizer{}}}
+}
+
+func TestManagerTransitions(t *testing.T) {
+	repository := &mocks.TestRepository{}
+	m := NewManager()
+
+	var illegal IllegalTransitionError
+	assert.True(t, errors.As(m.StopRecording("t1"), &illegal))
+	assert.Equal(t, IllegalTransitionError{Testname: "t1", From: StateIdle, To: StateStopping}, illegal)
+
+	assert.NoError(t, m.StartRecording("t1", record.NewRunner("t1", sources(), df.OrderingNone, false, repository)))
+	assert.Equal(t, []Session{{Testname: "t1", State: StateRecording, Started: m.Sessions()[0].Started}}, m.Sessions())
+
+	// neither a second recording nor a verification of a test being recorded,
+	// the logs of the rejected runners are closed
+	rejected := sources()
+	err := m.StartRecording("t1", record.NewRunner("t1", rejected, df.OrderingNone, false, repository))
+	assert.True(t, errors.As(err, &illegal))
+	assert.True(t, rejected[0].Log.(*mocks.SQLLog).Closed)
+	rejected = sources()
+	err = m.StartVerification("t1", verify.NewRunner("t1", rejected, df.Config{}, repository))
+	assert.True(t, errors.As(err, &illegal))
+	assert.True(t, rejected[0].Log.(*mocks.SQLLog).Closed)
+	_, err = m.StopVerification("t1")
+	assert.True(t, errors.As(err, &illegal))
+
+	assert.NoError(t, m.StopRecording("t1"))
+	assert.Equal(t, StateIdle, m.State("t1"))
+	assert.Empty(t, m.Sessions())
+
+	assert.NoError(t, m.StartVerification("t1", verify.NewRunner("t1", sources(), df.Config{}, repository)))
+	assert.Equal(t, StateVerifying, m.State("t1"))
+	runner, err := m.StopVerification("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", runner.Testcase().Name)
+	assert.Equal(t, StateIdle, m.State("t1"))
+}
+
+func TestManagerFailedStart(t *testing.T) {
+	m := NewManager()
+	s := sources()
+	err := m.StartVerification("unknown", verify.NewRunner("unknown", s, df.Config{}, &mocks.TestRepository{}))
+	assert.Error(t, err)
+	assert.True(t, s[0].Log.(*mocks.SQLLog).Closed)
+	assert.Equal(t, StateIdle, m.State("unknown"))
+}
+
+func TestManagerFailingChannel(t *testing.T) {
+	m := NewManager()
+	s := sources()
+	s[0].Log = &mocks.SQLLog{TailError: errors.New("permission denied")}
+	err := m.StartRecording("t1", record.NewRunner("t1", s, df.OrderingNone, false, &mocks.TestRepository{}))
+	var channelErr df.ChannelError
+	assert.True(t, errors.As(err, &channelErr))
+	assert.Equal(t, "mock", channelErr.Channel)
+	assert.Equal(t, StateIdle, m.State("t1"))
+}
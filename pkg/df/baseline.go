@@ -0,0 +1,130 @@
+package df
+
+import (
+	"fmt"
+)
+
+// Replacement replaces the unfulfilled expectation Uuid by the additional
+// statement at index Additional of the last verification run.
+type Replacement struct {
+	Uuid       string `json:"uuid"`
+	Additional int    `json:"additional"`
+}
+
+// Rebaseline selects the statements of the last verification run that become
+// the new baseline of a test: the unfulfilled expectations in Remove are
+// removed, the additional statements in Add (indizes into the test's additional
+// expectations) become new expectations and each Replacement substitutes an
+// unfulfilled expectation by an additional statement at the same position.
+// Revision is the revision of the test the statements were selected from,
+// since each verification run replaces the additional expectations.
+type Rebaseline struct {
+	Revision int           `json:"revision"`
+	Remove   []string      `json:"remove,omitempty"`
+	Add      []int         `json:"add,omitempty"`
+	Replace  []Replacement `json:"replace,omitempty"`
+}
+
+// Rebaseline applies r to t. The current revision of t is kept as former
+// baseline, see RollbackBaseline. Accepted statements become expectations with
+// a uuid created by newUUID and are removed from the additional expectations.
+// Fails with ErrConflict if r wasn't selected from the current revision of t.
+func (t *Testcase) Rebaseline(r Rebaseline, newUUID func() string) error {
+	if r.Revision != t.Revision {
+		return fmt.Errorf("%w: statements of revision %d selected, test '%s' has revision %d", ErrConflict, r.Revision, t.Name, t.Revision)
+	}
+	accepted := make(map[int]bool)
+	accept := func(i int) (Expectation, error) {
+		if i < 0 || i >= len(t.AdditionalExpectations) {
+			return Expectation{}, fmt.Errorf("%w: additional statement %d doesn't exist", ErrInvalidEdit, i)
+		}
+		if accepted[i] {
+			return Expectation{}, fmt.Errorf("%w: additional statement %d is accepted twice", ErrInvalidEdit, i)
+		}
+		accepted[i] = true
+		a := t.AdditionalExpectations[i]
+		return Expectation{Uuid: newUUID(), Tokens: a.Tokens, Pattern: a.Pattern, Channel: a.Channel, IgnoreDiffs: []int{}}, nil
+	}
+	if len(r.Remove) == 0 && len(r.Add) == 0 && len(r.Replace) == 0 {
+		return fmt.Errorf("%w: no statements selected", ErrInvalidEdit)
+	}
+
+	// edit a copy, thus t remains unchanged if r is invalid
+	edited := *t
+	edited.Expectations = append([]Expectation(nil), t.Expectations...)
+	unfulfilled := func(uuid string) (int, error) {
+		i := edited.index(uuid)
+		if i < 0 {
+			return -1, fmt.Errorf("%w: %s", ErrExpectationNotFound, uuid)
+		}
+		if edited.Expectations[i].Fulfilled && !edited.Expectations[i].Disabled {
+			return -1, fmt.Errorf("%w: expectation %s is fulfilled", ErrInvalidEdit, uuid)
+		}
+		return i, nil
+	}
+	for _, replacement := range r.Replace {
+		i, err := unfulfilled(replacement.Uuid)
+		if err != nil {
+			return err
+		}
+		e, err := accept(replacement.Additional)
+		if err != nil {
+			return err
+		}
+		edited.Expectations[i] = e
+		edited.dropCorrelations(replacement.Uuid)
+	}
+	for _, uuid := range r.Remove {
+		if _, err := unfulfilled(uuid); err != nil {
+			return err
+		}
+		edited.RemoveExpectation(uuid)
+	}
+	for _, i := range r.Add {
+		e, err := accept(i)
+		if err != nil {
+			return err
+		}
+		edited.Expectations = append(edited.Expectations, e)
+	}
+
+	var additional []Expectation
+	for i, a := range t.AdditionalExpectations {
+		if !accepted[i] {
+			additional = append(additional, a)
+		}
+	}
+	edited.AdditionalExpectations = additional
+	edited.FormerBaseline = t.Revision
+	edited.Baseline++
+	edited.OrderViolations, edited.BrokenCorrelations = nil, nil
+	*t = edited
+	return nil
+}
+
+// RollbackBaseline restores the former baseline of t from former, the revision
+// t.FormerBaseline of the test. The expectations of the current baseline are
+// discarded.
+func (t *Testcase) RollbackBaseline(former Testcase) error {
+	if t.FormerBaseline == 0 {
+		return fmt.Errorf("%w: test '%s' has no former baseline", ErrInvalidEdit, t.Name)
+	}
+	if former.Revision != t.FormerBaseline {
+		return fmt.Errorf("%w: former baseline of test '%s' is revision %d, not %d", ErrInvalidEdit, t.Name, t.FormerBaseline, former.Revision)
+	}
+	t.Expectations, t.Correlations = former.Expectations, former.Correlations
+	t.Baseline, t.FormerBaseline = former.Baseline, former.FormerBaseline
+	t.OrderViolations, t.BrokenCorrelations = nil, nil
+	return nil
+}
+
+// dropCorrelations removes the correlations referencing the expectation uuid.
+func (t *Testcase) dropCorrelations(uuid string) {
+	var correlations []Correlation
+	for _, c := range t.Correlations {
+		if c.Source.Uuid != uuid && c.Target.Uuid != uuid {
+			correlations = append(correlations, c)
+		}
+	}
+	t.Correlations = correlations
+}
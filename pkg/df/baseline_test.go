@@ -0,0 +1,84 @@
+package df
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rebaselineTestcase() Testcase {
+	return Testcase{
+		Name:     "create-job",
+		Revision: 3,
+		Expectations: []Expectation{
+			{Uuid: "e1", Tokens: Tokenize("insert into job (id, name) values (1, 'a')"), Pattern: "insert", Fulfilled: true, Verified: 3},
+			{Uuid: "e2", Tokens: Tokenize("update job set name='b' where id=1"), Pattern: "update"},
+			{Uuid: "e3", Tokens: Tokenize("select * from job"), Pattern: "select"},
+		},
+		Correlations: []Correlation{{Source: TokenRef{Uuid: "e1", Token: 8}, Target: TokenRef{Uuid: "e2", Token: 5}}},
+		AdditionalExpectations: []Expectation{
+			{Tokens: Tokenize("update job set title='b' where id=1"), Pattern: "update", Channel: "mysql"},
+			{Tokens: Tokenize("delete from job where id=1"), Pattern: "delete", Channel: "mysql"},
+			{Tokens: Tokenize("select count(*) from job"), Pattern: "select", Channel: "mysql"},
+		},
+	}
+}
+
+func TestRebaseline(t *testing.T) {
+	n := 0
+	newUUID := func() string {
+		n++
+		return fmt.Sprintf("n%d", n)
+	}
+	tc := rebaselineTestcase()
+	err := tc.Rebaseline(Rebaseline{
+		Revision: 3,
+		Remove:   []string{"e3"},
+		Add:      []int{1},
+		Replace:  []Replacement{{Uuid: "e2", Additional: 0}},
+	}, newUUID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Expectation{
+		{Uuid: "e1", Tokens: Tokenize("insert into job (id, name) values (1, 'a')"), Pattern: "insert", Fulfilled: true, Verified: 3},
+		{Uuid: "n1", Tokens: Tokenize("update job set title='b' where id=1"), Pattern: "update", Channel: "mysql", IgnoreDiffs: []int{}},
+		{Uuid: "n2", Tokens: Tokenize("delete from job where id=1"), Pattern: "delete", Channel: "mysql", IgnoreDiffs: []int{}},
+	}, tc.Expectations)
+	assert.Empty(t, tc.Correlations)
+	assert.Equal(t, []Expectation{rebaselineTestcase().AdditionalExpectations[2]}, tc.AdditionalExpectations)
+	assert.Equal(t, 1, tc.Baseline)
+	assert.Equal(t, 3, tc.FormerBaseline)
+
+	// the former baseline is restored from revision 3
+	tc.Revision = 4
+	assert.ErrorIs(t, tc.RollbackBaseline(tc), ErrInvalidEdit)
+	assert.NoError(t, tc.RollbackBaseline(rebaselineTestcase()))
+	assert.Equal(t, rebaselineTestcase().Expectations, tc.Expectations)
+	assert.Equal(t, rebaselineTestcase().Correlations, tc.Correlations)
+	assert.Equal(t, 0, tc.Baseline)
+	assert.Equal(t, 0, tc.FormerBaseline)
+	assert.ErrorIs(t, tc.RollbackBaseline(rebaselineTestcase()), ErrInvalidEdit)
+}
+
+func TestRebaselineInvalid(t *testing.T) {
+	tests := []struct {
+		desc string
+		r    Rebaseline
+		err  error
+	}{
+		{desc: "nothing selected", r: Rebaseline{Revision: 3}, err: ErrInvalidEdit},
+		{desc: "stale revision", r: Rebaseline{Revision: 2, Add: []int{1}}, err: ErrConflict},
+		{desc: "fulfilled", r: Rebaseline{Revision: 3, Remove: []string{"e1"}}, err: ErrInvalidEdit},
+		{desc: "unknown", r: Rebaseline{Revision: 3, Remove: []string{"e9"}}, err: ErrExpectationNotFound},
+		{desc: "additional out of range", r: Rebaseline{Revision: 3, Add: []int{3}}, err: ErrInvalidEdit},
+		{desc: "accepted twice", r: Rebaseline{Revision: 3, Add: []int{1}, Replace: []Replacement{{Uuid: "e2", Additional: 1}}}, err: ErrInvalidEdit},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tc := rebaselineTestcase()
+			assert.ErrorIs(t, tc.Rebaseline(test.r, GoogleUUIDProvider{}.NewString), test.err)
+			assert.Equal(t, rebaselineTestcase(), tc)
+		})
+	}
+}
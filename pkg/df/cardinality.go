@@ -0,0 +1,110 @@
+package df
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Normalize replaces the literal values of tokens by "?". Statements with equal
+// normalized tokens only differ in their values, e.g. the repeated select of an
+// N+1 query. The tokenizers strip the quotes of string literals, thus a value is
+// a literal if it is a number or an uuid, if it follows a comparison, e.g.
+// title='Hello' or title = 'Hello', or if it is part of a values or in list.
+func Normalize(tokens []string) []string {
+	var normalized []string
+	list := false
+	for i, t := range tokens {
+		prefix, v, suffix := splitValue(t)
+		if i > 0 && listKeyword(tokens[i-1]) && strings.HasPrefix(t, "(") {
+			list = true
+		}
+		// the value of a comparison may be an empty string, e.g. tags=''
+		compared := strings.ContainsAny(prefix, "=<>") && !comparison(prefix) || i > 0 && comparison(tokens[i-1])
+		if list || literal(v) || compared && !qualifiedRegex.MatchString(v) {
+			t = prefix + "?" + suffix
+		}
+		if list && strings.Contains(suffix, ")") && !strings.HasSuffix(suffix, ",") {
+			list = false
+		}
+		normalized = append(normalized, t)
+	}
+	return normalized
+}
+
+// qualifiedRegex matches qualified column names like job.id, which are compared
+// to other columns in joins and are no literals.
+var qualifiedRegex = regexp.MustCompile(`^[A-Za-z_]\w*\.[A-Za-z_]\w*$`)
+
+// literal returns true if v is a number or an uuid.
+func literal(v string) bool {
+	return numberRegex.MatchString(v) || uuidRegex.MatchString(v)
+}
+
+// comparison returns true if token is a standalone comparison operator.
+func comparison(token string) bool {
+	switch strings.ToLower(token) {
+	case "=", "<", ">", "<=", ">=", "<>", "!=", "like":
+		return true
+	}
+	return false
+}
+
+// listKeyword returns true if token introduces a list of literals.
+func listKeyword(token string) bool {
+	switch strings.ToLower(token) {
+	case "values", "in":
+		return true
+	}
+	return false
+}
+
+// Repeated returns true if e stands for several occurrences of the same
+// normalized statement.
+func (e Expectation) Repeated() bool {
+	return e.Count > 1 || e.Min > 0 || e.Max > 0
+}
+
+// Range returns the minimum and maximum number of occurrences of e that fulfill
+// e. Min and Max default to the recorded Count.
+func (e Expectation) Range() (int, int) {
+	lower, upper := max(e.Count, 1), max(e.Count, 1)
+	if e.Min > 0 {
+		lower = e.Min
+	}
+	if e.Max > 0 {
+		upper = e.Max
+	}
+	return lower, upper
+}
+
+// AcceptsCount returns true if n occurrences of e fulfill e.
+func (e Expectation) AcceptsCount(n int) bool {
+	lower, upper := e.Range()
+	return n >= lower && n <= upper
+}
+
+// NormalizedEqual returns true if tokens only differ from e's tokens in their
+// literal values.
+func (e Expectation) NormalizedEqual(tokens []string) bool {
+	if len(tokens) != len(e.Tokens) {
+		return false
+	}
+	normalized := Normalize(e.Tokens)
+	for i, t := range Normalize(tokens) {
+		if t != normalized[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Cardinality describes the expected and actual occurrences of e, e.g.
+// "expected 50, got 73".
+func (e Expectation) Cardinality() string {
+	lower, upper := e.Range()
+	if lower == upper {
+		return fmt.Sprintf("expected %d, got %d", lower, e.Occurrences)
+	}
+	return fmt.Sprintf("expected %d-%d, got %d", lower, upper, e.Occurrences)
+}
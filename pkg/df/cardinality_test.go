@@ -0,0 +1,56 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []string
+		expected []string
+	}{
+		{"integer", Tokenize("select * from job where id=12"), Tokenize("select * from job where id=?")},
+		{"string", Tokenize("select * from job where title='Hello World'"), Tokenize("select * from job where title=?")},
+		{"empty string", Tokenize("update job set tags='', title='Hello' where id=1"), Tokenize("update job set tags=?, title=? where id=?")},
+		{"spaced comparison", Tokenize("select * from job where title like 'Hello%' and id > 3"), Tokenize("select * from job where title like ? and id > ?")},
+		{"values", Tokenize("insert into job (id, title, tags) values (1, 'Hello World', null), (2, 'Bye', 'go')"),
+			Tokenize("insert into job (id, title, tags) values (?, ?, ?), (?, ?, ?)")},
+		{"in", Tokenize("select * from job where title in ('Hello', 'World') and published is null"),
+			Tokenize("select * from job where title in (?, ?) and published is null")},
+		{"uuid", Tokenize("delete from job where uuid='6ba7b810-9dad-11d1-80b4-00c04fd430c8'"), Tokenize("delete from job where uuid=?")},
+		{"null", Tokenize("select * from job where published is null"), Tokenize("select * from job where published is null")},
+		{"join", Tokenize("select * from job j join tag t on j.id=t.job_id"), Tokenize("select * from job j join tag t on j.id=t.job_id")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Normalize(test.tokens))
+		})
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	tests := []struct {
+		name        string
+		expectation Expectation
+		occurrences int
+		accepts     bool
+		cardinality string
+	}{
+		{"single", Expectation{}, 1, true, "expected 1, got 1"},
+		{"exact", Expectation{Count: 50}, 73, false, "expected 50, got 73"},
+		{"range", Expectation{Count: 50, Min: 40, Max: 60}, 45, true, "expected 40-60, got 45"},
+		{"above range", Expectation{Count: 50, Min: 40, Max: 60}, 61, false, "expected 40-60, got 61"},
+		{"min only", Expectation{Count: 50, Min: 10}, 20, true, "expected 10-50, got 20"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := test.expectation
+			e.Occurrences = test.occurrences
+			assert.Equal(t, test.accepts, e.AcceptsCount(test.occurrences))
+			assert.Equal(t, test.cardinality, e.Cardinality())
+		})
+	}
+}
@@ -5,4 +5,36 @@ type Channel struct {
 	Log      string
 	Format   string
 	Patterns []string
+
+	// Tokenizer optionally replaces the default tokenizer of Format, e.g. "sql"
+	Tokenizer string
+
+	// Regex configures the log of channels with format "regex"
+	Regex RegexConfig
+
+	// Proxy configures channels with format "mysql-proxy" or "postgres-proxy"
+	Proxy ProxyConfig
+}
+
+// RegexConfig describes the entries of a log read by the "regex" format. Line
+// is a regular expression with the named groups "ts", "session" (optional) and
+// "statement". TimestampLayout is the Go time layout of the "ts" group, e.g.
+// "2006-01-02 15:04:05.000". Lines matching the optional Continuation regex are
+// appended to the statement of the preceding entry. Timestamps without zone
+// information are interpreted in TimeZone, an IANA time zone name that defaults
+// to UTC.
+type RegexConfig struct {
+	Line            string `json:"line"`
+	TimestampLayout string `json:"timestamp_layout"`
+	Continuation    string `json:"continuation,omitempty"`
+	TimeZone        string `json:"time_zone,omitempty"`
+}
+
+// ProxyConfig describes the wire-protocol proxy of a channel. The SUT connects
+// to Listen, e.g. "localhost:3307", instead of the database. The proxy forwards
+// all connections to Upstream, e.g. "localhost:3306", and decodes the
+// statements sent by the SUT.
+type ProxyConfig struct {
+	Listen   string `json:"listen"`
+	Upstream string `json:"upstream"`
 }
@@ -7,6 +7,7 @@ package df
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -41,16 +42,34 @@ type Config struct {
 	Api struct {
 		Port int `json:"port"` // api http port
 	}
+
+	Storage StorageConfig `json:"storage"` // where tests are stored
+}
+
+// Storage types of StorageConfig.
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+)
+
+// StorageConfig describes where tests are stored. Type is StorageJSON (default)
+// or StorageSQLite. JSON tests are stored as <Dir>/tests/<name>.json, SQLite
+// tests in the database <Dir>/datafrog.db. Without Dir, JSON tests are stored
+// as <name>.json in the working directory and the database is created in the
+// working directory.
+type StorageConfig struct {
+	Type string `json:"type,omitempty"`
+	Dir  string `json:"dir,omitempty"`
 }
 
 // NewDefaultConfig creates a new Config instance by trying to find a file
 // config.json in the current or in the config subdirectory.
 func NewDefaultConfig() (Config, error) {
 	if exists("config.json") {
-		return NewConfig("config.json"), nil
+		return NewConfig("config.json")
 	}
 	if exists("config/config.json") {
-		return NewConfig("config/config.json"), nil
+		return NewConfig("config/config.json")
 	}
 
 	return Config{}, errors.New("config.json not found")
@@ -58,24 +77,24 @@ func NewDefaultConfig() (Config, error) {
 
 // NewConfig creates a new instance given its settings from filename in json
 // format.
-func NewConfig(filename string) Config {
+func NewConfig(filename string) (Config, error) {
 	log.Printf("using config file '%s'", filename)
 	configfile, err := os.Open(filename)
 	if err != nil {
-		log.Fatal(err)
+		return Config{}, err
 	}
 	defer func(configfile *os.File) {
-		err := configfile.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
+		_ = configfile.Close()
 	}(configfile)
-	byteValue, _ := io.ReadAll(configfile)
+	byteValue, err := io.ReadAll(configfile)
+	if err != nil {
+		return Config{}, err
+	}
 	var config Config
 	if err := json.Unmarshal(byteValue, &config); err != nil {
-		log.Fatal(err)
+		return Config{}, fmt.Errorf("invalid config file '%s': %w", filename, err)
 	}
-	return config
+	return config, nil
 }
 
 func exists(filename string) bool {
@@ -0,0 +1,42 @@
+package df
+
+import (
+	"fmt"
+)
+
+// TokenRef references a single token of an expectation.
+type TokenRef struct {
+	Uuid  string `json:"uuid"`  // uuid of the referenced expectation
+	Token int    `json:"token"` // index of the referenced token
+}
+
+// Correlation describes a data flow between two expectations: the dynamic value
+// found at Source must reappear at Target within the same run. Example: the id
+// generated by "insert into job ... values (..., 42)" must be used by the
+// succeeding "update job ... where id=42".
+type Correlation struct {
+	Source TokenRef `json:"source"`
+	Target TokenRef `json:"target"`
+}
+
+// CorrelationViolation describes a broken data flow, that is a Correlation whose
+// target value didn't match its source value.
+type CorrelationViolation struct {
+	Correlation
+	Source      string `json:"source_statement"`
+	SourceValue string `json:"source_value"`
+	Target      string `json:"target_statement"`
+	TargetValue string `json:"target_value"`
+}
+
+func (v CorrelationViolation) String() string {
+	return fmt.Sprintf("value '%s' of '%s' reappeared as '%s' in '%s'", v.SourceValue, v.Source, v.TargetValue, v.Target)
+}
+
+// Value extracts the plain value from token by removing surrounding brackets,
+// separators and leading comparisons. Examples: "id=12" and "12)," both become
+// "12".
+func Value(token string) string {
+	_, v, _ := splitValue(token)
+	return v
+}
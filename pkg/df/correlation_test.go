@@ -0,0 +1,25 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{token: "id=12", expected: "12"},
+		{token: "12)", expected: "12"},
+		{token: "(World,", expected: "World"},
+		{token: "job0_.publish_trials<1", expected: "1"},
+		{token: "null,", expected: "null"},
+	}
+	for _, test := range tests {
+		t.Run(test.token, func(t *testing.T) {
+			assert.Equal(t, test.expected, Value(test.token))
+		})
+	}
+}
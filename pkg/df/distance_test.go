@@ -0,0 +1,27 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected int
+	}{
+		{"equal", "select * from job", "select * from job", 0},
+		{"substitution", "select * from job where id=1", "select * from job where id=2", 1},
+		{"insertion", "update job set a=1 where id=1", "update job set a=1, b=2 where id=1", 2},
+		{"deletion", "delete from job where id=1", "delete from job", 2},
+		{"empty", "", "select * from job", 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Distance(Tokenize(test.a), Tokenize(test.b)))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package df
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ExpectationEdit describes a manual change of a single expectation. Nil fields
+// remain unchanged.
+type ExpectationEdit struct {
+	Disabled    *bool   `json:"disabled,omitempty"`
+	Pattern     *string `json:"pattern,omitempty"`
+	IgnoreDiffs *[]int  `json:"ignoreDiffs,omitempty"`
+}
+
+// EditExpectation applies edit to the expectation with the given uuid. Ignore
+// rules of indizes that are removed from IgnoreDiffs are dropped.
+func (t *Testcase) EditExpectation(uuid string, edit ExpectationEdit) error {
+	i := t.index(uuid)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrExpectationNotFound, uuid)
+	}
+	e := t.Expectations[i]
+	if edit.Disabled != nil {
+		e.Disabled = *edit.Disabled
+	}
+	if edit.Pattern != nil {
+		if *edit.Pattern == "" {
+			return fmt.Errorf("%w: pattern must not be empty", ErrInvalidEdit)
+		}
+		e.Pattern = *edit.Pattern
+	}
+	if edit.IgnoreDiffs != nil {
+		diffs, err := ignoreDiffs(e, *edit.IgnoreDiffs)
+		if err != nil {
+			return err
+		}
+		var rules []IgnoreRule
+		for _, r := range e.IgnoreRules {
+			if contains(diffs, r.Index) {
+				rules = append(rules, r)
+			}
+		}
+		e.IgnoreDiffs, e.IgnoreRules = diffs, rules
+	}
+	t.Expectations[i] = e
+	return nil
+}
+
+// RemoveExpectation removes the expectation with the given uuid together with
+// the correlations referencing it. Returns false if there is no such
+// expectation.
+func (t *Testcase) RemoveExpectation(uuid string) bool {
+	i := t.index(uuid)
+	if i < 0 {
+		return false
+	}
+	t.Expectations = append(t.Expectations[:i], t.Expectations[i+1:]...)
+	t.dropCorrelations(uuid)
+	return true
+}
+
+// AddExpectation appends e to the expectations, e.g. to promote an additional
+// statement of the last verification run. e requires a uuid that isn't used
+// yet.
+func (t *Testcase) AddExpectation(e Expectation) error {
+	if e.Uuid == "" || len(e.Tokens) == 0 || e.Pattern == "" {
+		return fmt.Errorf("%w: expectation requires uuid, tokens and pattern", ErrInvalidEdit)
+	}
+	if t.index(e.Uuid) >= 0 {
+		return fmt.Errorf("%w: duplicate uuid %s", ErrInvalidEdit, e.Uuid)
+	}
+	diffs, err := ignoreDiffs(e, e.IgnoreDiffs)
+	if err != nil {
+		return err
+	}
+	for _, r := range e.IgnoreRules {
+		if !contains(diffs, r.Index) {
+			return fmt.Errorf("%w: ignore rule of token %d that isn't ignored", ErrInvalidEdit, r.Index)
+		}
+	}
+	e.IgnoreDiffs = diffs
+	t.Expectations = append(t.Expectations, e)
+	return nil
+}
+
+// ignoreDiffs returns the sorted and deduplicated token indizes diffs. Indizes
+// beyond the tokens of e are rejected, since verification runs access the
+// tokens by them.
+func ignoreDiffs(e Expectation, diffs []int) ([]int, error) {
+	result := []int{}
+	for _, d := range diffs {
+		if d < 0 || d >= len(e.Tokens) {
+			return nil, fmt.Errorf("%w: token index %d out of range [0, %d)", ErrInvalidEdit, d, len(e.Tokens))
+		}
+		if !contains(result, d) {
+			result = append(result, d)
+		}
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// Reorder arranges the expectations in the order of uuids, which must contain
+// the uuid of each expectation exactly once.
+func (t *Testcase) Reorder(uuids []string) error {
+	if len(uuids) != len(t.Expectations) {
+		return fmt.Errorf("%w: order must contain all %d expectations", ErrInvalidEdit, len(t.Expectations))
+	}
+	reordered := make([]Expectation, 0, len(uuids))
+	seen := make(map[string]bool)
+	for _, uuid := range uuids {
+		i := t.index(uuid)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", ErrExpectationNotFound, uuid)
+		}
+		if seen[uuid] {
+			return fmt.Errorf("%w: duplicate uuid %s", ErrInvalidEdit, uuid)
+		}
+		seen[uuid] = true
+		reordered = append(reordered, t.Expectations[i])
+	}
+	t.Expectations = reordered
+	return nil
+}
+
+// index returns the position of the expectation with the given uuid or -1.
+func (t Testcase) index(uuid string) int {
+	for i, e := range t.Expectations {
+		if e.Uuid == uuid {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func editTestcase() Testcase {
+	return Testcase{
+		Expectations: []Expectation{
+			{Uuid: "e1", Tokens: Tokenize("insert into job values (42)"), Pattern: "insert", IgnoreDiffs: []int{4},
+				IgnoreRules: []IgnoreRule{{Index: 4, Shape: ShapeInteger}}},
+			{Uuid: "e2", Tokens: Tokenize("update job where id=42"), Pattern: "update", IgnoreDiffs: []int{}},
+		},
+		Correlations: []Correlation{{Source: TokenRef{Uuid: "e1", Token: 4}, Target: TokenRef{Uuid: "e2", Token: 3}}},
+	}
+}
+
+func TestEditExpectation(t *testing.T) {
+	disabled, pattern := true, "insert into"
+	tests := []struct {
+		desc        string
+		uuid        string
+		edit        ExpectationEdit
+		err         error
+		disabled    bool
+		pattern     string
+		ignoreDiffs []int
+		ignoreRules []IgnoreRule
+	}{
+		{desc: "disable", uuid: "e1", edit: ExpectationEdit{Disabled: &disabled},
+			disabled: true, pattern: "insert", ignoreDiffs: []int{4}, ignoreRules: []IgnoreRule{{Index: 4, Shape: ShapeInteger}}},
+		{desc: "pattern", uuid: "e1", edit: ExpectationEdit{Pattern: &pattern},
+			pattern: "insert into", ignoreDiffs: []int{4}, ignoreRules: []IgnoreRule{{Index: 4, Shape: ShapeInteger}}},
+		{desc: "ignore diffs drop rules", uuid: "e1", edit: ExpectationEdit{IgnoreDiffs: &[]int{2, 0, 2}},
+			pattern: "insert", ignoreDiffs: []int{0, 2}},
+		{desc: "index out of range", uuid: "e1", edit: ExpectationEdit{IgnoreDiffs: &[]int{5}}, err: ErrInvalidEdit},
+		{desc: "unknown", uuid: "e3", edit: ExpectationEdit{Disabled: &disabled}, err: ErrExpectationNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tc := editTestcase()
+			err := tc.EditExpectation(test.uuid, test.edit)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				assert.Equal(t, editTestcase(), tc)
+				return
+			}
+			assert.NoError(t, err)
+			e, _ := tc.Expectation(test.uuid)
+			assert.Equal(t, test.disabled, e.Disabled)
+			assert.Equal(t, test.pattern, e.Pattern)
+			assert.Equal(t, test.ignoreDiffs, e.IgnoreDiffs)
+			assert.Equal(t, test.ignoreRules, e.IgnoreRules)
+		})
+	}
+}
+
+func TestRemoveExpectation(t *testing.T) {
+	tc := editTestcase()
+	assert.True(t, tc.RemoveExpectation("e1"))
+	assert.Len(t, tc.Expectations, 1)
+	assert.Equal(t, "e2", tc.Expectations[0].Uuid)
+	assert.Empty(t, tc.Correlations)
+	assert.False(t, tc.RemoveExpectation("e1"))
+}
+
+func TestAddExpectation(t *testing.T) {
+	tc := editTestcase()
+	assert.NoError(t, tc.AddExpectation(Expectation{Uuid: "e3", Tokens: Tokenize("delete from job"), Pattern: "delete"}))
+	assert.Equal(t, "e3", tc.Expectations[2].Uuid)
+	assert.Equal(t, []int{}, tc.Expectations[2].IgnoreDiffs)
+	assert.ErrorIs(t, tc.AddExpectation(Expectation{Uuid: "e3", Tokens: Tokenize("delete"), Pattern: "delete"}), ErrInvalidEdit)
+	assert.ErrorIs(t, tc.AddExpectation(Expectation{Uuid: "e4", Pattern: "delete"}), ErrInvalidEdit)
+	assert.ErrorIs(t, tc.AddExpectation(Expectation{Uuid: "e4", Tokens: []string{"x"}, Pattern: "x", IgnoreDiffs: []int{5}}), ErrInvalidEdit)
+	assert.ErrorIs(t, tc.AddExpectation(Expectation{Uuid: "e4", Tokens: []string{"x"}, Pattern: "x", IgnoreDiffs: []int{0},
+		IgnoreRules: []IgnoreRule{{Index: 3, Shape: ShapeInteger}}}), ErrInvalidEdit)
+	assert.Len(t, tc.Expectations, 3)
+
+	assert.NoError(t, tc.AddExpectation(Expectation{Uuid: "e4", Tokens: Tokenize("delete from job where id=1"), Pattern: "delete",
+		IgnoreDiffs: []int{4, 4}, IgnoreRules: []IgnoreRule{{Index: 4, Shape: ShapeInteger}}}))
+	assert.Equal(t, []int{4}, tc.Expectations[3].IgnoreDiffs)
+}
+
+func TestReorder(t *testing.T) {
+	tc := editTestcase()
+	assert.NoError(t, tc.Reorder([]string{"e2", "e1"}))
+	assert.Equal(t, "e2", tc.Expectations[0].Uuid)
+	assert.Equal(t, "e1", tc.Expectations[1].Uuid)
+
+	assert.ErrorIs(t, tc.Reorder([]string{"e1"}), ErrInvalidEdit)
+	assert.ErrorIs(t, tc.Reorder([]string{"e1", "e1"}), ErrInvalidEdit)
+	assert.ErrorIs(t, tc.Reorder([]string{"e1", "e3"}), ErrExpectationNotFound)
+}
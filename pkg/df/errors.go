@@ -0,0 +1,42 @@
+package df
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTestNotFound is returned by a TestRepository if the requested test doesn't
+// exist.
+var ErrTestNotFound = errors.New("test not found")
+
+// ErrConflict is returned by a TestRepository if a test is written based on an
+// outdated revision, i.e. the test has been written by someone else since it
+// was read.
+var ErrConflict = errors.New("test has been modified concurrently")
+
+// ErrRevisionNotFound is returned by a TestRepository if the requested revision
+// of a test doesn't exist or has been dropped.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ErrExpectationNotFound is returned if an expectation to be edited doesn't
+// exist.
+var ErrExpectationNotFound = errors.New("expectation not found")
+
+// ErrInvalidEdit is returned if an edit of a test would make the test
+// inconsistent, e.g. an IgnoreDiffs index beyond the expectation's tokens.
+var ErrInvalidEdit = errors.New("invalid edit")
+
+// ChannelError reports a failure of the log of a channel, e.g. a missing or
+// unreadable log file.
+type ChannelError struct {
+	Channel string
+	Err     error
+}
+
+func (e ChannelError) Error() string {
+	return fmt.Sprintf("channel '%s': %v", e.Channel, e.Err)
+}
+
+func (e ChannelError) Unwrap() error {
+	return e.Err
+}
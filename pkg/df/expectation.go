@@ -19,19 +19,46 @@ import (
 // "Fulfilled" and gets an by one increased "Verified" count. Expectations
 // should be persisted between test runs. Thus their Verified counter increases
 // over time and the overall test quality gains.
+//
+// Channel names the channel the expectation was recorded from. Expectations
+// without channel stem from single channel recordings and are verified against
+// every channel.
+//
+// Statements that reappear several times within a recording and only differ
+// in their literal values are collapsed into a single expectation with Count
+// occurrences. Such an expectation is fulfilled if the number of its
+// Occurrences within a verification run is in the range of Min and Max, which
+// both default to Count.
 type Expectation struct {
 	Uuid      string   `json:"uuid"`
 	Tokens    []string `json:"tokens"`
 	Pattern   string
+	Channel   string `json:"channel,omitempty"`
 	Fulfilled bool
 	Verified  int
 
-	IgnoreDiffs []int `json:"ignoreDiffs"` // indizes of tokens allowed to deviate when comparing two Expectations
+	IgnoreDiffs []int        `json:"ignoreDiffs"`           // indizes of tokens allowed to deviate when comparing two Expectations
+	IgnoreRules []IgnoreRule `json:"ignoreRules,omitempty"` // shapes of the values allowed at the IgnoreDiffs indizes
+
+	Count       int `json:"count,omitempty"`       // number of recorded occurrences of repeated statements
+	Min         int `json:"min,omitempty"`         // minimum number of accepted occurrences
+	Max         int `json:"max,omitempty"`         // maximum number of accepted occurrences
+	Occurrences int `json:"occurrences,omitempty"` // number of occurrences within the last verification run
+
+	// Closest is the actual statement of the last verification run that comes
+	// closest to an unfulfilled expectation
+	Closest string `json:"closest,omitempty"`
+
+	// Disabled expectations are kept within the test but skipped by
+	// verification runs
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 // Equal compares e's tokens with the given tokens. The tokens sets are equal if
 // they have the same length, all their elements are equal or if two elements
-// are unequal but their index is contained in IgnoreDiffs.
+// are unequal but their index is contained in IgnoreDiffs. If an IgnoreRule
+// exists for such an index, the deviating token must be accepted by the rule.
+// Indizes without rule accept any deviation.
 func (e Expectation) Equal(tokens []string) bool {
 	equal := true
 	if len(tokens) != len(e.Tokens) {
@@ -39,7 +66,7 @@ func (e Expectation) Equal(tokens []string) bool {
 	}
 	for i, v := range e.Tokens {
 		if v != tokens[i] {
-			if contains(e.IgnoreDiffs, i) {
+			if contains(e.IgnoreDiffs, i) && e.accepts(i, tokens[i]) {
 				log.WithFields(log.Fields{
 					"index":    i,
 					"expected": v,
@@ -60,6 +87,27 @@ func (e Expectation) Equal(tokens []string) bool {
 	return equal
 }
 
+// accepts returns true if the ignore rule for index i accepts token or if there
+// is no rule for i.
+func (e Expectation) accepts(i int, token string) bool {
+	for _, r := range e.IgnoreRules {
+		if r.Index == i {
+			return r.Accepts(e.Tokens[i], token)
+		}
+	}
+	return true
+}
+
+// InferRules infers an IgnoreRule for each index in diffs by comparing e's
+// tokens with the given tokens.
+func (e Expectation) InferRules(tokens []string, diffs []int) []IgnoreRule {
+	var rules []IgnoreRule
+	for _, i := range diffs {
+		rules = append(rules, InferRule(i, e.Tokens[i], tokens[i]))
+	}
+	return rules
+}
+
 // Diff builds the index set of differences between e.Tokens and tokens.
 func (e Expectation) Diff(tokens []string) ([]int, error) {
 	if len(tokens) != len(e.Tokens) {
@@ -75,6 +123,12 @@ func (e Expectation) Diff(tokens []string) ([]int, error) {
 	return diffs, nil
 }
 
+// BelongsTo returns true if e was recorded from channel or if e was recorded
+// without channel at all.
+func (e Expectation) BelongsTo(channel string) bool {
+	return e.Channel == "" || e.Channel == channel
+}
+
 func contains[T comparable](values []T, value T) bool {
 	for _, v := range values {
 		if v == value {
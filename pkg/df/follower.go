@@ -0,0 +1,169 @@
+package df
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pollInterval limits the time a Follower waits for a change notification
+// before it checks the log file and the done channel again.
+const pollInterval = 250 * time.Millisecond
+
+// A Follower follows a growing log file like "tail -F". It is shared by all log
+// formats. The follower waits for changes of the file by means of the
+// platform's file notification, e.g. inotify on linux, and falls back to
+// polling elsewhere. A rotated log file, i.e. a new file with the same name,
+// is reopened and read from its beginning. A truncated log file is read again
+// from its beginning.
+type Follower struct {
+	name    string
+	file    *os.File
+	info    os.FileInfo // file info of the open file, used to detect rotation
+	reader  *bufio.Reader
+	offset  int64    // number of bytes read from the open file
+	partial string   // incomplete last line, completed by the next read
+	pending []string // lines given back by Unread
+	watcher watcher
+}
+
+// watcher waits for changes of a watched file.
+type watcher interface {
+	// wait returns as soon as the file may have changed or after timeout.
+	wait(timeout time.Duration) error
+	close() error
+}
+
+// NewFollower opens the log file name and positions the read cursor at its
+// beginning.
+func NewFollower(name string) (*Follower, error) {
+	f := &Follower{name: name}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	w, err := newWatcher(name)
+	if err != nil {
+		_ = f.file.Close()
+		return nil, err
+	}
+	f.watcher = w
+	return f, nil
+}
+
+// Name returns the name of the followed log file.
+func (f *Follower) Name() string {
+	return f.name
+}
+
+func (f *Follower) open() error {
+	file, err := os.Open(f.name)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file, f.info = file, info
+	f.reset(0)
+	return nil
+}
+
+// reset discards all buffered data and sets the read cursor to offset.
+func (f *Follower) reset(offset int64) {
+	f.reader = bufio.NewReader(f.file)
+	f.offset = offset
+	f.partial = ""
+	f.pending = nil
+}
+
+// Tail sets the read cursor to the end of the log file without reading its
+// content.
+func (f *Follower) Tail() error {
+	offset, err := f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	f.reset(offset)
+	return nil
+}
+
+// ReadLine returns the next complete line including its terminating \n. Waits
+// until a new line becomes available. Returns with an empty line and a nil
+// error if the done channel was closed.
+func (f *Follower) ReadLine(done <-chan struct{}) (string, error) {
+	if len(f.pending) > 0 {
+		line := f.pending[len(f.pending)-1]
+		f.pending = f.pending[:len(f.pending)-1]
+		return line, nil
+	}
+	for {
+		s, err := f.reader.ReadString('\n')
+		f.offset += int64(len(s))
+		f.partial += s
+		if err == nil {
+			line := f.partial
+			f.partial = ""
+			return line, nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return "", err
+		}
+
+		// -> end of file reached
+		if err := f.reopen(); err != nil {
+			return "", err
+		}
+		select {
+		case <-done:
+			return "", nil
+		default:
+		}
+		if err := f.watcher.wait(pollInterval); err != nil {
+			return "", err
+		}
+	}
+}
+
+// Unread gives line back to the follower, it is returned by the next call to
+// ReadLine.
+func (f *Follower) Unread(line string) {
+	f.pending = append(f.pending, line)
+}
+
+// reopen checks the log file for rotation and truncation. A rotated file is
+// reopened, a truncated file is read again from its beginning.
+func (f *Follower) reopen() error {
+	info, err := os.Stat(f.name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // -> rotated but not yet recreated, keep reading the old file
+	}
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, f.info) {
+		log.Printf("%s rotated, reopening", f.name)
+		return f.open()
+	}
+	if info.Size() < f.offset {
+		log.Printf("%s truncated, reading from start", f.name)
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		f.reset(0)
+	}
+	return nil
+}
+
+// Close closes the log file and stops watching it.
+func (f *Follower) Close() error {
+	return errors.Join(f.watcher.close(), f.file.Close())
+}
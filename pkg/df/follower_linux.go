@@ -0,0 +1,56 @@
+//go:build linux
+
+package df
+
+import (
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyWatcher waits for inotify events of the directory of the watched file.
+// Watching the directory instead of the file itself also reports the creation
+// of a new file after rotation.
+type inotifyWatcher struct {
+	fd  int
+	buf []byte
+}
+
+func newWatcher(name string) (watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint32(unix.IN_MODIFY | unix.IN_CREATE | unix.IN_MOVED_TO | unix.IN_DELETE | unix.IN_CLOSE_WRITE)
+	if _, err := unix.InotifyAddWatch(fd, filepath.Dir(name), mask); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	return &inotifyWatcher{fd: fd, buf: make([]byte, 4096)}, nil
+}
+
+func (w *inotifyWatcher) wait(timeout time.Duration) error {
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err == unix.EINTR {
+		return nil
+	}
+	if err != nil || n == 0 {
+		return err
+	}
+
+	// drain the pending events, the follower checks the file itself
+	for {
+		if _, err := unix.Read(w.fd, w.buf); err != nil {
+			if err == unix.EAGAIN {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (w *inotifyWatcher) close() error {
+	return unix.Close(w.fd)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package df
+
+import "time"
+
+// pollWatcher is used on platforms without file notification support. It just
+// waits for timeout.
+type pollWatcher struct{}
+
+func newWatcher(string) (watcher, error) {
+	return pollWatcher{}, nil
+}
+
+func (pollWatcher) wait(timeout time.Duration) error {
+	time.Sleep(timeout)
+	return nil
+}
+
+func (pollWatcher) close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package df
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendLog(t *testing.T, name string, s string) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// readLine reads the next line of f and fails if no line arrives within 2s.
+func readLine(t *testing.T, f *Follower) string {
+	done := make(chan struct{})
+	timer := time.AfterFunc(2*time.Second, func() { close(done) })
+	defer timer.Stop()
+	line, err := f.ReadLine(done)
+	require.NoError(t, err)
+	return line
+}
+
+func TestFollower(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "general.log")
+	appendLog(t, name, "old 1\nold 2\n")
+	f, err := NewFollower(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	require.NoError(t, f.Tail())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		appendLog(t, name, "new 1\nnew")
+		time.Sleep(50 * time.Millisecond)
+		appendLog(t, name, " 2\n")
+	}()
+	assert.Equal(t, "new 1\n", readLine(t, f))
+	assert.Equal(t, "new 2\n", readLine(t, f))
+
+	f.Unread("new 2\n")
+	assert.Equal(t, "new 2\n", readLine(t, f))
+
+	// truncation
+	require.NoError(t, os.Truncate(name, 0))
+	appendLog(t, name, "truncated\n")
+	assert.Equal(t, "truncated\n", readLine(t, f))
+
+	// rotation
+	require.NoError(t, os.Rename(name, name+".1"))
+	appendLog(t, name, "rotated\n")
+	assert.Equal(t, "rotated\n", readLine(t, f))
+}
+
+func TestFollowerDone(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "general.log")
+	appendLog(t, name, "")
+	f, err := NewFollower(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	done := make(chan struct{})
+	close(done)
+	line, err := f.ReadLine(done)
+	assert.NoError(t, err)
+	assert.Empty(t, line)
+}
@@ -0,0 +1,39 @@
+package df
+
+import "fmt"
+
+// Forbidden describes statements that must never appear during a verification
+// run, e.g. a stray delete or the repeated select of an N+1 query. Pattern uses
+// the same include!exclude syntax as the channel patterns, thus it is either a
+// complete statement or a part of it. An empty channel forbids matching
+// statements on all channels.
+type Forbidden struct {
+	Pattern string `json:"pattern"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// Matches returns true if the statement s logged on channel is forbidden.
+func (f Forbidden) Matches(channel string, s string) bool {
+	if f.Channel != "" && f.Channel != channel {
+		return false
+	}
+	return NewPattern(f.Pattern).matches(s)
+}
+
+func (f Forbidden) String() string {
+	if f.Channel != "" {
+		return fmt.Sprintf("[%s] %s", f.Channel, f.Pattern)
+	}
+	return f.Pattern
+}
+
+// ForbiddenViolation describes a forbidden statement that appeared during a
+// verification run.
+type ForbiddenViolation struct {
+	Forbidden
+	Statement string `json:"statement"`
+}
+
+func (v ForbiddenViolation) String() string {
+	return fmt.Sprintf("%s (forbidden by: %s)", v.Statement, v.Forbidden)
+}
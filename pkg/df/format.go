@@ -0,0 +1,106 @@
+package df
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Format bundles the LogFactory and Tokenizer required to read and split the
+// log of a channel. Channels refer to their format by name, see Channel.Format.
+type Format struct {
+	LogFactory LogFactory
+	Tokenizer  Tokenizer
+}
+
+// Registry maps format names like "mysql" or "postgres" to their Format. In
+// addition, it maps tokenizer names to tokenizers that channels may use instead
+// of the default tokenizer of their format.
+type Registry struct {
+	formats    map[string]Format
+	tokenizers map[string]Tokenizer
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{formats: make(map[string]Format), tokenizers: make(map[string]Tokenizer)}
+}
+
+// Register registers format under name. An already registered format with the
+// same name is replaced.
+func (r *Registry) Register(name string, format Format) {
+	r.formats[name] = format
+}
+
+// RegisterTokenizer registers tokenizer under name.
+func (r *Registry) RegisterTokenizer(name string, tokenizer Tokenizer) {
+	r.tokenizers[name] = tokenizer
+}
+
+// Lookup returns the format referenced by channel.Format. The format's tokenizer
+// is replaced by the tokenizer referenced by channel.Tokenizer, if set.
+func (r *Registry) Lookup(channel Channel) (Format, error) {
+	f, ok := r.formats[channel.Format]
+	if !ok {
+		return Format{}, fmt.Errorf("channel '%s': unknown format '%s', supported formats: %v",
+			channel.Name, channel.Format, r.Names())
+	}
+	if channel.Tokenizer != "" {
+		t, ok := r.tokenizers[channel.Tokenizer]
+		if !ok {
+			return Format{}, fmt.Errorf("channel '%s': unknown tokenizer '%s', supported tokenizers: %v",
+				channel.Name, channel.Tokenizer, keys(r.tokenizers))
+		}
+		f.Tokenizer = t
+	}
+	return f, nil
+}
+
+// Validate ensures that the format of each channel is registered and that the
+// channel's settings are valid for its format.
+func (r *Registry) Validate(channels []Channel) error {
+	for _, ch := range channels {
+		f, err := r.Lookup(ch)
+		if err != nil {
+			return err
+		}
+		if v, ok := f.LogFactory.(ChannelValidator); ok {
+			if err := v.Validate(ch); err != nil {
+				return fmt.Errorf("channel '%s': %w", ch.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Sources looks up the format of each channel and creates the channel's source.
+// The logs of the returned sources must be closed by the caller.
+func (r *Registry) Sources(channels []Channel) ([]Source, error) {
+	var sources []Source
+	for _, ch := range channels {
+		f, err := r.Lookup(ch)
+		if err != nil {
+			return nil, err
+		}
+		l, err := f.LogFactory.Create(ch)
+		if err != nil {
+			CloseSources(sources)
+			return nil, ChannelError{Channel: ch.Name, Err: err}
+		}
+		sources = append(sources, Source{Channel: ch, Log: l, Tokenizer: f.Tokenizer})
+	}
+	return sources, nil
+}
+
+// Names returns the sorted names of all registered formats.
+func (r *Registry) Names() []string {
+	return keys(r.formats)
+}
+
+func keys[T any](m map[string]T) []string {
+	var names []string
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
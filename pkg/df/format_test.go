@@ -0,0 +1,40 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register("mysql", Format{})
+
+	_, err := r.Lookup(Channel{Name: "db", Format: "mysql"})
+	assert.NoError(t, err)
+
+	_, err = r.Lookup(Channel{Name: "db", Format: "oracle"})
+	assert.Error(t, err)
+
+	assert.NoError(t, r.Validate([]Channel{{Format: "mysql"}}))
+	assert.Error(t, r.Validate([]Channel{{Format: "mysql"}, {Format: "postgres"}}))
+}
+
+type tokenizer struct{}
+
+func (tokenizer) Tokenize(string, []string) []string {
+	return nil
+}
+
+func TestRegistryTokenizer(t *testing.T) {
+	r := NewRegistry()
+	r.Register("mysql", Format{})
+	r.RegisterTokenizer("sql", tokenizer{})
+
+	f, err := r.Lookup(Channel{Format: "mysql", Tokenizer: "sql"})
+	assert.NoError(t, err)
+	assert.Equal(t, tokenizer{}, f.Tokenizer)
+
+	_, err = r.Lookup(Channel{Format: "mysql", Tokenizer: "yacc"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package df
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Shape describes the kind of values accepted by an IgnoreRule.
+type Shape string
+
+const (
+	ShapeAny       Shape = "any"       // accepts every value, used when no common shape was found
+	ShapeInteger   Shape = "integer"   // e.g. 42
+	ShapeNumber    Shape = "number"    // e.g. 42.5
+	ShapeUUID      Shape = "uuid"      // e.g. 023a6a95-6c8a-4483-bcfb-17b1c58c317f
+	ShapeTimestamp Shape = "timestamp" // ISO date, time or timestamp, e.g. 2024-04-08 14:48:15
+	ShapeRange     Shape = "range"     // number between Min and Max
+	ShapeRegex     Shape = "regex"     // value matching Regex
+)
+
+var (
+	integerRegex   = regexp.MustCompile(`^-?\d+$`)
+	numberRegex    = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][-+]?\d+)?$`)
+	uuidRegex      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	timestampRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?)?(Z|[+-]\d{2}(:?\d{2})?)?$`)
+)
+
+// IgnoreRule restricts the values a deviating token at Index may take. Rules are
+// inferred from the recorded and the reference token during the first
+// verification run, see InferRule. Only the plain value of a token may deviate,
+// surrounding brackets, separators and comparisons must remain equal.
+type IgnoreRule struct {
+	Index int     `json:"index"`
+	Shape Shape   `json:"shape"`
+	Min   float64 `json:"min,omitempty"`   // lower bound of ShapeRange
+	Max   float64 `json:"max,omitempty"`   // upper bound of ShapeRange
+	Regex string  `json:"regex,omitempty"` // expression of ShapeRegex
+}
+
+// InferRule infers the rule for index from the recorded token expected and the
+// deviating token actual. The rule's shape is the most specific shape both
+// values share. Integers are usually ids or counters that keep growing, thus
+// only fractional numbers, e.g. prices, are restricted to a range, see
+// inferRange.
+func InferRule(index int, expected, actual string) IgnoreRule {
+	rule := IgnoreRule{Index: index, Shape: ShapeAny}
+	ep, ev, es := splitValue(expected)
+	ap, av, as := splitValue(actual)
+	if ep != ap || es != as || quoted(ev) != quoted(av) {
+		return rule
+	}
+	ev, av = unquote(ev), unquote(av)
+	if !integerRegex.MatchString(ev) || !integerRegex.MatchString(av) {
+		if r, ok := inferRange(ev, av); ok {
+			r.Index = index
+			return r
+		}
+	}
+	for _, s := range []struct {
+		shape Shape
+		regex *regexp.Regexp
+	}{
+		{ShapeInteger, integerRegex},
+		{ShapeNumber, numberRegex},
+		{ShapeUUID, uuidRegex},
+		{ShapeTimestamp, timestampRegex},
+	} {
+		if s.regex.MatchString(ev) && s.regex.MatchString(av) {
+			rule.Shape = s.shape
+			return rule
+		}
+	}
+	if r := generalize(ev); r == generalize(av) && len(ev) > 0 {
+		rule.Shape = ShapeRegex
+		rule.Regex = r
+	}
+	return rule
+}
+
+// inferRange returns a ShapeRange rule if both values are numbers. The range
+// covers the decimal order of magnitude of the larger value, e.g. 3.5 and 4.2
+// become [0, 10] and -0.25 and 0.5 become [-1, 1].
+func inferRange(expected, actual string) (IgnoreRule, bool) {
+	if !numberRegex.MatchString(expected) || !numberRegex.MatchString(actual) {
+		return IgnoreRule{}, false
+	}
+	e, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return IgnoreRule{}, false
+	}
+	a, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return IgnoreRule{}, false
+	}
+	magnitude := 1.0
+	if m := math.Max(math.Abs(e), math.Abs(a)); m > 0 {
+		magnitude = math.Pow(10, math.Floor(math.Log10(m))+1)
+	}
+	rule := IgnoreRule{Shape: ShapeRange, Max: magnitude}
+	if e < 0 || a < 0 {
+		rule.Min = -magnitude
+	}
+	return rule, true
+}
+
+// Accepts returns true if actual may replace the recorded token expected.
+func (r IgnoreRule) Accepts(expected, actual string) bool {
+	if r.Shape == ShapeAny || r.Shape == "" {
+		return true
+	}
+	ep, ev, es := splitValue(expected)
+	ap, av, as := splitValue(actual)
+	if ep != ap || es != as || quoted(ev) != quoted(av) {
+		return false
+	}
+	av = unquote(av)
+	switch r.Shape {
+	case ShapeInteger:
+		return integerRegex.MatchString(av)
+	case ShapeNumber:
+		return numberRegex.MatchString(av)
+	case ShapeUUID:
+		return uuidRegex.MatchString(av)
+	case ShapeTimestamp:
+		return timestampRegex.MatchString(av)
+	case ShapeRange:
+		f, err := strconv.ParseFloat(av, 64)
+		return err == nil && f >= r.Min && f <= r.Max
+	case ShapeRegex:
+		re := compile(r.Regex)
+		return re != nil && re.MatchString(av)
+	}
+	return false
+}
+
+// regexes caches the compiled expressions of ShapeRegex rules, thus each
+// expression is compiled once instead of once per compared token.
+var regexes sync.Map // expression -> *regexp.Regexp, nil if invalid
+
+// compile returns the compiled expression or nil if expression is invalid.
+func compile(expression string) *regexp.Regexp {
+	if re, ok := regexes.Load(expression); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		re = nil
+	}
+	regexes.Store(expression, re)
+	return re
+}
+
+func (r IgnoreRule) String() string {
+	switch r.Shape {
+	case ShapeRange:
+		return fmt.Sprintf("%d: %s [%g, %g]", r.Index, r.Shape, r.Min, r.Max)
+	case ShapeRegex:
+		return fmt.Sprintf("%d: %s %s", r.Index, r.Shape, r.Regex)
+	}
+	return fmt.Sprintf("%d: %s", r.Index, r.Shape)
+}
+
+// splitValue splits token into the plain value and its surrounding prefix and
+// suffix. Example: "(id=12)," becomes "(id=", "12" and "),".
+func splitValue(token string) (string, string, string) {
+	v := strings.TrimRight(token, ",;)")
+	suffix := token[len(v):]
+	trimmed := strings.TrimLeft(v, "(")
+	prefix := v[:len(v)-len(trimmed)]
+	v = trimmed
+	if i := strings.LastIndexAny(v, "=<>"); i > -1 {
+		prefix = prefix + v[:i+1]
+		v = v[i+1:]
+	}
+	return prefix, v, suffix
+}
+
+func quoted(v string) bool {
+	return len(v) > 1 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'")
+}
+
+func unquote(v string) string {
+	if quoted(v) {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// generalize builds a regular expression that replaces each run of letters and
+// digits of v by its character class.
+func generalize(v string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	last := ""
+	for _, r := range v {
+		switch {
+		case r >= '0' && r <= '9':
+			if last != `\d+` {
+				last = `\d+`
+				b.WriteString(last)
+			}
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			if last != `[A-Za-z]+` {
+				last = `[A-Za-z]+`
+				b.WriteString(last)
+			}
+		default:
+			last = ""
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
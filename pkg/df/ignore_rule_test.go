@@ -0,0 +1,73 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferRule(t *testing.T) {
+	tests := []struct {
+		desc     string
+		expected string
+		actual   string
+		rule     IgnoreRule
+	}{
+		{desc: "integer", expected: "3)", actual: "4)", rule: IgnoreRule{Shape: ShapeInteger}},
+		{desc: "integer with comparison", expected: "id=3", actual: "id=14", rule: IgnoreRule{Shape: ShapeInteger}},
+		{desc: "range", expected: "3.5,", actual: "4,", rule: IgnoreRule{Shape: ShapeRange, Max: 10}},
+		{desc: "negative range", expected: "price=-0.25", actual: "price=0.5", rule: IgnoreRule{Shape: ShapeRange, Min: -1, Max: 1}},
+		{desc: "quoted range", expected: "'149.90'", actual: "'99.5'", rule: IgnoreRule{Shape: ShapeRange, Max: 1000}},
+		{desc: "number", expected: "1e400,", actual: "4.5,", rule: IgnoreRule{Shape: ShapeNumber}},
+		{desc: "uuid", expected: "'023a6a95-6c8a-4483-bcfb-17b1c58c317f'", actual: "'8f6c2d4e-1b7a-4c3e-9f0d-2a5b6c7d8e9f'", rule: IgnoreRule{Shape: ShapeUUID}},
+		{desc: "timestamp", expected: "2024-04-17 15:55:56,", actual: "2024-04-17 15:56:56,", rule: IgnoreRule{Shape: ShapeTimestamp}},
+		{desc: "regex", expected: "JOB-12", actual: "JOB-1234", rule: IgnoreRule{Shape: ShapeRegex, Regex: `^[A-Za-z]+-\d+$`}},
+		{desc: "json field", expected: "owner.id=7", actual: "owner.id=12", rule: IgnoreRule{Shape: ShapeInteger}},
+		{desc: "different shapes", expected: "Hello,", actual: "4711,", rule: IgnoreRule{Shape: ShapeAny}},
+		{desc: "different prefix", expected: "id=3", actual: "job_id=3", rule: IgnoreRule{Shape: ShapeAny}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.rule, InferRule(0, test.expected, test.actual))
+		})
+	}
+}
+
+func TestIgnoreRuleAccepts(t *testing.T) {
+	tests := []struct {
+		desc     string
+		rule     IgnoreRule
+		expected string
+		actual   string
+		accepts  bool
+	}{
+		{desc: "integer", rule: IgnoreRule{Shape: ShapeInteger}, expected: "3)", actual: "42)", accepts: true},
+		{desc: "integer rejects null", rule: IgnoreRule{Shape: ShapeInteger}, expected: "3)", actual: "null)", accepts: false},
+		{desc: "timestamp rejects string", rule: IgnoreRule{Shape: ShapeTimestamp}, expected: "'2024-04-17 15:55:56'", actual: "'Hello'", accepts: false},
+		{desc: "timestamp rejects unquoted null", rule: IgnoreRule{Shape: ShapeTimestamp}, expected: "'2024-04-17 15:55:56'", actual: "null", accepts: false},
+		{desc: "range", rule: IgnoreRule{Shape: ShapeRange, Min: 1, Max: 10}, expected: "1", actual: "10", accepts: true},
+		{desc: "range exceeded", rule: IgnoreRule{Shape: ShapeRange, Min: 1, Max: 10}, expected: "1", actual: "11", accepts: false},
+		{desc: "regex", rule: IgnoreRule{Shape: ShapeRegex, Regex: `^JOB-\d+$`}, expected: "JOB-1", actual: "JOB-77", accepts: true},
+		{desc: "invalid regex", rule: IgnoreRule{Shape: ShapeRegex, Regex: `^JOB-(\d+$`}, expected: "JOB-1", actual: "JOB-77", accepts: false},
+		{desc: "any", rule: IgnoreRule{Shape: ShapeAny}, expected: "1", actual: "Hello", accepts: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.accepts, test.rule.Accepts(test.expected, test.actual))
+		})
+	}
+}
+
+func TestEqualConsidersIgnoreRules(t *testing.T) {
+	e := Expectation{
+		Tokens:      Tokenize("update job set published_timestamp='2024-04-17 15:55:58' where id=3"),
+		IgnoreDiffs: []int{3, 5},
+		IgnoreRules: []IgnoreRule{{Index: 3, Shape: ShapeTimestamp}},
+	}
+	assert.True(t, e.Equal(Tokenize("update job set published_timestamp='2024-04-18 10:00:00' where id=4")))
+	assert.False(t, e.Equal(Tokenize("update job set published_timestamp=null where id=4")))
+
+	// legacy expectations without rules accept any deviation
+	e.IgnoreRules = nil
+	assert.True(t, e.Equal(Tokenize("update job set published_timestamp=null where id=4")))
+}
@@ -9,6 +9,6 @@ import (
 type Log interface {
 	Timestamp(s string) (time.Time, error)
 	NextLine(done chan struct{}) (string, error)
-	Close()
+	Close() error
 	Tail() error
 }
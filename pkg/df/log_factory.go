@@ -1,5 +1,12 @@
 package df
 
+// LogFactory creates the Log of a channel.
 type LogFactory interface {
-	Create(filename string) Log
+	Create(channel Channel) (Log, error)
+}
+
+// A ChannelValidator is a LogFactory that validates the format specific
+// settings of a channel before any log is created, see Registry.Validate.
+type ChannelValidator interface {
+	Validate(channel Channel) error
 }
@@ -0,0 +1,76 @@
+package df
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ordering defines whether and how the recorded order of a testcase's
+// expectations is enforced during verification.
+type Ordering string
+
+const (
+	// OrderingNone accepts expectations in any order.
+	OrderingNone Ordering = ""
+
+	// OrderingStrict requires all expectations to be fulfilled in recorded
+	// order.
+	OrderingStrict Ordering = "strict"
+
+	// OrderingTable requires only expectations that touch the same table to be
+	// fulfilled in recorded order.
+	OrderingTable Ordering = "table"
+)
+
+// ParseOrdering converts s into an Ordering. An empty string results in
+// OrderingNone.
+func ParseOrdering(s string) (Ordering, error) {
+	switch o := Ordering(s); o {
+	case OrderingNone, OrderingStrict, OrderingTable:
+		return o, nil
+	}
+	return OrderingNone, fmt.Errorf("unknown ordering '%s', allowed: strict | table", s)
+}
+
+// Group returns the key of the group whose expectations must be fulfilled in
+// recorded order. Expectations of different groups may be fulfilled in any
+// order relative to each other. Since channels are read concurrently, ordering
+// is only enforced within a channel. Returns false if e isn't subject to
+// ordering at all.
+func (o Ordering) Group(e Expectation) (string, bool) {
+	switch o {
+	case OrderingStrict:
+		return e.Channel, true
+	case OrderingTable:
+		return e.Channel + "/" + Table(e.Tokens), true
+	}
+	return "", false
+}
+
+// OrderViolation describes a statement that fulfilled its expectation out of
+// recorded order.
+type OrderViolation struct {
+	Uuid      string `json:"uuid"`
+	Statement string `json:"statement"`
+	Expected  int    `json:"expected"` // position of the expectation within the recording
+	Actual    int    `json:"actual"`   // position of the statement within the verification run
+}
+
+func (v OrderViolation) String() string {
+	return fmt.Sprintf("%s (expected position: %d, actual position: %d)", v.Statement, v.Expected, v.Actual)
+}
+
+// Table returns the name of the table the tokenized statement operates on, that
+// is the token succeeding the first "into", "update" or "from" keyword. Returns
+// an empty string if no table was found.
+func Table(tokens []string) string {
+	for i, t := range tokens {
+		switch strings.ToLower(t) {
+		case "into", "update", "from":
+			if i+1 < len(tokens) {
+				return strings.ToLower(strings.Trim(tokens[i+1], "`\"(),;"))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{s: "insert into job (description, id) values ('World', 3)", expected: "job"},
+		{s: "update job set description='World' where id=1", expected: "job"},
+		{s: "delete from application where id=1", expected: "application"},
+		{s: "select job0_.id as id1_0_ from job job0_ where job0_.id=1", expected: "job"},
+		{s: "commit", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			assert.Equal(t, test.expected, Table(Tokenize(test.s)))
+		})
+	}
+}
+
+func TestParseOrdering(t *testing.T) {
+	o, err := ParseOrdering("table")
+	assert.NoError(t, err)
+	assert.Equal(t, OrderingTable, o)
+
+	o, err = ParseOrdering("")
+	assert.NoError(t, err)
+	assert.Equal(t, OrderingNone, o)
+
+	_, err = ParseOrdering("random")
+	assert.Error(t, err)
+}
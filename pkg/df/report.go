@@ -6,39 +6,164 @@ import (
 	"time"
 )
 
+// ChannelReport summarizes the verification results of a single channel.
+type ChannelReport struct {
+	Name         string `json:"name"`
+	Expectations int    `json:"expectations"`
+	Fulfilled    int    `json:"fulfilled"`
+}
+
+// ExpectationResult describes the result of a single expectation within a
+// verification run.
+type ExpectationResult struct {
+	Uuid        string `json:"uuid"`
+	Statement   string `json:"statement"`
+	Channel     string `json:"channel,omitempty"`
+	Fulfilled   bool   `json:"fulfilled"`
+	Verified    int    `json:"verified"`
+	Cardinality string `json:"cardinality,omitempty"` // expected and actual occurrences of repeated statements
+	Closest     string `json:"closest,omitempty"`     // closest actual statement of an unfulfilled expectation
+}
+
 type Report struct {
-	Testname               string        `json:"testname"`
-	LastExecution          time.Time     `json:"last_execution"`
-	Verifications          int           `json:"verifications"`
-	Expectations           int           `json:"expectations"`
-	Fulfilled              int           `json:"fulfilled"`
-	Unfulfilled            []Expectation `json:"unfulfilled,omitempty"`
-	VerificationMean       float32       `json:"verification_mean"`
-	AdditionalExpectations []string      `json:"additional_expectations,omitempty"`
+	Testname               string                 `json:"testname"`
+	LastExecution          time.Time              `json:"last_execution"`
+	Verifications          int                    `json:"verifications"`
+	Expectations           int                    `json:"expectations"`
+	Fulfilled              int                    `json:"fulfilled"`
+	Unfulfilled            []Expectation          `json:"unfulfilled,omitempty"`
+	VerificationMean       float32                `json:"verification_mean"`
+	AdditionalExpectations []string               `json:"additional_expectations,omitempty"`
+	Channels               []ChannelReport        `json:"channels,omitempty"`
+	OutOfOrder             []OrderViolation       `json:"out_of_order,omitempty"`
+	BrokenDataFlow         []CorrelationViolation `json:"broken_data_flow,omitempty"`
+	Forbidden              []ForbiddenViolation   `json:"forbidden,omitempty"`
+	Results                []ExpectationResult    `json:"results,omitempty"`
+
+	// Passed is true if all expectations were fulfilled in valid order, no data
+	// flow was broken and no forbidden statement appeared
+	Passed bool `json:"passed"`
+}
+
+// NewReport creates a report of the last verification run of tc. The
+// additional expectations are taken from the latest run in the history of tc.
+// Disabled expectations aren't reported.
+func NewReport(tc Testcase) Report {
+	report := Report{
+		Testname:       tc.Name,
+		LastExecution:  tc.LastExecution,
+		Verifications:  tc.Verifications,
+		OutOfOrder:     tc.OrderViolations,
+		BrokenDataFlow: tc.BrokenCorrelations,
+		Forbidden:      tc.ForbiddenViolations,
+	}
+	verifiedSum := 0
+	channels := make(map[string]int) // channel name -> index in report.Channels
+	for _, e := range tc.Expectations {
+		if e.Disabled {
+			continue
+		}
+		report.Expectations++
+		verifiedSum += e.Verified
+		if e.Fulfilled {
+			report.Fulfilled++
+		} else {
+			report.Unfulfilled = append(report.Unfulfilled, e)
+		}
+		if e.Channel != "" {
+			i, ok := channels[e.Channel]
+			if !ok {
+				i = len(report.Channels)
+				channels[e.Channel] = i
+				report.Channels = append(report.Channels, ChannelReport{Name: e.Channel})
+			}
+			report.Channels[i].Expectations++
+			if e.Fulfilled {
+				report.Channels[i].Fulfilled++
+			}
+		}
+		result := ExpectationResult{
+			Uuid:      e.Uuid,
+			Statement: e.String(),
+			Channel:   e.Channel,
+			Fulfilled: e.Fulfilled,
+			Verified:  e.Verified,
+			Closest:   e.Closest,
+		}
+		if e.Repeated() {
+			result.Cardinality = e.Cardinality()
+		}
+		report.Results = append(report.Results, result)
+	}
+	if report.Expectations > 0 {
+		report.VerificationMean = float32(verifiedSum) / float32(report.Expectations)
+	}
+	if len(tc.Runs) > 0 {
+		report.AdditionalExpectations = tc.Runs[len(tc.Runs)-1].Additional
+	}
+	report.Passed = len(report.Unfulfilled) == 0 && len(report.OutOfOrder) == 0 &&
+		len(report.BrokenDataFlow) == 0 && len(report.Forbidden) == 0
+	return report
 }
 
 func (r Report) String() string {
 
 	return fmt.Sprintf("Testname: %s\n"+
+		"Passed: %t\n"+
 		"Last execution: %s\n"+
 		"Verfications: %d\n"+
 		"Expectations: %d\n"+
 		"Fulfilled: %d\n"+
 		"Verification mean: %f\n"+
-		"Unfulfilled: %s\n",
+		"%s"+
+		"Unfulfilled: %s\n"+
+		"Out of order: %s\n"+
+		"Broken data flow: %s\n"+
+		"Forbidden: %s\n",
 		r.Testname,
+		r.Passed,
 		r.LastExecution.Format(time.DateTime),
 		r.Verifications,
 		r.Expectations,
 		r.Fulfilled,
 		r.VerificationMean,
-		strings.Join(toString(r.Unfulfilled), "\n"))
+		channelsToString(r.Channels),
+		strings.Join(toString(r.Unfulfilled), "\n"),
+		strings.Join(violationsToString(r.OutOfOrder), "\n"),
+		strings.Join(violationsToString(r.BrokenDataFlow), "\n"),
+		strings.Join(violationsToString(r.Forbidden), "\n"))
+}
+
+func channelsToString(channels []ChannelReport) string {
+	s := ""
+	for _, c := range channels {
+		s = s + fmt.Sprintf("Channel %s: %d of %d fulfilled\n", c.Name, c.Fulfilled, c.Expectations)
+	}
+	return s
 }
 
 func toString(e []Expectation) []string {
 	var result []string
 	for _, e := range e {
-		result = append(result, e.Shorten(6))
+		s := e.Shorten(6)
+		if e.Channel != "" {
+			s = fmt.Sprintf("[%s] %s", e.Channel, s)
+		}
+		if e.Repeated() {
+			s = fmt.Sprintf("%s (%s)", s, e.Cardinality())
+		}
+		if e.Closest != "" {
+			s = fmt.Sprintf("%s (closest: %s)", s, e.Closest)
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func violationsToString[T fmt.Stringer](violations []T) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.String())
 	}
 	return result
 }
@@ -0,0 +1,149 @@
+package df
+
+import (
+	"reflect"
+	"time"
+)
+
+// MaxRevisions limits the number of revisions a TestRepository keeps of each
+// test. Older revisions are dropped.
+const MaxRevisions = 100
+
+// Kinds of changes that lead to a new revision of a test.
+const (
+	ChangeRecording    = "recording"
+	ChangeVerification = "verification"
+	ChangeEdit         = "edit"
+	ChangeRebaseline   = "rebaseline"
+	ChangeRollback     = "rollback"
+	ChangeMigration    = "migration"
+)
+
+// Revision describes a kept revision of a test.
+type Revision struct {
+	Number       int       `json:"number"`
+	Written      time.Time `json:"written"`
+	Change       string    `json:"change,omitempty"`
+	Expectations int       `json:"expectations"` // number of expectations
+}
+
+// Rollback returns the test revision as replacement of the test current. The
+// expectations, correlations, forbidden statements and baselines are taken
+// from revision, while the run history remains the one of current. The result
+// is based on current's revision, thus it can be written over current.
+func Rollback(current Testcase, revision Testcase) Testcase {
+	restored := revision
+	restored.Revision = current.Revision
+	restored.Change = ChangeRollback
+	restored.Verifications = current.Verifications
+	restored.LastExecution = current.LastExecution
+	restored.Runs = current.Runs
+	restored.AdditionalExpectations = current.AdditionalExpectations
+	return restored
+}
+
+// RevisionDiff describes the differences between two revisions of a test.
+// Expectations are compared by their uuid, the results of verification runs,
+// e.g. Fulfilled or Verified, are ignored.
+type RevisionDiff struct {
+	From                int                 `json:"from"`
+	To                  int                 `json:"to"`
+	Added               []Expectation       `json:"added,omitempty"`
+	Removed             []Expectation       `json:"removed,omitempty"`
+	Changed             []ExpectationChange `json:"changed,omitempty"`
+	AddedCorrelations   []Correlation       `json:"added_correlations,omitempty"`
+	RemovedCorrelations []Correlation       `json:"removed_correlations,omitempty"`
+	AddedForbidden      []Forbidden         `json:"added_forbidden,omitempty"`
+	RemovedForbidden    []Forbidden         `json:"removed_forbidden,omitempty"`
+}
+
+// ExpectationChange describes an expectation that differs between two
+// revisions. Fields names the changed fields, e.g. "ignoreDiffs".
+type ExpectationChange struct {
+	Uuid   string      `json:"uuid"`
+	Fields []string    `json:"fields"`
+	Before Expectation `json:"before"`
+	After  Expectation `json:"after"`
+}
+
+// Diff compares the revisions from and to of a test. The position of an
+// expectation counts as changed if its position among the expectations
+// contained in both revisions differs.
+func Diff(from, to Testcase) RevisionDiff {
+	diff := RevisionDiff{From: from.Revision, To: to.Revision}
+	fromOrder, toOrder := commonOrder(from, to), commonOrder(to, from)
+	for _, after := range to.Expectations {
+		i := from.index(after.Uuid)
+		if i < 0 {
+			diff.Added = append(diff.Added, after)
+			continue
+		}
+		before := from.Expectations[i]
+		fields := changedFields(before, after)
+		if fromOrder[after.Uuid] != toOrder[after.Uuid] {
+			fields = append(fields, "position")
+		}
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, ExpectationChange{Uuid: after.Uuid, Fields: fields, Before: before, After: after})
+		}
+	}
+	for _, before := range from.Expectations {
+		if to.index(before.Uuid) < 0 {
+			diff.Removed = append(diff.Removed, before)
+		}
+	}
+	diff.AddedCorrelations, diff.RemovedCorrelations = compare(from.Correlations, to.Correlations)
+	diff.AddedForbidden, diff.RemovedForbidden = compare(from.Forbidden, to.Forbidden)
+	return diff
+}
+
+// commonOrder returns the position of each expectation of a among the
+// expectations of a that are contained in b as well.
+func commonOrder(a, b Testcase) map[string]int {
+	order := make(map[string]int)
+	for _, e := range a.Expectations {
+		if b.index(e.Uuid) >= 0 {
+			order[e.Uuid] = len(order)
+		}
+	}
+	return order
+}
+
+// changedFields returns the names of the fields that differ between the
+// definitions of the expectations a and b.
+func changedFields(a, b Expectation) []string {
+	var fields []string
+	for _, f := range []struct {
+		name  string
+		equal bool
+	}{
+		{"tokens", reflect.DeepEqual(a.Tokens, b.Tokens)},
+		{"pattern", a.Pattern == b.Pattern},
+		{"channel", a.Channel == b.Channel},
+		{"ignoreDiffs", len(a.IgnoreDiffs) == 0 && len(b.IgnoreDiffs) == 0 || reflect.DeepEqual(a.IgnoreDiffs, b.IgnoreDiffs)},
+		{"ignoreRules", len(a.IgnoreRules) == 0 && len(b.IgnoreRules) == 0 || reflect.DeepEqual(a.IgnoreRules, b.IgnoreRules)},
+		{"cardinality", a.Count == b.Count && a.Min == b.Min && a.Max == b.Max},
+		{"disabled", a.Disabled == b.Disabled},
+	} {
+		if !f.equal {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// compare returns the values that are only contained in to respectively only
+// in from.
+func compare[T comparable](from, to []T) (added []T, removed []T) {
+	for _, v := range to {
+		if !contains(from, v) {
+			added = append(added, v)
+		}
+	}
+	for _, v := range from {
+		if !contains(to, v) {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,69 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	from := Testcase{
+		Revision: 3,
+		Expectations: []Expectation{
+			{Uuid: "e1", Tokens: Tokenize("insert into job values (1)"), Pattern: "insert", IgnoreDiffs: []int{}},
+			{Uuid: "e2", Tokens: Tokenize("update job where id=1"), Pattern: "update"},
+			{Uuid: "e3", Tokens: Tokenize("select * from job"), Pattern: "select"},
+		},
+		Forbidden: []Forbidden{{Pattern: "delete"}},
+	}
+	to := Testcase{
+		Revision: 5,
+		Expectations: []Expectation{
+			{Uuid: "e0", Tokens: Tokenize("select 1"), Pattern: "select"},
+			{Uuid: "e2", Tokens: Tokenize("update job where id=1"), Pattern: "update", IgnoreDiffs: []int{3}, Verified: 2},
+			{Uuid: "e1", Tokens: Tokenize("insert into job values (1)"), Pattern: "insert", Fulfilled: true},
+		},
+		Correlations: []Correlation{{Source: TokenRef{Uuid: "e1", Token: 4}, Target: TokenRef{Uuid: "e2", Token: 3}}},
+	}
+
+	diff := Diff(from, to)
+	assert.Equal(t, 3, diff.From)
+	assert.Equal(t, 5, diff.To)
+	assert.Equal(t, []Expectation{to.Expectations[0]}, diff.Added)
+	assert.Equal(t, []Expectation{from.Expectations[2]}, diff.Removed)
+	assert.Len(t, diff.Changed, 2)
+	assert.Equal(t, "e2", diff.Changed[0].Uuid)
+	assert.Equal(t, []string{"ignoreDiffs", "position"}, diff.Changed[0].Fields)
+	assert.Equal(t, "e1", diff.Changed[1].Uuid)
+	assert.Equal(t, []string{"position"}, diff.Changed[1].Fields)
+	assert.Equal(t, to.Correlations, diff.AddedCorrelations)
+	assert.Empty(t, diff.RemovedCorrelations)
+	assert.Empty(t, diff.AddedForbidden)
+	assert.Equal(t, from.Forbidden, diff.RemovedForbidden)
+
+	assert.Equal(t, RevisionDiff{From: 5, To: 5}, Diff(to, to))
+}
+
+func TestRollback(t *testing.T) {
+	revision := Testcase{
+		Name:          "create-job",
+		Revision:      2,
+		Change:        ChangeRecording,
+		Verifications: 1,
+		Expectations:  []Expectation{{Uuid: "e1", IgnoreDiffs: []int{}}},
+	}
+	current := Testcase{
+		Name:          "create-job",
+		Revision:      4,
+		Change:        ChangeVerification,
+		Verifications: 3,
+		Expectations:  []Expectation{{Uuid: "e1", IgnoreDiffs: []int{0, 1}}},
+		Runs:          []Run{{Passed: true}, {Passed: true}},
+	}
+	restored := Rollback(current, revision)
+	assert.Equal(t, 4, restored.Revision)
+	assert.Equal(t, ChangeRollback, restored.Change)
+	assert.Equal(t, 3, restored.Verifications)
+	assert.Equal(t, current.Runs, restored.Runs)
+	assert.Equal(t, revision.Expectations, restored.Expectations)
+}
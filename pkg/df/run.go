@@ -0,0 +1,81 @@
+package df
+
+import "time"
+
+// MaxRuns limits the number of verification runs kept in the history of a
+// test. Older runs are dropped.
+const MaxRuns = 100
+
+// Driver outcomes of a verification run. The outcome of runs without UI
+// driver, e.g. runs with manual UI interactions, stays empty.
+const (
+	DriverPassed = "passed"
+	DriverFailed = "failed"
+)
+
+// Run represents a single verification run within the history of a test.
+type Run struct {
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	Duration    time.Duration `json:"duration"`
+	Fulfilled   []string      `json:"fulfilled,omitempty"`   // uuids of the fulfilled expectations
+	Unfulfilled []string      `json:"unfulfilled,omitempty"` // uuids of the unfulfilled expectations
+	Additional  []string      `json:"additional,omitempty"`  // statements that didn't match any expectation
+	Passed      bool          `json:"passed"`
+	Driver      string        `json:"driver,omitempty"` // outcome of the UI driver that executed the use case
+}
+
+// AddRun appends r to the run history of t and drops the oldest runs if the
+// history exceeds MaxRuns.
+func (t *Testcase) AddRun(r Run) {
+	t.Runs = append(t.Runs, r)
+	if len(t.Runs) > MaxRuns {
+		t.Runs = t.Runs[len(t.Runs)-MaxRuns:]
+	}
+}
+
+// ExpectationHistory summarizes the results of a single expectation over the
+// run history of its test.
+type ExpectationHistory struct {
+	Uuid      string `json:"uuid"`
+	Statement string `json:"statement"`
+	Runs      int    `json:"runs"`      // number of runs the expectation took part in
+	Fulfilled int    `json:"fulfilled"` // number of runs that fulfilled the expectation
+	Trend     []bool `json:"trend"`     // results of the runs in chronological order
+	Flakiness int    `json:"flakiness"` // percentage of succeeding runs with a changed result
+	Flaky     bool   `json:"flaky"`
+}
+
+// History returns the history of each expectation of t.
+func (t Testcase) History() []ExpectationHistory {
+	var history []ExpectationHistory
+	for _, e := range t.Expectations {
+		h := ExpectationHistory{Uuid: e.Uuid, Statement: e.Shorten(6)}
+		flips := 0
+		for _, r := range t.Runs {
+			var fulfilled bool
+			switch {
+			case contains(r.Fulfilled, e.Uuid):
+				fulfilled = true
+			case contains(r.Unfulfilled, e.Uuid):
+				fulfilled = false
+			default:
+				continue // -> expectation didn't exist at the time of r
+			}
+			if len(h.Trend) > 0 && h.Trend[len(h.Trend)-1] != fulfilled {
+				flips++
+			}
+			h.Trend = append(h.Trend, fulfilled)
+			h.Runs++
+			if fulfilled {
+				h.Fulfilled++
+			}
+		}
+		if h.Runs > 1 {
+			h.Flakiness = flips * 100 / (h.Runs - 1)
+		}
+		h.Flaky = flips > 0
+		history = append(history, h)
+	}
+	return history
+}
@@ -0,0 +1,36 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddRun(t *testing.T) {
+	tc := Testcase{}
+	for i := 0; i < MaxRuns+5; i++ {
+		tc.AddRun(Run{Additional: []string{string(rune('a' + i%26))}})
+	}
+	assert.Len(t, tc.Runs, MaxRuns)
+	assert.Equal(t, []string{"f"}, tc.Runs[0].Additional)
+}
+
+func TestHistory(t *testing.T) {
+	tc := Testcase{
+		Expectations: []Expectation{
+			{Uuid: "e1", Tokens: Tokenize("insert into job")},
+			{Uuid: "e2", Tokens: Tokenize("update job")},
+			{Uuid: "e3", Tokens: Tokenize("delete from job")},
+		},
+		Runs: []Run{
+			{Fulfilled: []string{"e1", "e2"}},
+			{Fulfilled: []string{"e1"}, Unfulfilled: []string{"e2"}},
+			{Fulfilled: []string{"e1", "e2", "e3"}},
+		},
+	}
+	assert.Equal(t, []ExpectationHistory{
+		{Uuid: "e1", Statement: "insert into job", Runs: 3, Fulfilled: 3, Trend: []bool{true, true, true}},
+		{Uuid: "e2", Statement: "update job", Runs: 3, Fulfilled: 2, Trend: []bool{true, false, true}, Flakiness: 100, Flaky: true},
+		{Uuid: "e3", Statement: "delete from job", Runs: 1, Fulfilled: 1, Trend: []bool{true}},
+	}, tc.History())
+}
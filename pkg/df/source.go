@@ -0,0 +1,96 @@
+package df
+
+import (
+	"errors"
+	"sync"
+)
+
+// A Source connects a Channel with the Log and Tokenizer used to read and split
+// its statements.
+type Source struct {
+	Channel   Channel
+	Log       Log
+	Tokenizer Tokenizer
+}
+
+// Line represents a single line read from the log of Source. Err is set if the
+// log of Source failed, no more lines are read from this log afterward.
+type Line struct {
+	Source Source
+	Text   string
+	Err    error
+}
+
+// Tokenize splits the line into tokens by using the tokenizer and patterns of
+// its source.
+func (l Line) Tokenize() []string {
+	return l.Source.Tokenizer.Tokenize(l.Text, l.Source.Channel.Patterns)
+}
+
+// ReadLines reads the logs of all sources concurrently and sends each line to
+// the returned channel. Reading stops when the done channel is closed or stop
+// is called. A failing log is reported as a line with a ChannelError. stop
+// returns as soon as no log is read anymore, thus the logs may be closed
+// afterward.
+func ReadLines(sources []Source, done chan struct{}) (lines <-chan Line, stop func()) {
+	out := make(chan Line)
+	quit := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+		case <-stopped:
+		}
+		close(quit)
+	}()
+
+	var wg sync.WaitGroup
+	for _, s := range sources {
+		wg.Add(1)
+		go func(s Source) {
+			defer wg.Done()
+			for {
+				line := Line{Source: s}
+				line.Text, line.Err = s.Log.NextLine(quit)
+				if line.Err != nil {
+					line.Err = ChannelError{Channel: s.Channel.Name, Err: line.Err}
+				}
+				select {
+				case out <- line:
+				case <-quit:
+					return
+				}
+				if line.Err != nil {
+					return
+				}
+			}
+		}(s)
+	}
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() { close(stopped) })
+		wg.Wait()
+	}
+}
+
+// TailSources sets the read cursor of the logs of all sources to their end.
+func TailSources(sources []Source) error {
+	for _, s := range sources {
+		if err := s.Log.Tail(); err != nil {
+			return ChannelError{Channel: s.Channel.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// CloseSources closes the logs of all sources.
+func CloseSources(sources []Source) error {
+	var errs []error
+	for _, s := range sources {
+		if err := s.Log.Close(); err != nil {
+			errs = append(errs, ChannelError{Channel: s.Channel.Name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package df
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowLog returns a line on each call of NextLine and keeps reading for a
+// while after the done channel was closed, like a follower waiting for a
+// change notification.
+type slowLog struct {
+	reading atomic.Int32
+}
+
+func (l *slowLog) NextLine(done chan struct{}) (string, error) {
+	l.reading.Add(1)
+	defer l.reading.Add(-1)
+	select {
+	case <-done:
+		time.Sleep(50 * time.Millisecond)
+		return "", nil
+	case <-time.After(time.Millisecond):
+		return "select 1\n", nil
+	}
+}
+
+func (l *slowLog) Timestamp(string) (time.Time, error) { return time.Time{}, nil }
+func (l *slowLog) Close() error                        { return nil }
+func (l *slowLog) Tail() error                         { return nil }
+
+func TestReadLinesStop(t *testing.T) {
+	logs := []*slowLog{{}, {}}
+	sources := []Source{{Channel: Channel{Name: "a"}, Log: logs[0]}, {Channel: Channel{Name: "b"}, Log: logs[1]}}
+	lines, stop := ReadLines(sources, make(chan struct{}))
+	assert.Equal(t, "select 1\n", (<-lines).Text)
+
+	stop()
+	for _, l := range logs {
+		assert.Zero(t, l.reading.Load(), "log is still read after stop")
+	}
+	stop() // -> may be called twice
+}
@@ -1,9 +1,38 @@
 package df
 
+import "fmt"
+
+// TestRepository stores tests. Write only replaces a stored test if the
+// written testcase has the same Revision as the stored test, otherwise it
+// fails with ErrConflict. A new test must have revision 0. The written test
+// gets the next revision, thus concurrent writers, e.g. two verification runs,
+// can't silently overwrite each other's changes.
+//
+// Each written revision is kept, limited to the last MaxRevisions revisions of
+// a test, thus a test can be rolled back, see Rollback. Deleting a test deletes
+// its revisions.
 type TestRepository interface {
 	All() ([]Testcase, error)
 	Get(testname string) (Testcase, error)
 	Exists(filename string) bool
 	Write(testname string, testcase Testcase) error
 	Delete(testname string) error
+
+	// Revisions returns the kept revisions of test testname, oldest first.
+	Revisions(testname string) ([]Revision, error)
+
+	// GetRevision returns the test testname as it was written in revision.
+	GetRevision(testname string, revision int) (Testcase, error)
+}
+
+// CheckRevision returns an ErrConflict if testcase can't replace the stored
+// test of revision stored. exists is false if the test isn't stored yet.
+func CheckRevision(testcase Testcase, stored int, exists bool) error {
+	switch {
+	case !exists && testcase.Revision != 0:
+		return fmt.Errorf("%w: test '%s' has been deleted", ErrConflict, testcase.Name)
+	case exists && testcase.Revision != stored:
+		return fmt.Errorf("%w: test '%s' has revision %d, expected %d", ErrConflict, testcase.Name, stored, testcase.Revision)
+	}
+	return nil
 }
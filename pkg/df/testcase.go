@@ -11,29 +11,127 @@ type Testcase struct {
 	Expectations  []Expectation `json:"expectation"`
 	LastExecution time.Time     `json:"last_execution"`
 
+	// Revision counts the writes of the test, see TestRepository.Write
+	Revision int `json:"revision"`
+
+	// Change describes the kind of change that led to the revision, e.g.
+	// ChangeVerification
+	Change string `json:"change,omitempty"`
+
+	// Ordering defines if the expectations must be fulfilled in recorded order
+	Ordering Ordering `json:"ordering,omitempty"`
+
+	// Statements of the last verification run that fulfilled their expectation
+	// out of recorded order
+	OrderViolations []OrderViolation `json:"order_violations,omitempty"`
+
+	// Data flows between expectations learned from the recording and the first
+	// verification run
+	Correlations []Correlation `json:"correlations,omitempty"`
+
+	// Correlations broken by the last verification run
+	BrokenCorrelations []CorrelationViolation `json:"broken_correlations,omitempty"`
+
+	// Statements that must never appear during a verification run
+	Forbidden []Forbidden `json:"forbidden,omitempty"`
+
+	// Forbidden statements that appeared during the last verification run
+	ForbiddenViolations []ForbiddenViolation `json:"forbidden_violations,omitempty"`
+
+	// History of the verification runs, limited to the last MaxRuns runs
+	Runs []Run `json:"runs,omitempty"`
+
 	// Expectations, that match one of the patterns but didn't match one of the
-	// expected expectations
+	// expected expectations during the last verification run
 	AdditionalExpectations []Expectation `json:"additional_expectations"`
+
+	// Baseline numbers the expectation sets of the test: 0 is the recording,
+	// each re-baseline increases it by one
+	Baseline int `json:"baseline,omitempty"`
+
+	// FormerBaseline is the revision of the test holding the baseline replaced
+	// by the last re-baseline or 0, see RollbackBaseline
+	FormerBaseline int `json:"former_baseline,omitempty"`
+}
+
+// Copy returns a copy of t that doesn't share the slices of t, thus it stays
+// unchanged while t is modified, e.g. by a running recording or verification.
+func (t Testcase) Copy() Testcase {
+	c := t
+	c.Expectations = append([]Expectation(nil), t.Expectations...)
+	c.OrderViolations = append([]OrderViolation(nil), t.OrderViolations...)
+	c.Correlations = append([]Correlation(nil), t.Correlations...)
+	c.BrokenCorrelations = append([]CorrelationViolation(nil), t.BrokenCorrelations...)
+	c.Forbidden = append([]Forbidden(nil), t.Forbidden...)
+	c.ForbiddenViolations = append([]ForbiddenViolation(nil), t.ForbiddenViolations...)
+	c.Runs = append([]Run(nil), t.Runs...)
+	c.AdditionalExpectations = append([]Expectation(nil), t.AdditionalExpectations...)
+	return c
 }
 
-// Fulfilled returns the fulfilled expectations.
+// Fulfilled returns the fulfilled expectations. Disabled expectations are
+// skipped.
 func (t Testcase) Fulfilled() []Expectation {
 	var unfulfilled []Expectation
 	for _, e := range t.Expectations {
-		if e.Fulfilled {
+		if e.Fulfilled && !e.Disabled {
 			unfulfilled = append(unfulfilled, e)
 		}
 	}
 	return unfulfilled
 }
 
-// Unfulfilled returns the unfulfilled expectations.
+// Expectation returns the expectation with the given uuid.
+func (t Testcase) Expectation(uuid string) (Expectation, bool) {
+	for _, e := range t.Expectations {
+		if e.Uuid == uuid {
+			return e, true
+		}
+	}
+	return Expectation{}, false
+}
+
+// Forbid adds f to the forbidden statements unless it's already forbidden.
+func (t *Testcase) Forbid(f Forbidden) {
+	for _, existing := range t.Forbidden {
+		if existing == f {
+			return
+		}
+	}
+	t.Forbidden = append(t.Forbidden, f)
+}
+
+// Allow removes f from the forbidden statements. Returns false if f wasn't
+// forbidden.
+func (t *Testcase) Allow(f Forbidden) bool {
+	for i, existing := range t.Forbidden {
+		if existing == f {
+			t.Forbidden = append(t.Forbidden[:i], t.Forbidden[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Unfulfilled returns the unfulfilled expectations. Disabled expectations are
+// skipped.
 func (t Testcase) Unfulfilled() []Expectation {
 	var unfulfilled []Expectation
 	for _, e := range t.Expectations {
-		if !e.Fulfilled {
+		if !e.Fulfilled && !e.Disabled {
 			unfulfilled = append(unfulfilled, e)
 		}
 	}
 	return unfulfilled
 }
+
+// Disabled returns the disabled expectations.
+func (t Testcase) Disabled() []Expectation {
+	var disabled []Expectation
+	for _, e := range t.Expectations {
+		if e.Disabled {
+			disabled = append(disabled, e)
+		}
+	}
+	return disabled
+}
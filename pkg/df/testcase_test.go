@@ -0,0 +1,24 @@
+package df
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopy(t *testing.T) {
+	tc := Testcase{
+		Expectations:           make([]Expectation, 1, 2),
+		AdditionalExpectations: make([]Expectation, 0, 1),
+	}
+	c := tc.Copy()
+
+	tc.Expectations[0].Uuid = "e1"
+	tc.Expectations = append(tc.Expectations, Expectation{Uuid: "e2"})
+	tc.AdditionalExpectations = append(tc.AdditionalExpectations, Expectation{Uuid: "a1"})
+	c.AdditionalExpectations = append(c.AdditionalExpectations, Expectation{Uuid: "a2"})
+
+	assert.Equal(t, []Expectation{{}}, c.Expectations)
+	assert.Equal(t, "a1", tc.AdditionalExpectations[0].Uuid)
+	assert.Equal(t, "a2", c.AdditionalExpectations[0].Uuid)
+}
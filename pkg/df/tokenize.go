@@ -2,6 +2,9 @@ package df
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -36,3 +39,27 @@ func Tokenize(s string) []string {
 	log.Debug(tokens)
 	return tokens
 }
+
+// Compact renders a multi-line statement as single line. Each run of line
+// breaks, tabs and spaces outside quoted strings becomes a single space, thus
+// 'Java  Dev' keeps both of its spaces.
+func Compact(statement string) string {
+	var b strings.Builder
+	quoted := false
+	space := false
+	for _, r := range strings.TrimSpace(statement) {
+		if !quoted && unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if r == '\'' {
+			quoted = !quoted
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -43,3 +43,8 @@ func TestTokenize(t *testing.T) {
 		})
 	}
 }
+
+func TestCompact(t *testing.T) {
+	assert.Equal(t, "select * from job where title='Java  Dev\nHamburg' and id=1",
+		Compact("\n  select *\r\n\tfrom job\n  where title='Java  Dev\nHamburg'   and id=1\n"))
+}
@@ -24,11 +24,11 @@ func NewPlaywrightRunner(c df.Config) PlaywrightRunner {
 }
 
 // Run runs testname by converting the name to its playwright format (full.json
-// becomes full.spec.ts)
-func (r PlaywrightRunner) Run(testname string) {
+// becomes full.spec.ts). Returns an error if the test doesn't exist or failed.
+func (r PlaywrightRunner) Run(testname string) error {
 	if !r.Exists(testname) {
 		log.Errorf("PlaywrightRunner: test file '%s' not found", testname)
-		return
+		return fmt.Errorf("test file '%s' not found", testname)
 	}
 
 	fn := r.ToPlaywright(testname)
@@ -39,7 +39,9 @@ func (r PlaywrightRunner) Run(testname string) {
 	cmd.Dir = r.config.Playwright.BaseDir
 	if err := cmd.Run(); err != nil {
 		log.Errorf("error running command: %v", err)
+		return err
 	}
+	return nil
 }
 
 // Exists converts testname to its corresponding playwright format and returns
@@ -0,0 +1,57 @@
+// Package export renders verification reports in formats that are consumed by
+// CI pipelines and dashboards, e.g. JUnit XML.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Format renders a [df.Report] to a writer.
+type Format struct {
+	ContentType string
+	Write       func(w io.Writer, r df.Report) error
+}
+
+var formats = map[string]Format{
+	"json":     {ContentType: "application/json", Write: JSON},
+	"junit":    {ContentType: "application/xml", Write: JUnit},
+	"markdown": {ContentType: "text/markdown; charset=utf-8", Write: Markdown},
+	"text":     {ContentType: "text/plain; charset=utf-8", Write: Text},
+}
+
+// Lookup returns the format registered under name.
+func Lookup(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown report format '%s', known formats: %v", name, Names())
+	}
+	return f, nil
+}
+
+// Names returns the sorted names of all formats.
+func Names() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSON writes r as indented json.
+func JSON(w io.Writer, r df.Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Text writes r as plain text.
+func Text(w io.Writer, r df.Report) error {
+	_, err := fmt.Fprint(w, r)
+	return err
+}
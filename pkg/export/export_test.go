@@ -0,0 +1,70 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+)
+
+func report() df.Report {
+	tc := df.Testcase{
+		Name:          "create-job",
+		LastExecution: time.Date(2024, 4, 8, 9, 39, 15, 0, time.UTC),
+		Verifications: 2,
+		Expectations: []df.Expectation{
+			{Uuid: "e1", Tokens: df.Tokenize("insert into job (id) values (1)"), Fulfilled: true, Verified: 2},
+			{Uuid: "e2", Tokens: df.Tokenize("update job set name='b' where id=1"), Channel: "mysql",
+				Closest: "update job set title='b' where id=2"},
+			{Uuid: "e3", Tokens: df.Tokenize("select * from application where job_id=1"), Count: 2, Occurrences: 3},
+		},
+		ForbiddenViolations: []df.ForbiddenViolation{
+			{Forbidden: df.Forbidden{Pattern: "delete"}, Statement: "delete from job"},
+		},
+		Runs: []df.Run{{Additional: []string{"select * from job"}}},
+	}
+	return df.NewReport(tc)
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"json", "junit", "markdown", "text"} {
+		_, err := Lookup(name)
+		assert.NoError(t, err)
+	}
+	_, err := Lookup("html")
+	assert.Error(t, err)
+}
+
+func TestJUnit(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, JUnit(&b, report()))
+
+	var suites junitTestsuites
+	assert.NoError(t, xml.Unmarshal(b.Bytes(), &suites))
+	assert.Len(t, suites.Testsuites, 1)
+	suite := suites.Testsuites[0]
+	assert.Equal(t, "create-job", suite.Name)
+	assert.Equal(t, 4, suite.Tests)
+	assert.Equal(t, 3, suite.Failures)
+	assert.Nil(t, suite.Testcases[0].Failure)
+	assert.Equal(t, "[mysql] update job set name=b where id=1", suite.Testcases[1].Name)
+	assert.Equal(t, "unfulfilled, closest actual statement: update job set title='b' where id=2", suite.Testcases[1].Failure.Message)
+	assert.Equal(t, "unfulfilled: expected 2, got 3", suite.Testcases[2].Failure.Message)
+	assert.Equal(t, "forbidden", suite.Testcases[3].Failure.Type)
+	assert.Contains(t, suite.SystemOut, "select * from job")
+}
+
+func TestMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, Markdown(&b, report()))
+	md := b.String()
+	assert.Contains(t, md, "# Verification of create-job failed")
+	assert.Contains(t, md, "| 3 | 1 | 2 | 2024-04-08 09:39:15 |")
+	assert.Contains(t, md, "closest actual statement: `update job set title='b' where id=2`")
+	assert.Contains(t, md, "(expected 2, got 3)")
+	assert.Contains(t, md, "## Forbidden")
+	assert.Contains(t, md, "## Additional statements\n\n- `select * from job`")
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+type junitTestsuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Testsuites []junitTestsuite `xml:"testsuite"`
+}
+
+type junitTestsuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Testcases []junitTestcase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit writes r as JUnit XML. Each expectation becomes a testcase that fails
+// if the expectation was unfulfilled, verified out of order or broke a data
+// flow. Each forbidden statement becomes a failed testcase of its own. The
+// additional statements are listed as system output of the testsuite.
+func JUnit(w io.Writer, r df.Report) error {
+	suite := junitTestsuite{Name: r.Testname, Timestamp: r.LastExecution.Format(time.RFC3339)}
+	for _, result := range r.Results {
+		tc := junitTestcase{Name: resultName(result), Classname: r.Testname}
+		var failures []string
+		var types []string
+		if !result.Fulfilled {
+			types = append(types, "unfulfilled")
+			failures = append(failures, unfulfilledMessage(result))
+		}
+		for _, v := range r.OutOfOrder {
+			if v.Uuid == result.Uuid {
+				types = append(types, "out-of-order")
+				failures = append(failures, "out of order: "+v.String())
+			}
+		}
+		for _, v := range r.BrokenDataFlow {
+			if v.Correlation.Source.Uuid == result.Uuid || v.Correlation.Target.Uuid == result.Uuid {
+				types = append(types, "broken-data-flow")
+				failures = append(failures, "broken data flow: "+v.String())
+			}
+		}
+		if len(failures) > 0 {
+			tc.Failure = &junitFailure{
+				Message: failures[0],
+				Type:    strings.Join(types, ","),
+				Text:    strings.Join(failures, "\n"),
+			}
+		}
+		suite.Testcases = append(suite.Testcases, tc)
+	}
+	for _, v := range r.Forbidden {
+		suite.Testcases = append(suite.Testcases, junitTestcase{
+			Name:      "forbidden: " + v.Forbidden.String(),
+			Classname: r.Testname,
+			Failure:   &junitFailure{Message: v.String(), Type: "forbidden", Text: v.Statement},
+		})
+	}
+	for _, tc := range suite.Testcases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.Testcases)
+	if len(r.AdditionalExpectations) > 0 {
+		suite.SystemOut = "additional statements:\n" + strings.Join(r.AdditionalExpectations, "\n")
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestsuites{Testsuites: []junitTestsuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func resultName(result df.ExpectationResult) string {
+	if result.Channel != "" {
+		return fmt.Sprintf("[%s] %s", result.Channel, result.Statement)
+	}
+	return result.Statement
+}
+
+func unfulfilledMessage(result df.ExpectationResult) string {
+	m := "unfulfilled"
+	if result.Cardinality != "" {
+		m = fmt.Sprintf("%s: %s", m, result.Cardinality)
+	}
+	if result.Closest != "" {
+		m = fmt.Sprintf("%s, closest actual statement: %s", m, result.Closest)
+	}
+	return m
+}
@@ -0,0 +1,76 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Markdown writes a summary of r as markdown, e.g. to be posted as comment of
+// a pull request.
+func Markdown(w io.Writer, r df.Report) error {
+	var b strings.Builder
+	status := "passed"
+	if !r.Passed {
+		status = "failed"
+	}
+	fmt.Fprintf(&b, "# Verification of %s %s\n\n", r.Testname, status)
+	fmt.Fprintf(&b, "| Expectations | Fulfilled | Verifications | Last execution |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %s |\n", r.Expectations, r.Fulfilled, r.Verifications, r.LastExecution.Format(time.DateTime))
+
+	if len(r.Channels) > 1 {
+		fmt.Fprintf(&b, "\n## Channels\n\n| Channel | Expectations | Fulfilled |\n|---|---|---|\n")
+		for _, c := range r.Channels {
+			fmt.Fprintf(&b, "| %s | %d | %d |\n", c.Name, c.Expectations, c.Fulfilled)
+		}
+	}
+
+	var unfulfilled []string
+	for _, result := range r.Results {
+		if result.Fulfilled {
+			continue
+		}
+		s := fmt.Sprintf("`%s`", resultName(result))
+		if result.Cardinality != "" {
+			s = fmt.Sprintf("%s (%s)", s, result.Cardinality)
+		}
+		if result.Closest != "" {
+			s = fmt.Sprintf("%s<br>closest actual statement: `%s`", s, result.Closest)
+		}
+		unfulfilled = append(unfulfilled, s)
+	}
+	writeList(&b, "Unfulfilled", unfulfilled)
+	writeList(&b, "Out of order", codes(r.OutOfOrder))
+	writeList(&b, "Broken data flow", codes(r.BrokenDataFlow))
+	writeList(&b, "Forbidden", codes(r.Forbidden))
+	var additional []string
+	for _, s := range r.AdditionalExpectations {
+		additional = append(additional, fmt.Sprintf("`%s`", s))
+	}
+	writeList(&b, "Additional statements", additional)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+func codes[T fmt.Stringer](values []T) []string {
+	var result []string
+	for _, v := range values {
+		result = append(result, fmt.Sprintf("`%s`", v))
+	}
+	return result
+}
@@ -8,72 +8,283 @@ import (
 	log "github.com/sirupsen/logrus"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-type JSONTestRepository struct{}
+// mu serializes the revision check and the write or delete of tests.
+var mu sync.Mutex
+
+// JSONTestRepository stores each test as <name>.json in Dir. Without Dir, tests
+// are stored in the working directory, where all json files except the config
+// files are considered tests. Tests are written to a temporary file that
+// replaces the test file afterward, thus a crash never leaves a partially
+// written test. Test files that can't be parsed nevertheless are moved to the
+// subdirectory "quarantine". The revisions of a test are kept as
+// revisions/<name>/<revision>.json.
+type JSONTestRepository struct {
+	Dir string
+}
+
+// NewJSONTestRepository creates a repository storing its tests in dir. The
+// directory is created if it doesn't exist.
+func NewJSONTestRepository(dir string) (JSONTestRepository, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return JSONTestRepository{}, fmt.Errorf("NewJSONTestRepository failed: %w", err)
+	}
+	return JSONTestRepository{Dir: dir}, nil
+}
+
+// filename returns the name of the file of testname. testname may already
+// contain the suffix ".json".
+func (r JSONTestRepository) filename(testname string) string {
+	if !strings.HasSuffix(testname, ".json") {
+		testname = fmt.Sprintf("%s.json", testname)
+	}
+	if r.Dir == "" {
+		return testname
+	}
+	return filepath.Join(r.Dir, testname)
+}
+
+// dir returns the directory containing the test files.
+func (r JSONTestRepository) dir() string {
+	if r.Dir == "" {
+		return "."
+	}
+	return r.Dir
+}
+
+// revisionsDir returns the directory containing the revisions of testname.
+func (r JSONTestRepository) revisionsDir(testname string) string {
+	return filepath.Join(r.dir(), "revisions", strings.TrimSuffix(testname, ".json"))
+}
 
 func (r JSONTestRepository) Delete(testname string) error {
-	if err := os.Remove(fmt.Sprintf("%s.json", testname)); err != nil {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.Remove(r.filename(testname)); err != nil {
 		return err
 	}
-	return nil
+	return os.RemoveAll(r.revisionsDir(testname))
 }
 
+// Write replaces the test file of testname by testcase, see df.TestRepository.
+// A test file that can't be parsed is replaced regardless of its revision.
 func (r JSONTestRepository) Write(testname string, testcase df.Testcase) error {
-	f, err := os.Create(fmt.Sprintf("%s.json", testname))
-	defer func(f *os.File) {
-		if err := f.Close(); err != nil {
-			log.Errorf("unable to close file: %v", err)
+	mu.Lock()
+	defer mu.Unlock()
+	stored, err := r.Get(testname)
+	switch {
+	case err == nil:
+		if err := df.CheckRevision(testcase, stored.Revision, true); err != nil {
+			return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
 		}
-	}(f)
+	case errors.Is(err, df.ErrTestNotFound):
+		if err := df.CheckRevision(testcase, 0, false); err != nil {
+			return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+		}
+		// drop stale revisions of a test file that has been removed manually
+		if err := os.RemoveAll(r.revisionsDir(testname)); err != nil {
+			return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+		}
+	case errors.Is(err, InvalidJsonError{}):
+		log.Warnf("replacing invalid testfile '%s'", r.filename(testname))
+	default:
+		return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+	}
+
+	testcase.Revision++
+	b, err := json.Marshal(testcase)
 	if err != nil {
+		return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+	}
+	if err := r.writeRevision(testname, testcase.Revision, b); err != nil {
+		return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+	}
+	if err := writeFile(r.filename(testname), b); err != nil {
+		return fmt.Errorf("JSONTestRepository.Write failed: %w", err)
+	}
+	log.Printf("successfully wrote %s (revision %d)\n", r.filename(testname), testcase.Revision)
+	return nil
+}
+
+// writeRevision keeps b as revision of testname and drops the revisions that
+// exceed df.MaxRevisions.
+func (r JSONTestRepository) writeRevision(testname string, revision int, b []byte) error {
+	dir := r.revisionsDir(testname)
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
-	b, err := json.Marshal(testcase)
+	if err := writeFile(filepath.Join(dir, fmt.Sprintf("%d.json", revision)), b); err != nil {
+		return err
+	}
+	numbers, err := r.revisionNumbers(testname)
+	if err != nil {
+		return err
+	}
+	for _, n := range numbers {
+		if n <= revision-df.MaxRevisions {
+			if err := os.Remove(filepath.Join(dir, fmt.Sprintf("%d.json", n))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revisionNumbers returns the numbers of the kept revisions of testname in
+// ascending order.
+func (r JSONTestRepository) revisionNumbers(testname string) ([]int, error) {
+	entries, err := os.ReadDir(r.revisionsDir(testname))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var numbers []int
+	for _, e := range entries {
+		n, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil || e.IsDir() {
+			continue // -> temporary file
+		}
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+func (r JSONTestRepository) Revisions(testname string) ([]df.Revision, error) {
+	if !r.Exists(testname) {
+		return nil, fmt.Errorf("%w: %s", df.ErrTestNotFound, strings.TrimSuffix(testname, ".json"))
+	}
+	numbers, err := r.revisionNumbers(testname)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("JSONTestRepository.Revisions failed: %w", err)
+	}
+	var revisions []df.Revision
+	for _, n := range numbers {
+		name := filepath.Join(r.revisionsDir(testname), fmt.Sprintf("%d.json", n))
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("JSONTestRepository.Revisions failed: %w", err)
+		}
+		tc, err := r.GetRevision(testname, n)
+		if err != nil {
+			return nil, fmt.Errorf("JSONTestRepository.Revisions failed: %w", err)
+		}
+		revisions = append(revisions, df.Revision{Number: n, Written: info.ModTime(), Change: tc.Change, Expectations: len(tc.Expectations)})
+	}
+	return revisions, nil
+}
+
+func (r JSONTestRepository) GetRevision(testname string, revision int) (df.Testcase, error) {
+	b, err := os.ReadFile(filepath.Join(r.revisionsDir(testname), fmt.Sprintf("%d.json", revision)))
+	if errors.Is(err, os.ErrNotExist) {
+		return df.Testcase{}, fmt.Errorf("%w: %s revision %d", df.ErrRevisionNotFound, strings.TrimSuffix(testname, ".json"), revision)
+	}
+	if err != nil {
+		return df.Testcase{}, fmt.Errorf("JSONTestRepository.GetRevision failed: %w", err)
+	}
+	var tc df.Testcase
+	if err := json.Unmarshal(b, &tc); err != nil {
+		return df.Testcase{}, fmt.Errorf("JSONTestRepository.GetRevision failed: %w", err)
+	}
+	return tc, nil
+}
+
+// writeFile writes b to a temporary file in the directory of filename and
+// renames it to filename once it is completely written and synced.
+func writeFile(filename string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
 	}
 	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
 	if err != nil {
-		log.Fatal(err)
+		_ = os.Remove(f.Name())
 	}
-	log.Printf("successfully wrote %s\n", f.Name())
-	return nil
+	return err
 }
 
-func (r JSONTestRepository) Exists(testname string) bool {
-	fn := testname
-	if !strings.HasSuffix(testname, ".json") {
-		fn = fmt.Sprintf("%s.json", testname)
+// quarantine moves the test file name to the subdirectory "quarantine" unless
+// it has been replaced by a valid test in the meantime. The file is kept for
+// inspection but no longer considered a test.
+func (r JSONTestRepository) quarantine(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, err := r.Get(name); !errors.Is(err, InvalidJsonError{}) {
+		return nil
 	}
+	dir := filepath.Join(r.dir(), "quarantine")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, fmt.Sprintf("%s.%s", name, time.Now().UTC().Format("20060102T150405.000000000")))
+	return os.Rename(r.filename(name), target)
+}
 
-	if _, err := os.Stat(fn); os.IsNotExist(err) {
+func (r JSONTestRepository) Exists(testname string) bool {
+	if _, err := os.Stat(r.filename(testname)); os.IsNotExist(err) {
 		return false
 	}
 	return true
 }
 
+// Files returns the names of the test files including the suffix ".json". Unlike
+// All it neither parses nor quarantines the files.
+func (r JSONTestRepository) Files() ([]string, error) {
+	dir, err := os.ReadDir(r.dir())
+	if err != nil {
+		return nil, fmt.Errorf("JSONTestRepository.Files failed: %w", err)
+	}
+	var files []string
+	for _, f := range dir {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		if r.Dir == "" && strings.HasPrefix(f.Name(), "config") {
+			continue
+		}
+		files = append(files, f.Name())
+	}
+	return files, nil
+}
+
 func (r JSONTestRepository) All() ([]df.Testcase, error) {
 	var all []df.Testcase
-	dir, err := os.ReadDir(".")
+	files, err := r.Files()
 	if err != nil {
 		return nil, fmt.Errorf("JSONTestRepository.All failed: %w", err)
 	}
-	for _, f := range dir {
-		if strings.HasSuffix(f.Name(), ".json") && !strings.HasPrefix(f.Name(), "config") {
-			tc, err := r.Get(f.Name())
-			if err != nil {
-				if errors.Is(err, InvalidJsonError{}) {
-					log.Errorf("testfile '%s' contains invalid json. deleting file.", f.Name())
-					_ = os.Remove(f.Name())
-				} else {
-					log.Errorf("JSONTestRepository.Get failed: %v", err)
+	for _, name := range files {
+		tc, err := r.Get(name)
+		if err != nil {
+			if errors.Is(err, InvalidJsonError{}) {
+				log.Errorf("testfile '%s' contains invalid json. moving file to quarantine.", name)
+				if err := r.quarantine(name); err != nil {
+					log.Errorf("JSONTestRepository.All failed to quarantine '%s': %v", name, err)
 				}
 			} else {
-				all = append(all, tc)
+				log.Errorf("JSONTestRepository.Get failed: %v", err)
 			}
+			continue
 		}
+		all = append(all, tc)
 	}
 	return all, nil
 }
@@ -85,11 +296,11 @@ func (e InvalidJsonError) Error() string {
 }
 
 func (r JSONTestRepository) Get(testname string) (df.Testcase, error) {
-	fn := testname
-	if !strings.HasSuffix(testname, ".json") {
-		fn = fmt.Sprintf("%s.json", testname)
-	}
+	fn := r.filename(testname)
 	f, err := os.Open(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return df.Testcase{}, fmt.Errorf("%w: %s", df.ErrTestNotFound, strings.TrimSuffix(testname, ".json"))
+	}
 	if err != nil {
 		return df.Testcase{}, err
 	}
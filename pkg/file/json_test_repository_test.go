@@ -0,0 +1,100 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONTestRepositoryRevisions(t *testing.T) {
+	r, err := NewJSONTestRepository(filepath.Join(t.TempDir(), "tests"))
+	require.NoError(t, err)
+
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{{Uuid: "1", Tokens: []string{"insert"}}}}
+	require.NoError(t, r.Write(tc.Name, tc))
+	stored, err := r.Get(tc.Name)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Revision)
+
+	// two writers based on revision 1, the second one gets a conflict
+	first, second := stored, stored
+	first.Verifications = 1
+	second.Verifications = 2
+	require.NoError(t, r.Write(tc.Name, first))
+	assert.ErrorIs(t, r.Write(tc.Name, second), df.ErrConflict)
+	stored, err = r.Get(tc.Name)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Revision)
+	assert.Equal(t, 1, stored.Verifications)
+
+	// a new test must have revision 0
+	assert.ErrorIs(t, r.Write("delete-job", df.Testcase{Name: "delete-job", Revision: 3}), df.ErrConflict)
+
+	// no temporary files are left
+	entries, err := os.ReadDir(r.Dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "create-job.json", entries[0].Name())
+	assert.Equal(t, "revisions", entries[1].Name())
+}
+
+func TestJSONTestRepositoryKeepsRevisions(t *testing.T) {
+	r, err := NewJSONTestRepository(filepath.Join(t.TempDir(), "tests"))
+	require.NoError(t, err)
+	tc := df.Testcase{Name: "create-job", Change: df.ChangeRecording, Expectations: []df.Expectation{{Uuid: "1", Tokens: []string{"insert"}}}}
+	require.NoError(t, r.Write(tc.Name, tc))
+	tc.Revision, tc.Change, tc.Expectations = 1, df.ChangeEdit, nil
+	require.NoError(t, r.Write(tc.Name, tc))
+
+	revisions, err := r.Revisions(tc.Name)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Number)
+	assert.Equal(t, df.ChangeRecording, revisions[0].Change)
+	assert.Equal(t, 1, revisions[0].Expectations)
+	assert.Equal(t, 2, revisions[1].Number)
+	assert.Equal(t, 0, revisions[1].Expectations)
+
+	first, err := r.GetRevision(tc.Name, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Revision)
+	assert.Len(t, first.Expectations, 1)
+	_, err = r.GetRevision(tc.Name, 3)
+	assert.ErrorIs(t, err, df.ErrRevisionNotFound)
+
+	// only the last df.MaxRevisions revisions are kept
+	for i := 2; i < df.MaxRevisions+3; i++ {
+		tc.Revision = i
+		require.NoError(t, r.Write(tc.Name, tc))
+	}
+	revisions, err = r.Revisions(tc.Name)
+	require.NoError(t, err)
+	assert.Len(t, revisions, df.MaxRevisions)
+	assert.Equal(t, 4, revisions[0].Number)
+
+	require.NoError(t, r.Delete(tc.Name))
+	_, err = r.Revisions(tc.Name)
+	assert.ErrorIs(t, err, df.ErrTestNotFound)
+	assert.NoDirExists(t, filepath.Join(r.Dir, "revisions", tc.Name))
+}
+
+func TestJSONTestRepositoryQuarantine(t *testing.T) {
+	r, err := NewJSONTestRepository(filepath.Join(t.TempDir(), "tests"))
+	require.NoError(t, err)
+	require.NoError(t, r.Write("create-job", df.Testcase{Name: "create-job"}))
+	require.NoError(t, os.WriteFile(filepath.Join(r.Dir, "broken.json"), nil, 0644))
+
+	all, err := r.All()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "create-job", all[0].Name)
+
+	assert.False(t, r.Exists("broken"))
+	quarantined, err := filepath.Glob(filepath.Join(r.Dir, "quarantine", "broken.json.*"))
+	require.NoError(t, err)
+	assert.Len(t, quarantined, 1)
+}
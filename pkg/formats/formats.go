@@ -0,0 +1,34 @@
+// Package formats provides the registry of all channel formats supported by
+// datafrog.
+package formats
+
+import (
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/lexer"
+	"github.com/rwirdemann/datafrog/pkg/mysql"
+	"github.com/rwirdemann/datafrog/pkg/postgres"
+	"github.com/rwirdemann/datafrog/pkg/proxy"
+	"github.com/rwirdemann/datafrog/pkg/regex"
+	"github.com/rwirdemann/datafrog/pkg/rest"
+)
+
+// NewRegistry creates a registry containing all supported formats and
+// tokenizers.
+func NewRegistry() *df.Registry {
+	r := df.NewRegistry()
+	r.Register("mysql", df.Format{LogFactory: mysql.LogFactory{}, Tokenizer: mysql.Tokenizer{}})
+	r.Register("mysql-slow", df.Format{LogFactory: mysql.SlowLogFactory{}, Tokenizer: mysql.Tokenizer{}})
+	r.Register("mysql-performance-schema", df.Format{LogFactory: mysql.PerformanceSchemaLogFactory{}, Tokenizer: mysql.Tokenizer{}})
+	r.Register("postgres", df.Format{LogFactory: postgres.LogFactory{}, Tokenizer: postgres.Tokenizer{}})
+	r.Register("postgres-csv", df.Format{LogFactory: postgres.CSVLogFactory{}, Tokenizer: postgres.Tokenizer{}})
+	r.Register("postgres-json", df.Format{LogFactory: postgres.JSONLogFactory{}, Tokenizer: postgres.Tokenizer{}})
+	for name, protocol := range proxy.Formats {
+		r.Register(name, df.Format{LogFactory: proxy.LogFactory{Protocol: protocol}, Tokenizer: proxy.Tokenizer{}})
+	}
+	r.Register("regex", df.Format{LogFactory: regex.LogFactory{}, Tokenizer: regex.Tokenizer{}})
+	r.Register(rest.ProxyFormat, df.Format{LogFactory: rest.ProxyLogFactory{}, Tokenizer: rest.Tokenizer{}})
+	r.Register(rest.HARFormat, df.Format{LogFactory: rest.HARLogFactory{}, Tokenizer: rest.Tokenizer{}})
+	r.Register(rest.AccessLogFormat, df.Format{LogFactory: rest.AccessLogFactory{}, Tokenizer: rest.Tokenizer{}})
+	r.RegisterTokenizer("sql", lexer.Tokenizer{})
+	return r
+}
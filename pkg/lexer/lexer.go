@@ -0,0 +1,285 @@
+// Package lexer provides a SQL aware tokenizer. Unlike df.Tokenize, which splits
+// statements by spaces, the lexer understands the structure of SQL statements:
+// "id=12" and "id = 12" produce the same tokens, keywords are normalized to
+// lower case and variable length IN lists are collapsed into a single token.
+package lexer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind classifies a Token.
+type Kind int
+
+const (
+	Keyword Kind = iota
+	Identifier
+	Operator
+	Punctuation
+	Parameter // placeholder like ?, $1 or :name
+	String
+	Number
+	Null
+	Timestamp // string literal containing a date, time or timestamp
+	List      // collapsed IN list
+)
+
+var kindNames = []string{"keyword", "identifier", "operator", "punctuation", "parameter",
+	"string", "number", "null", "timestamp", "list"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Token represents a single lexical unit of a SQL statement.
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+var keywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`add all alter and any as asc begin between by case check column
+		commit constraint create cross default delete desc distinct drop else end escape exists false
+		fetch for foreign from full group having if ignore in inner insert intersect into is join key
+		left like limit not null offset on or order outer primary references returning right rollback
+		select set start table then to transaction true truncate union unique update using values
+		when where with`) {
+		keywords[k] = true
+	}
+}
+
+var timestamp = regexp.MustCompile(`^'\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?)?(Z|[+-]\d{2}(:?\d{2})?)?'$|^'\d{2}:\d{2}(:\d{2}(\.\d+)?)?'$`)
+
+// Lex splits the SQL statement s into its tokens. Whitespace and comments are
+// dropped, keywords are converted to lower case and IN lists that contain only
+// literals and parameters are collapsed into a single List token.
+func Lex(s string) []Token {
+	l := lexer{input: []rune(s)}
+	l.run()
+	return collapseLists(l.tokens)
+}
+
+type lexer struct {
+	input  []rune
+	pos    int
+	tokens []Token
+}
+
+func (l *lexer) peek(offset int) rune {
+	if i := l.pos + offset; i >= 0 && i < len(l.input) {
+		return l.input[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) emit(kind Kind, text string) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Text: text})
+}
+
+func (l *lexer) run() {
+	for l.pos < len(l.input) {
+		r := l.peek(0)
+		switch {
+		case unicode.IsSpace(r):
+			l.pos++
+		case r == '-' && l.peek(1) == '-':
+			l.skipUntil("\n")
+		case r == '/' && l.peek(1) == '*':
+			l.skipUntil("*/")
+		case r == '\'':
+			l.lexString()
+		case r == '`' || r == '"':
+			l.lexQuotedIdentifier(r)
+		case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(l.peek(1))) || (r == '-' && unicode.IsDigit(l.peek(1)) && l.signAllowed()):
+			l.lexNumber()
+		case r == '?':
+			l.pos++
+			l.emit(Parameter, "?")
+		case (r == '$' || r == ':') && (unicode.IsDigit(l.peek(1)) || isIdentifierStart(l.peek(1))) && l.peek(-1) != ':':
+			l.pos++
+			l.emit(Parameter, string(r)+l.scan(isIdentifierPart))
+		case isIdentifierStart(r):
+			l.lexWord()
+		case strings.ContainsRune("(),;", r):
+			l.pos++
+			l.emit(Punctuation, string(r))
+		default:
+			l.lexOperator()
+		}
+	}
+
+	// a trailing semicolon doesn't change the statement
+	if n := len(l.tokens); n > 0 && l.tokens[n-1].Text == ";" {
+		l.tokens = l.tokens[:n-1]
+	}
+}
+
+func (l *lexer) skipUntil(end string) {
+	for l.pos < len(l.input) && !strings.HasPrefix(string(l.input[l.pos:]), end) {
+		l.pos++
+	}
+	l.pos = min(l.pos+len(end), len(l.input))
+}
+
+func (l *lexer) scan(accept func(r rune) bool) string {
+	start := l.pos
+	for l.pos < len(l.input) && accept(l.input[l.pos]) {
+		l.pos++
+	}
+	return string(l.input[start:l.pos])
+}
+
+// lexString reads a single quoted string literal. Quotes are escaped either by
+// doubling them or by a backslash.
+func (l *lexer) lexString() {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.input) {
+		r := l.input[l.pos]
+		if r == '\\' {
+			l.pos += 2
+			continue
+		}
+		if r == '\'' {
+			if l.peek(1) == '\'' {
+				l.pos += 2
+				continue
+			}
+			l.pos++
+			break
+		}
+		l.pos++
+	}
+	text := string(l.input[start:min(l.pos, len(l.input))])
+	if timestamp.MatchString(text) {
+		l.emit(Timestamp, text)
+		return
+	}
+	l.emit(String, text)
+}
+
+func (l *lexer) lexQuotedIdentifier(quote rune) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.input) && l.input[l.pos] != quote {
+		l.pos++
+	}
+	l.pos = min(l.pos+1, len(l.input))
+	l.emit(Identifier, string(l.input[start:l.pos]))
+}
+
+func (l *lexer) lexNumber() {
+	start := l.pos
+	if l.peek(0) == '-' {
+		l.pos++
+	}
+	l.scan(unicode.IsDigit)
+	if l.peek(0) == '.' && unicode.IsDigit(l.peek(1)) {
+		l.pos++
+		l.scan(unicode.IsDigit)
+	}
+	if (l.peek(0) == 'e' || l.peek(0) == 'E') && (unicode.IsDigit(l.peek(1)) || ((l.peek(1) == '-' || l.peek(1) == '+') && unicode.IsDigit(l.peek(2)))) {
+		l.pos += 2
+		l.scan(unicode.IsDigit)
+	}
+	l.emit(Number, string(l.input[start:l.pos]))
+}
+
+func (l *lexer) lexWord() {
+	word := l.scan(isIdentifierPart)
+	lower := strings.ToLower(word)
+	switch {
+	case lower == "null":
+		l.emit(Null, lower)
+	case keywords[lower]:
+		l.emit(Keyword, lower)
+	default:
+		l.emit(Identifier, word)
+	}
+}
+
+var operators = []string{"<=>", "<>", "!=", "<=", ">=", "||", "::", "->>", "->"}
+
+func (l *lexer) lexOperator() {
+	rest := string(l.input[l.pos:])
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			l.pos += len([]rune(op))
+			l.emit(Operator, op)
+			return
+		}
+	}
+	l.pos++
+	l.emit(Operator, string(l.input[l.pos-1]))
+}
+
+// signAllowed returns true if a minus sign at the current position belongs to a
+// number instead of being a subtraction.
+func (l *lexer) signAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	switch prev := l.tokens[len(l.tokens)-1]; prev.Kind {
+	case Operator, Keyword:
+		return true
+	case Punctuation:
+		return prev.Text != ")"
+	}
+	return false
+}
+
+func isIdentifierStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentifierPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '.'
+}
+
+// collapseLists replaces the parenthesized value list of each IN predicate by a
+// single List token, as long as the list contains nothing but literals and
+// parameters. Thus "in (1, 2, 3)" and "in (1, 2)" result in the same number of
+// tokens.
+func collapseLists(tokens []Token) []Token {
+	var result []Token
+	for i := 0; i < len(tokens); i++ {
+		result = append(result, tokens[i])
+		if tokens[i].Kind != Keyword || tokens[i].Text != "in" || i+1 >= len(tokens) || tokens[i+1].Text != "(" {
+			continue
+		}
+		end, ok := listEnd(tokens, i+1)
+		if !ok {
+			continue
+		}
+		var values []string
+		for _, t := range tokens[i+2 : end] {
+			if t.Text != "," {
+				values = append(values, t.Text)
+			}
+		}
+		result = append(result, Token{Kind: List, Text: "(" + strings.Join(values, ", ") + ")"})
+		i = end
+	}
+	return result
+}
+
+// listEnd returns the index of the closing bracket of the list that starts at
+// the opening bracket tokens[start]. Returns false if the list contains other
+// tokens than literals, parameters and commas.
+func listEnd(tokens []Token, start int) (int, bool) {
+	for i := start + 1; i < len(tokens); i++ {
+		switch t := tokens[i]; {
+		case t.Text == ")":
+			return i, i > start+1
+		case t.Text == ",", t.Kind == String, t.Kind == Number, t.Kind == Null, t.Kind == Timestamp, t.Kind == Parameter:
+			continue
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,82 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLex(t *testing.T) {
+	tokens := Lex("INSERT INTO job (description, publish_at, id) VALUES ('World, X', '2024-04-08 14:48:15', 12)")
+	assert.Equal(t, []Token{
+		{Keyword, "insert"}, {Keyword, "into"}, {Identifier, "job"},
+		{Punctuation, "("}, {Identifier, "description"}, {Punctuation, ","}, {Identifier, "publish_at"},
+		{Punctuation, ","}, {Identifier, "id"}, {Punctuation, ")"},
+		{Keyword, "values"},
+		{Punctuation, "("}, {String, "'World, X'"}, {Punctuation, ","}, {Timestamp, "'2024-04-08 14:48:15'"},
+		{Punctuation, ","}, {Number, "12"}, {Punctuation, ")"},
+	}, tokens)
+}
+
+func TestLexLiterals(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Token
+	}{
+		{s: "'it''s'", expected: Token{String, "'it''s'"}},
+		{s: "'2024-04-08T14:48:15.123Z'", expected: Token{Timestamp, "'2024-04-08T14:48:15.123Z'"}},
+		{s: "'2024-04-08'", expected: Token{Timestamp, "'2024-04-08'"}},
+		{s: "-3.5e2", expected: Token{Number, "-3.5e2"}},
+		{s: "NULL", expected: Token{Null, "null"}},
+		{s: "$1", expected: Token{Parameter, "$1"}},
+		{s: "?", expected: Token{Parameter, "?"}},
+		{s: "`job`", expected: Token{Identifier, "`job`"}},
+		{s: "job0_.publish_at", expected: Token{Identifier, "job0_.publish_at"}},
+	}
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			assert.Equal(t, []Token{test.expected}, Lex(test.s))
+		})
+	}
+}
+
+func TestLexNormalizesWhitespaceAndCase(t *testing.T) {
+	a := Lex("select * from job where id=12;")
+	b := Lex("SELECT *\n  FROM job\tWHERE id = 12")
+	assert.Equal(t, a, b)
+}
+
+func TestLexCollapsesInLists(t *testing.T) {
+	a := Lex("select * from job where id in (1, 2, 3)")
+	b := Lex("select * from job where id IN (1,2)")
+	assert.Len(t, b, len(a))
+	assert.Equal(t, Token{List, "(1, 2, 3)"}, a[len(a)-1])
+	assert.Equal(t, Token{List, "(1, 2)"}, b[len(b)-1])
+
+	// sub selects are not collapsed
+	c := Lex("select * from job where id in (select job_id from application)")
+	assert.Equal(t, Token{Punctuation, ")"}, c[len(c)-1])
+}
+
+func TestLexSkipsComments(t *testing.T) {
+	assert.Equal(t, Lex("select 1"), Lex("/* hibernate */ select -- comment\n 1"))
+}
+
+func TestLexSubtraction(t *testing.T) {
+	assert.Equal(t, []Token{{Identifier, "a"}, {Operator, "-"}, {Number, "1"}}, Lex("a -1"))
+	assert.Equal(t, []Token{{Identifier, "a"}, {Operator, "="}, {Number, "-1"}}, Lex("a=-1"))
+}
+
+func TestTokenize(t *testing.T) {
+	s := "2024-04-08T09:39:15.070009Z	 2549 Query	update job set description='World, X' where id=39\n"
+	assert.Equal(t, []string{"update", "job", "set", "description", "=", "'World, X'", "where", "id", "=", "39"},
+		Tokenizer{}.Tokenize(s, []string{"update job"}))
+}
+
+func TestTokenizeNonASCIIPrefix(t *testing.T) {
+	// lowering 'Ⱥ' and 'İ' changes their byte length
+	s := strings.Repeat("Ⱥ", 10) + " İ Query	SELECT name from job\n"
+	assert.Equal(t, []string{"select", "name", "from", "job"}, Tokenizer{}.Tokenize(s, []string{"select"}))
+	assert.Equal(t, []string{"'Ⱥİ'"}, Tokenizer{}.Tokenize("'Ⱥİ'", []string{"select"}))
+}
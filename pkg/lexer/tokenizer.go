@@ -0,0 +1,53 @@
+package lexer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Tokenizer is a df.Tokenizer that splits statements by using the SQL lexer. It
+// may be combined with any channel format by setting the channel's tokenizer to
+// "sql".
+type Tokenizer struct {
+}
+
+// Tokenize cuts timestamp and any additional characters that not are part of
+// the plain sql statement from s. The cleaned statement is split into its SQL
+// tokens afterward.
+func (t Tokenizer) Tokenize(s string, patterns []string) []string {
+	var tokens []string
+	for _, token := range Lex(strings.TrimSuffix(cutPrefix(s, patterns), "\n")) {
+		tokens = append(tokens, token.Text)
+	}
+	return tokens
+}
+
+func cutPrefix(s string, patterns []string) string {
+	for _, p := range patterns {
+		idx := indexFold(s, df.NewPattern(p).Include)
+		if idx > -1 {
+			return s[idx:]
+		}
+	}
+	return s
+}
+
+// indexFold returns the byte index of the first case-insensitive occurrence of
+// substr in s or -1. In contrast to searching the lowered s, the index always
+// refers to s, since lowering may change the byte length of runes.
+func indexFold(s, substr string) int {
+	n := utf8.RuneCountInString(substr)
+	for i := range s {
+		j := i
+		for k := 0; k < n && j < len(s); k++ {
+			_, size := utf8.DecodeRuneInString(s[j:])
+			j += size
+		}
+		if strings.EqualFold(s[i:j], substr) {
+			return i
+		}
+	}
+	return -1
+}
@@ -5,6 +5,6 @@ import "github.com/rwirdemann/datafrog/pkg/df"
 type LogFactory struct {
 }
 
-func (f LogFactory) Create(filename string) df.Log {
-	return &SQLLog{}
+func (f LogFactory) Create(df.Channel) (df.Log, error) {
+	return &SQLLog{}, nil
 }
@@ -10,10 +10,14 @@ type SQLLog struct {
 	logs        []string
 	index       int
 	doneChannel chan struct{} // close this channel to notify verification loop to stop
+
+	TailError error // returned by Tail
+	LineError error // returned by NextLine after all logs have been read
+	Closed    bool  // set by Close
 }
 
 func (l *SQLLog) Tail() error {
-	return nil
+	return l.TailError
 }
 
 func NewMemSQLLog(logs []string, doneChannel chan struct{}) *SQLLog {
@@ -30,7 +34,7 @@ func (l *SQLLog) Timestamp(s string) (time.Time, error) {
 
 func (l *SQLLog) NextLine(done chan struct{}) (string, error) {
 	if l.index >= len(l.logs) {
-		return "", nil
+		return "", l.LineError
 	}
 	line := l.logs[l.index]
 	l.index = l.index + 1
@@ -40,6 +44,7 @@ func (l *SQLLog) NextLine(done chan struct{}) (string, error) {
 	return line, nil
 }
 
-func (l *SQLLog) Close() {
-
+func (l *SQLLog) Close() error {
+	l.Closed = true
+	return nil
 }
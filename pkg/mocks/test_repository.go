@@ -1,19 +1,37 @@
 package mocks
 
 import (
-	"errors"
+	"fmt"
 	"github.com/rwirdemann/datafrog/pkg/df"
 )
 
 type TestRepository struct {
 	Testcases []df.Testcase
+	History   map[string][]df.Testcase // written testcases by name, oldest first
 }
 
 func (r *TestRepository) Delete(testname string) error {
-	panic("implement me")
+	for i, tc := range r.Testcases {
+		if tc.Name == testname {
+			r.Testcases = append(r.Testcases[:i], r.Testcases[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", df.ErrTestNotFound, testname)
 }
 
 func (r *TestRepository) Write(_ string, testcase df.Testcase) error {
+	if r.History == nil {
+		r.History = make(map[string][]df.Testcase)
+	}
+	testcase.Revision++
+	r.History[testcase.Name] = append(r.History[testcase.Name], testcase)
+	for i, tc := range r.Testcases {
+		if tc.Name == testcase.Name {
+			r.Testcases[i] = testcase
+			return nil
+		}
+	}
 	r.Testcases = append(r.Testcases, testcase)
 	return nil
 }
@@ -28,7 +46,7 @@ func (r *TestRepository) Get(testname string) (df.Testcase, error) {
 			return tc, nil
 		}
 	}
-	return df.Testcase{}, errors.New("testcase not found")
+	return df.Testcase{}, fmt.Errorf("%w: %s", df.ErrTestNotFound, testname)
 }
 
 func (r *TestRepository) Exists(filename string) bool {
@@ -38,3 +56,20 @@ func (r *TestRepository) Exists(filename string) bool {
 	}
 	return true
 }
+
+func (r *TestRepository) Revisions(testname string) ([]df.Revision, error) {
+	var revisions []df.Revision
+	for _, tc := range r.History[testname] {
+		revisions = append(revisions, df.Revision{Number: tc.Revision, Change: tc.Change, Expectations: len(tc.Expectations)})
+	}
+	return revisions, nil
+}
+
+func (r *TestRepository) GetRevision(testname string, revision int) (df.Testcase, error) {
+	for _, tc := range r.History[testname] {
+		if tc.Revision == revision {
+			return tc, nil
+		}
+	}
+	return df.Testcase{}, fmt.Errorf("%w: %s revision %d", df.ErrRevisionNotFound, testname, revision)
+}
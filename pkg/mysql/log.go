@@ -1,50 +1,37 @@
 package mysql
 
 import (
-	"bufio"
 	"errors"
 	"github.com/rwirdemann/datafrog/pkg/df"
 	log "github.com/sirupsen/logrus"
-	"io"
-	"os"
 	"time"
 )
 
 type Log struct {
-	logfile *os.File
-	reader  *bufio.Reader
+	follower *df.Follower
 }
 
-func NewMYSQLLog(logfileName string) Log {
-	logfile, err := os.Open(logfileName)
+// NewMYSQLLog opens the MySQL general query log logfileName.
+func NewMYSQLLog(logfileName string) (Log, error) {
+	follower, err := df.NewFollower(logfileName)
 	if err != nil {
-		log.Fatal(err)
+		return Log{}, err
 	}
-	return Log{logfile: logfile, reader: bufio.NewReader(logfile)}
+	return Log{follower: follower}, nil
 }
 
 // Tail sets the read cursor of the log file to its end.
 func (m Log) Tail() error {
-	log.Printf("tailing %s...", m.logfile.Name())
-	defer log.Printf("tailing successful!")
-	for {
-		_, err := m.reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				return nil
-			} else {
-				return err
-			}
-		}
-	}
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
 }
 
-func (m Log) Close() {
-	err := m.logfile.Close()
-	if err != nil {
-		log.Fatal(err)
+func (m Log) Close() error {
+	if err := m.follower.Close(); err != nil {
+		return err
 	}
-	log.Printf("%s closed", m.logfile.Name())
+	log.Printf("%s closed", m.follower.Name())
+	return nil
 }
 
 func (m Log) Timestamp(s string) (time.Time, error) {
@@ -59,21 +46,5 @@ func (m Log) Timestamp(s string) (time.Time, error) {
 // file. Waits until a new line becomes available. Returns with an empty line
 // and a nil error if the done channel was closed.
 func (m Log) NextLine(done chan struct{}) (string, error) {
-	for {
-		select {
-		default:
-			line, err := m.reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-				return "", err
-			}
-			return line, nil
-		case <-done:
-			log.Printf("nextline: done channel closed")
-			return "", nil
-		}
-	}
+	return m.follower.ReadLine(done)
 }
@@ -5,6 +5,23 @@ import "github.com/rwirdemann/datafrog/pkg/df"
 type LogFactory struct {
 }
 
-func (f LogFactory) Create(filename string) df.Log {
-	return NewMYSQLLog(filename)
+func (f LogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewMYSQLLog(channel.Log)
+}
+
+// SlowLogFactory creates logs reading the MySQL slow query log.
+type SlowLogFactory struct {
+}
+
+func (f SlowLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewSlowLog(channel.Log)
+}
+
+// PerformanceSchemaLogFactory creates logs reading a JSON-lines dump of
+// performance_schema.events_statements_history_long.
+type PerformanceSchemaLogFactory struct {
+}
+
+func (f PerformanceSchemaLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewPerformanceSchemaLog(channel.Log)
 }
@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+)
+
+// performanceSchemaTimeLayouts lists the accepted layouts of the TIMESTAMP
+// column of a PerformanceSchemaLog entry. Timestamps without time zone are UTC.
+var performanceSchemaTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999",
+}
+
+// performanceSchemaEntry represents a row of
+// performance_schema.events_statements_history_long. TIMESTAMP is the wall
+// clock time of the statement, computed by the dump from TIMER_START.
+type performanceSchemaEntry struct {
+	ThreadID  int64   `json:"THREAD_ID"`
+	EventID   int64   `json:"EVENT_ID"`
+	SQLText   *string `json:"SQL_TEXT"`
+	Timestamp string  `json:"TIMESTAMP"`
+}
+
+// PerformanceSchemaLog reads a JSON-lines dump of
+// performance_schema.events_statements_history_long, i.e. one json object per
+// row with the keys THREAD_ID, EVENT_ID, SQL_TEXT and TIMESTAMP. The dump may be
+// appended periodically: rows already read, i.e. rows whose EVENT_ID doesn't
+// exceed the last read EVENT_ID of their thread, are skipped.
+//
+// Each statement is returned as general query log line, see Log.
+type PerformanceSchemaLog struct {
+	follower *df.Follower
+	events   map[int64]int64 // last read EVENT_ID per THREAD_ID
+}
+
+// NewPerformanceSchemaLog opens the performance_schema dump logfileName.
+func NewPerformanceSchemaLog(logfileName string) (PerformanceSchemaLog, error) {
+	follower, err := df.NewFollower(logfileName)
+	if err != nil {
+		return PerformanceSchemaLog{}, err
+	}
+	return PerformanceSchemaLog{follower: follower, events: make(map[int64]int64)}, nil
+}
+
+// Tail sets the read cursor of the log file to its end. Rows already dumped
+// are skipped even if the dump repeats them afterward.
+func (m PerformanceSchemaLog) Tail() error {
+	log.Printf("tailing %s...", m.follower.Name())
+	for {
+		// remember the already dumped events
+		done := make(chan struct{})
+		close(done)
+		line, err := m.follower.ReadLine(done)
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			return nil
+		}
+		if e, err := parsePerformanceSchemaEntry(line); err == nil {
+			m.events[e.ThreadID] = max(m.events[e.ThreadID], e.EventID)
+		}
+	}
+}
+
+func (m PerformanceSchemaLog) Close() error {
+	return m.follower.Close()
+}
+
+func (m PerformanceSchemaLog) Timestamp(s string) (time.Time, error) {
+	return Log{}.Timestamp(s)
+}
+
+// NextLine reads the next new statement from the dump. Waits until a new
+// statement becomes available. Invalid rows and rows without SQL_TEXT are
+// skipped. Returns with an empty line and a nil error if the done channel was
+// closed.
+func (m PerformanceSchemaLog) NextLine(done chan struct{}) (string, error) {
+	for {
+		line, err := m.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		e, err := parsePerformanceSchemaEntry(line)
+		if err != nil {
+			log.Printf("%s: %v", m.follower.Name(), err)
+			continue
+		}
+		if last, ok := m.events[e.ThreadID]; ok && e.EventID <= last {
+			continue // -> already read
+		}
+		m.events[e.ThreadID] = e.EventID
+		if e.SQLText == nil || strings.TrimSpace(*e.SQLText) == "" {
+			continue
+		}
+		ts, err := parsePerformanceSchemaTime(e.Timestamp)
+		if err != nil {
+			log.Printf("%s: %v", m.follower.Name(), err)
+			continue
+		}
+		return generalLogLine(ts, e.ThreadID, *e.SQLText), nil
+	}
+}
+
+func parsePerformanceSchemaEntry(line string) (performanceSchemaEntry, error) {
+	var e performanceSchemaEntry
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		return e, fmt.Errorf("invalid performance_schema entry: %w", err)
+	}
+	return e, nil
+}
+
+func parsePerformanceSchemaTime(s string) (time.Time, error) {
+	for _, layout := range performanceSchemaTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid TIMESTAMP '%s'", s)
+}
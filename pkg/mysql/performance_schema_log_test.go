@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerformanceSchemaLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "statements.jsonl")
+	dump := `{"THREAD_ID": 48, "EVENT_ID": 10, "SQL_TEXT": "select 1", "TIMESTAMP": "2024-04-08 12:50:58.000001"}
+`
+	require.NoError(t, os.WriteFile(name, []byte(dump), 0644))
+	l, err := NewPerformanceSchemaLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Tail())
+
+	// the next dump repeats event 10
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"THREAD_ID": 48, "EVENT_ID": 10, "SQL_TEXT": "select 1", "TIMESTAMP": "2024-04-08 12:50:58.000001"}
+{"THREAD_ID": 48, "EVENT_ID": 11, "SQL_TEXT": null, "TIMESTAMP": "2024-04-08 12:50:59.000000"}
+{"THREAD_ID": 48, "EVENT_ID": 12, "SQL_TEXT": "insert into job (description, id)\nvalues ('World', 3)", "TIMESTAMP": "2024-04-08T12:50:59.605638Z"}
+`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	line, err := l.NextLine(make(chan struct{}))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-08T12:50:59.605638Z\t   48 Query\tinsert into job (description, id) values ('World', 3)\n", line)
+}
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+)
+
+// generalLogTime is the timestamp layout of the general query log. SlowLog and
+// PerformanceSchemaLog render their entries as general query log lines in
+// order to share Timestamp and Tokenizer with Log.
+const generalLogTime = "2006-01-02T15:04:05.000000Z"
+
+// generalLogLine renders statement as line of the general query log.
+func generalLogLine(ts time.Time, thread int64, statement string) string {
+	return fmt.Sprintf("%s\t%5d Query\t%s\n", ts.UTC().Format(generalLogTime), thread, df.Compact(statement))
+}
+
+var (
+	slowTimeRegex      = regexp.MustCompile(`^# Time: (\S+)`)
+	slowUserHostRegex  = regexp.MustCompile(`^# User@Host: .*Id:\s*(\d+)`)
+	slowSetTimestamp   = regexp.MustCompile(`^SET timestamp=(\d+);$`)
+	slowUseDatabase    = regexp.MustCompile(`^use \S+;$`)
+	slowServerPreamble = regexp.MustCompile(`^(\S+, Version: |Tcp port: |Time\s+Id\s+Command\s+Argument)`)
+)
+
+// SlowLog reads the MySQL slow query log. In order to log all statements the
+// slow query log requires the settings
+//
+//	slow_query_log = 1
+//	long_query_time = 0
+//
+// Each statement is returned as general query log line, see Log. Multi-line
+// statements are joined into a single line. The statement's timestamp is taken
+// from the "# Time:" header or, if missing, from the "SET timestamp" line.
+type SlowLog struct {
+	follower *df.Follower
+}
+
+// NewSlowLog opens the MySQL slow query log logfileName.
+func NewSlowLog(logfileName string) (SlowLog, error) {
+	follower, err := df.NewFollower(logfileName)
+	if err != nil {
+		return SlowLog{}, err
+	}
+	return SlowLog{follower: follower}, nil
+}
+
+// Tail sets the read cursor of the log file to its end.
+func (m SlowLog) Tail() error {
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
+}
+
+func (m SlowLog) Close() error {
+	return m.follower.Close()
+}
+
+func (m SlowLog) Timestamp(s string) (time.Time, error) {
+	return Log{}.Timestamp(s)
+}
+
+// NextLine reads the next statement from the log file. A statement ends with a
+// line terminated by ";". Waits until a complete statement becomes available.
+// Returns with an empty line and a nil error if the done channel was closed.
+func (m SlowLog) NextLine(done chan struct{}) (string, error) {
+	var ts time.Time
+	var thread int64
+	var statement []string
+	for {
+		line, err := m.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if len(statement) == 0 {
+			if match := slowTimeRegex.FindStringSubmatch(line); match != nil {
+				if t, err := time.Parse(time.RFC3339Nano, match[1]); err == nil {
+					ts = t
+				}
+				continue
+			}
+			if match := slowUserHostRegex.FindStringSubmatch(line); match != nil {
+				thread, _ = strconv.ParseInt(match[1], 10, 64)
+				continue
+			}
+			if match := slowSetTimestamp.FindStringSubmatch(line); match != nil {
+				if ts.IsZero() {
+					seconds, _ := strconv.ParseInt(match[1], 10, 64)
+					ts = time.Unix(seconds, 0)
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "#") || slowUseDatabase.MatchString(line) ||
+				slowServerPreamble.MatchString(line) || strings.TrimSpace(line) == "" {
+				continue
+			}
+		}
+
+		statement = append(statement, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			break
+		}
+	}
+
+	s := strings.TrimSuffix(strings.TrimSpace(strings.Join(statement, " ")), ";")
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return generalLogLine(ts, thread, s), nil
+}
@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const slowLog = `/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock
+Time                 Id Command    Argument
+# Time: 2024-04-08T12:50:59.605638Z
+# User@Host: root[root] @ localhost []  Id:     8
+# Query_time: 0.000215  Lock_time: 0.000003 Rows_sent: 0  Rows_examined: 0
+use jobs;
+SET timestamp=1712580659;
+insert into job (description, id)
+values ('World', 3);
+# User@Host: root[root] @ localhost []  Id:     9
+# Query_time: 0.000101  Lock_time: 0.000000 Rows_sent: 1  Rows_examined: 1
+SET timestamp=1712580660;
+select * from job where id=3;
+`
+
+func TestSlowLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "slow.log")
+	require.NoError(t, os.WriteFile(name, []byte(slowLog), 0644))
+	l, err := NewSlowLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan struct{})
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-08T12:50:59.605638Z\t    8 Query\tinsert into job (description, id) values ('World', 3)\n", line)
+	ts, err := l.Timestamp(line)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 8, 12, 50, 59, 605638000, time.UTC), ts)
+	assert.Equal(t, []string{"insert", "into", "job", "(description,", "id)", "values", "(World,", "3)"},
+		Tokenizer{}.Tokenize(line, []string{"insert"}))
+
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-08T12:51:00.000000Z\t    9 Query\tselect * from job where id=3\n", line)
+}
@@ -0,0 +1,33 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeNormalizedEqual(t *testing.T) {
+	patterns := []string{"update job", "insert into job", "select"}
+	tests := []struct {
+		name     string
+		recorded string
+		actual   string
+		equal    bool
+	}{
+		{"update", "2024-04-02T06:38:05.015501Z	1669 Query	update job set description='World, X', tags='', title='Hello' where id=39",
+			"2024-04-02T06:38:06.015501Z	1669 Query	update job set description='Moon', tags='go', title='Hello Moon' where id=40", true},
+		{"insert", "2024-04-02T06:38:05.015501Z	1669 Query	insert into job (id, title) values (1, 'Hello')",
+			"2024-04-02T06:38:06.015501Z	1669 Query	insert into job (id, title) values (2, 'Hello World')", true},
+		{"select", "2024-04-02T06:38:05.015501Z	1669 Query	select * from job where title='Hello'",
+			"2024-04-02T06:38:06.015501Z	1669 Query	select * from job where title='World'", true},
+		{"other column", "2024-04-02T06:38:05.015501Z	1669 Query	select * from job where title='Hello'",
+			"2024-04-02T06:38:06.015501Z	1669 Query	select * from job where tags='Hello'", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := df.Expectation{Tokens: Tokenizer{}.Tokenize(test.recorded, patterns)}
+			assert.Equal(t, test.equal, e.NormalizedEqual(Tokenizer{}.Tokenize(test.actual, patterns)))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Columns of a csvlog entry, see "Using CSV-Format Log Output" in the
+// PostgreSQL documentation.
+const (
+	csvLogTime       = 0
+	csvSessionID     = 5
+	csvTransactionID = 10
+	csvSeverity      = 11
+	csvMessage       = 13
+	csvDetail        = 14
+	csvColumns       = 15 // minimum number of columns
+)
+
+// CSVLog reads the csvlog of PostgreSQL, enabled by the configuration setting
+//
+//	log_destination = 'csvlog'
+//
+// Each entry is returned as single line rendered by record.String. Quoted
+// fields may span several lines, e.g. multi-line statements.
+type CSVLog struct {
+	follower *df.Follower
+}
+
+// NewCSVLog opens the PostgreSQL csvlog logfileName.
+func NewCSVLog(logfileName string) (CSVLog, error) {
+	follower, err := df.NewFollower(logfileName)
+	if err != nil {
+		return CSVLog{}, err
+	}
+	return CSVLog{follower: follower}, nil
+}
+
+func (m CSVLog) Close() error {
+	return m.follower.Close()
+}
+
+func (m CSVLog) Timestamp(s string) (time.Time, error) {
+	return recordTimestamp(s)
+}
+
+// Tail sets the read cursor of the log file to its end.
+func (m CSVLog) Tail() error {
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
+}
+
+// NextLine reads the next csvlog entry. Waits until a complete entry becomes
+// available. Invalid entries are skipped. Returns with an empty line and a nil
+// error if the done channel was closed.
+func (m CSVLog) NextLine(done chan struct{}) (string, error) {
+	for {
+		entry, err := m.nextEntry(done)
+		if err != nil || entry == "" {
+			return "", err
+		}
+		r, err := parseCSVRecord(entry)
+		if err != nil {
+			log.Printf("%s: %v", m.follower.Name(), err)
+			continue
+		}
+		return r.String(), nil
+	}
+}
+
+// nextEntry reads the lines of the next entry. A quoted field continues on the
+// next line as long as the quotes of the entry are unbalanced.
+func (m CSVLog) nextEntry(done chan struct{}) (string, error) {
+	var entry string
+	for {
+		line, err := m.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		entry += line
+		if strings.Count(entry, `"`)%2 == 0 {
+			return entry, nil
+		}
+	}
+}
+
+func parseCSVRecord(entry string) (record, error) {
+	reader := csv.NewReader(strings.NewReader(entry))
+	reader.FieldsPerRecord = -1
+	fields, err := reader.Read()
+	if err != nil {
+		return record{}, fmt.Errorf("invalid csvlog entry: %w", err)
+	}
+	if len(fields) < csvColumns {
+		return record{}, fmt.Errorf("invalid csvlog entry: %d columns", len(fields))
+	}
+	t, err := parseTime(fields[csvLogTime])
+	if err != nil {
+		return record{}, err
+	}
+	return record{
+		Time:          t,
+		SessionID:     fields[csvSessionID],
+		TransactionID: fields[csvTransactionID],
+		Severity:      fields[csvSeverity],
+		Message:       fields[csvMessage],
+		Detail:        fields[csvDetail],
+	}, nil
+}
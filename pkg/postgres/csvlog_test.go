@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const csvlog = `2024-04-19 10:12:16.889 CEST,"dfg","jobs",89718,"127.0.0.1:52144",662238b0.15e76,3,"INSERT",2024-04-19 10:12:00 CEST,3/42,7213,LOG,00000,"execute <unnamed>: insert into job (description, title, id)
+values ($1, $2, $3)","parameters: $1 = 'Say ""Hello""', $2 = NULL, $3 = '1'",,,,,,,,"PostgreSQL JDBC Driver","client backend",,0
+invalid entry
+2024-04-19 08:12:18.541 UTC,"dfg","jobs",89718,"127.0.0.1:52144",662238b0.15e76,4,"SELECT",2024-04-19 10:12:00 CEST,3/43,0,LOG,00000,"statement: select * from job",,,,,,,,,"psql","client backend",,0
+`
+
+func TestCSVLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "postgresql.csv")
+	require.NoError(t, os.WriteFile(name, []byte(csvlog), 0644))
+	l, err := NewCSVLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan struct{})
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-19T08:12:16.889Z [662238b0.15e76] [7213] LOG:  execute <unnamed>: insert into job (description, title, id) values ('Say \"Hello\"', NULL, '1')\n", line)
+	ts, err := l.Timestamp(line)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 19, 8, 12, 16, 889000000, time.UTC), ts)
+	assert.Equal(t, []string{"insert", "into", "job", "(description,", "title,", "id)", "values", "(Say \"Hello\",", "NULL,", "1)"},
+		Tokenizer{}.Tokenize(line, []string{"insert"}))
+
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-19T08:12:18.541Z [662238b0.15e76] [0] LOG:  statement: select * from job\n", line)
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"2024-04-19 10:12:16.889 CEST", time.Date(2024, 4, 19, 8, 12, 16, 889000000, time.UTC)},
+		{"2024-04-19 10:12:16.889 +02", time.Date(2024, 4, 19, 8, 12, 16, 889000000, time.UTC)},
+		{"2024-04-19 04:12:16.889 EDT", time.Date(2024, 4, 19, 8, 12, 16, 889000000, time.UTC)},
+		{"2024-04-19 08:12:16 UTC", time.Date(2024, 4, 19, 8, 12, 16, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		actual, err := parseTime(test.s)
+		assert.NoError(t, err, test.s)
+		assert.True(t, test.expected.Equal(actual), "%s: %v", test.s, actual)
+	}
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// JSONLog reads the jsonlog of PostgreSQL 15 and later, enabled by the
+// configuration setting
+//
+//	log_destination = 'jsonlog'
+//
+// Each entry is returned as single line rendered by record.String.
+type JSONLog struct {
+	follower *df.Follower
+}
+
+// jsonEntry contains the used keys of a jsonlog entry.
+type jsonEntry struct {
+	Timestamp string      `json:"timestamp"`
+	SessionID string      `json:"session_id"`
+	TxID      json.Number `json:"txid"`
+	Severity  string      `json:"error_severity"`
+	Message   string      `json:"message"`
+	Detail    string      `json:"detail"`
+}
+
+// NewJSONLog opens the PostgreSQL jsonlog logfileName.
+func NewJSONLog(logfileName string) (JSONLog, error) {
+	follower, err := df.NewFollower(logfileName)
+	if err != nil {
+		return JSONLog{}, err
+	}
+	return JSONLog{follower: follower}, nil
+}
+
+func (m JSONLog) Close() error {
+	return m.follower.Close()
+}
+
+func (m JSONLog) Timestamp(s string) (time.Time, error) {
+	return recordTimestamp(s)
+}
+
+// Tail sets the read cursor of the log file to its end.
+func (m JSONLog) Tail() error {
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
+}
+
+// NextLine reads the next jsonlog entry. Waits until a new entry becomes
+// available. Invalid entries are skipped. Returns with an empty line and a nil
+// error if the done channel was closed.
+func (m JSONLog) NextLine(done chan struct{}) (string, error) {
+	for {
+		line, err := m.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		r, err := parseJSONRecord(line)
+		if err != nil {
+			log.Printf("%s: %v", m.follower.Name(), err)
+			continue
+		}
+		return r.String(), nil
+	}
+}
+
+func parseJSONRecord(line string) (record, error) {
+	var e jsonEntry
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		return record{}, fmt.Errorf("invalid jsonlog entry: %w", err)
+	}
+	t, err := parseTime(e.Timestamp)
+	if err != nil {
+		return record{}, err
+	}
+	txid := e.TxID.String()
+	if txid == "" {
+		txid = "0"
+	}
+	return record{
+		Time:          t,
+		SessionID:     e.SessionID,
+		TransactionID: txid,
+		Severity:      e.Severity,
+		Message:       e.Message,
+		Detail:        e.Detail,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonlog = `{"timestamp":"2024-04-19 10:12:16.889 CEST","user":"dfg","dbname":"jobs","pid":89718,"session_id":"662238b0.15e76","line_num":3,"ps":"INSERT","vxid":"3/42","txid":7213,"error_severity":"LOG","message":"execute <unnamed>: insert into job (description, id)\nvalues ($1, $2)","detail":"parameters: $1 = 'World', $2 = '1'","application_name":"PostgreSQL JDBC Driver","backend_type":"client backend","query_id":0}
+{"timestamp":"2024-04-19 10:12:18.541 CEST","user":"dfg","dbname":"jobs","pid":89718,"session_id":"662238b0.15e76","line_num":4,"ps":"SELECT","vxid":"3/43","error_severity":"LOG","message":"statement: select * from job","backend_type":"client backend","query_id":0}
+`
+
+func TestJSONLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "postgresql.json")
+	require.NoError(t, os.WriteFile(name, []byte(jsonlog), 0644))
+	l, err := NewJSONLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan struct{})
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-19T08:12:16.889Z [662238b0.15e76] [7213] LOG:  execute <unnamed>: insert into job (description, id) values ('World', '1')\n", line)
+
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-19T08:12:18.541Z [662238b0.15e76] [0] LOG:  statement: select * from job\n", line)
+}
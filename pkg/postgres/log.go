@@ -1,37 +1,36 @@
 package postgres
 
 import (
-	"bufio"
 	"errors"
-	"fmt"
 	"github.com/rwirdemann/datafrog/pkg/df"
-	"io"
 	"log"
-	"os"
 	"regexp"
 	"strings"
 	"time"
 )
 
+// detailGrace is the time Log waits for the DETAIL line of a statement. The
+// server writes statement and DETAIL line at once, thus a missing DETAIL line
+// isn't awaited longer than necessary, e.g. for the last statement of a use
+// case.
+const detailGrace = 100 * time.Millisecond
+
 type Log struct {
-	logfile *os.File
-	reader  *bufio.Reader
-	config  df.Config
+	follower *df.Follower
+	patterns []string // statements matching one of these patterns are merged with their parameters
 }
 
-func NewPostgresLog(logfileName string, config df.Config) Log {
-	logfile, err := os.Open(logfileName)
+// NewPostgresLog opens the PostgreSQL log logfileName.
+func NewPostgresLog(logfileName string, patterns []string) (Log, error) {
+	follower, err := df.NewFollower(logfileName)
 	if err != nil {
-		log.Fatal(err)
+		return Log{}, err
 	}
-	return Log{logfile: logfile, reader: bufio.NewReader(logfile), config: config}
+	return Log{follower: follower, patterns: patterns}, nil
 }
 
-func (m Log) Close() {
-	err := m.logfile.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
+func (m Log) Close() error {
+	return m.follower.Close()
 }
 
 func (m Log) Timestamp(s string) (time.Time, error) {
@@ -44,60 +43,66 @@ func (m Log) Timestamp(s string) (time.Time, error) {
 
 // Tail sets the read cursor of the log file to its end.
 func (m Log) Tail() error {
-	log.Printf("tailing %s...", m.logfile.Name())
-	defer log.Printf("tailing successful!")
-	for {
-		_, err := m.reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				return nil
-			} else {
-				return err
-			}
-		}
-	}
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
 }
 
+// NextLine reads the next line terminated by the delimiter \n from the log
+// file and merges it with the parameters given in the succeeding DETAIL line.
+// Waits until a new line becomes available. Returns with an empty line and a nil
+// error if the done channel was closed.
 func (m Log) NextLine(done chan struct{}) (string, error) {
-	for {
-		line, err := m.reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
-			return "", err
-		}
+	line, err := m.follower.ReadLine(done)
+	if err != nil || line == "" {
+		return line, err
+	}
 
-		matches, _ := df.MatchesPattern(m.config.Channels[0].Patterns, line)
-		if matches {
-			line = m.mergeNext(line)
-		}
+	matches, _ := df.MatchesPattern(m.patterns, line)
+	if matches {
+		return m.mergeNext(line)
+	}
+	return line, nil
+}
 
+// mergeNext replaces the placeholders of line by the parameters given in the
+// succeeding DETAIL line. A succeeding line that isn't a DETAIL line is given
+// back to the follower and returned by the next call to NextLine. Gives up
+// waiting for the DETAIL line after detailGrace.
+func (m Log) mergeNext(line string) (string, error) {
+	grace := make(chan struct{})
+	timer := time.AfterFunc(detailGrace, func() { close(grace) })
+	next, err := m.follower.ReadLine(grace)
+	timer.Stop()
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(next, "DETAIL") {
+		if next != "" {
+			m.follower.Unread(next)
+		}
 		return line, nil
 	}
+	return bindParameters(line, next), nil
 }
 
-func (m Log) mergeNext(line string) string {
-	next, _ := m.reader.ReadString('\n')
-	if strings.Contains(next, "DETAIL") {
-		values := make(map[int]string)
-		r := regexp.MustCompile(`\$\d\s=\s'(?:[^']|'')*'|\$\d\s=\sNULL`)
-		matches := r.FindAllStringSubmatch(next, -1)
-		for i, v := range matches {
-			split := strings.Split(v[0], "=")
-			values[i] = strings.Trim(split[len(split)-1], " ")
-		}
-		i := 1
-		for {
-			placeholder := fmt.Sprintf("$%d", i)
-			if strings.Contains(line, placeholder) {
-				line = strings.Replace(line, placeholder, values[i-1], -1)
-				i = i + 1
-			} else {
-				break
-			}
-		}
+// parameterRegex matches a single parameter of a "parameters:" detail, e.g.
+// "$1 = 'World'" or "$4 = NULL".
+var parameterRegex = regexp.MustCompile(`(\$\d+)\s=\s('(?:[^']|'')*'|NULL)`)
+
+// placeholderRegex matches the placeholders of a prepared statement.
+var placeholderRegex = regexp.MustCompile(`\$\d+`)
+
+// bindParameters replaces the placeholders $1, $2, ... of statement by the
+// values given in detail, e.g. "parameters: $1 = 'World', $2 = NULL".
+func bindParameters(statement, detail string) string {
+	values := make(map[string]string)
+	for _, m := range parameterRegex.FindAllStringSubmatch(detail, -1) {
+		values[m[1]] = m[2]
 	}
-	return line
+	return placeholderRegex.ReplaceAllStringFunc(statement, func(p string) string {
+		if v, ok := values[p]; ok {
+			return v
+		}
+		return p
+	})
 }
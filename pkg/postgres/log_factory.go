@@ -0,0 +1,26 @@
+package postgres
+
+import "github.com/rwirdemann/datafrog/pkg/df"
+
+type LogFactory struct {
+}
+
+func (f LogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewPostgresLog(channel.Log, channel.Patterns)
+}
+
+// CSVLogFactory creates logs reading the PostgreSQL csvlog.
+type CSVLogFactory struct {
+}
+
+func (f CSVLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewCSVLog(channel.Log)
+}
+
+// JSONLogFactory creates logs reading the PostgreSQL jsonlog.
+type JSONLogFactory struct {
+}
+
+func (f JSONLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewJSONLog(channel.Log)
+}
@@ -2,12 +2,14 @@ package postgres
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
 
-	"github.com/rwirdemann/datafrog/pkg/df"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegex(t *testing.T) {
@@ -19,14 +21,39 @@ func TestRegex(t *testing.T) {
 }
 
 func TestReadLine(t *testing.T) {
-	c := df.Config{Channels: []df.Channel{{Patterns: []string{"insert"}}}}
-	pl := NewPostgresLog("postgres.log", c)
+	pl, err := NewPostgresLog("postgres.log", []string{"insert"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer pl.Close()
 	actual := readLine(t, pl)
 	expected := "2024-04-19 10:12:16.889 CEST [89718] LOG:  execute <unnamed>: insert into job (description, publish_at, publish_trials, published_timestamp, tags, title, id) values ('World', '2024-04-19 10:12:12', '0', NULL, '', 'Hello', '1')\n"
 	assert.Equal(t, expected, actual)
 }
 
+// The last statement of a use case has no succeeding line, thus a statement
+// without DETAIL line is returned without waiting for further lines.
+func TestReadLineWithoutDetail(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "postgres.log")
+	statement := "2024-04-19 10:12:16.889 CEST [89718] LOG:  statement: insert into job (id) values (1)\n"
+	require.NoError(t, os.WriteFile(name, []byte(statement), 0644))
+	pl, err := NewPostgresLog(name, []string{"insert"})
+	require.NoError(t, err)
+	defer pl.Close()
+
+	lines := make(chan string)
+	go func() {
+		line, _ := pl.NextLine(make(chan struct{}))
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		assert.Equal(t, statement, line)
+	case <-time.After(2 * time.Second):
+		t.Fatal("statement without DETAIL line not returned")
+	}
+}
+
 func TestPostgresTimestamp(t *testing.T) {
 	pl := Log{}
 	actual, err := pl.Timestamp("2024-04-19 10:12:16.889 CEST [89718] LOG:  execute <unnamed>: insert into job (description, publish_at, publish_trials, published_timestamp, tags, title, id) values ('World', '2024-04-19 10:12:12', '0', NULL, '', 'Hello', '1')")
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// record represents a single entry of a structured PostgreSQL log, i.e. a
+// csvlog or jsonlog entry.
+type record struct {
+	Time          time.Time
+	SessionID     string
+	TransactionID string
+	Severity      string
+	Message       string // e.g. "execute <unnamed>: insert into job ..."
+	Detail        string // e.g. "parameters: $1 = 'World', $2 = NULL"
+}
+
+// String renders r as single log line of the form
+//
+//	2024-04-19T08:12:16.889Z [662238b0.15e76] [0] LOG:  execute <unnamed>: insert into job ...
+//
+// The timestamp is converted to UTC, the placeholders of the statement are
+// replaced by the parameters given in Detail and line breaks of multi-line
+// statements are replaced by spaces.
+func (r record) String() string {
+	message := r.Message
+	if strings.HasPrefix(r.Detail, "parameters:") {
+		message = bindParameters(message, r.Detail)
+	}
+	message = df.Compact(message)
+	return fmt.Sprintf("%s [%s] [%s] %s:  %s\n",
+		r.Time.UTC().Format(time.RFC3339Nano), r.SessionID, r.TransactionID, r.Severity, message)
+}
+
+// recordTimestamp returns the timestamp of a line rendered by record.String.
+func recordTimestamp(s string) (time.Time, error) {
+	t, err := df.Timestamp(s, "^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9:.]+Z", time.RFC3339Nano)
+	if err != nil {
+		return time.Time{}, errors.New("string contains no valid Timestamp")
+	}
+	return t, nil
+}
+
+// timeLayouts lists the layouts of the log_time column of a structured log
+// depending on the PostgreSQL setting log_timezone.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999 -07",
+	"2006-01-02 15:04:05.999 -0700",
+	"2006-01-02 15:04:05.999 -07:00",
+	"2006-01-02 15:04:05.999 MST",
+}
+
+// zoneOffsets maps common time zone abbreviations to their offset in seconds.
+// Go only resolves the abbreviation of the local time zone.
+var zoneOffsets = map[string]int{
+	"UTC": 0, "GMT": 0, "WET": 0, "WEST": 3600, "CET": 3600, "CEST": 7200, "EET": 7200, "EEST": 10800,
+	"EST": -5 * 3600, "EDT": -4 * 3600, "CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600, "PST": -8 * 3600, "PDT": -7 * 3600,
+}
+
+// parseTime parses a log_time value like "2024-04-19 10:12:16.889 CEST".
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		name, offset := t.Zone()
+		if o, ok := zoneOffsets[name]; ok && offset != o {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, o))
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid log time '%s'", s)
+}
@@ -5,10 +5,11 @@ import (
 	"strings"
 )
 
-// Tokenizer tokenizes PostgreSQL log entries. PostgreSQL configuration
-// settings:
+// Tokenizer tokenizes PostgreSQL log entries of Log, CSVLog and JSONLog.
+// PostgreSQL configuration settings:
 //
-//	log_destination = 'stderr,csvlog'
+//	log_destination = 'stderr' | 'csvlog' | 'jsonlog'
+//	log_statement = 'all'
 //
 // PostgreSQL splits single sql statements into two parts:
 //
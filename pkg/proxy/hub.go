@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriberBuffer is the number of lines a Subscription buffers until they
+// are read.
+const subscriberBuffer = 4096
+
+// A Hub publishes the lines of a proxy to its subscriptions. Besides the wire
+// protocol proxies of this package, the http proxy of package rest publishes
+// its calls via a hub.
+type Hub struct {
+	name string // name used in log messages, e.g. the listen address
+
+	mu          sync.Mutex
+	subscribers map[chan string]struct{}
+}
+
+// NewHub creates a hub without subscriptions.
+func NewHub(name string) *Hub {
+	return &Hub{name: name, subscribers: make(map[chan string]struct{})}
+}
+
+// Publish sends line to all subscriptions. Subscriptions that don't keep up
+// lose the line.
+func (h *Hub) Publish(line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscribers {
+		select {
+		case s <- line:
+		default:
+			log.Warnf("proxy %s: subscriber lost line: %s", h.name, line)
+		}
+	}
+}
+
+// Subscribe returns a subscription that receives all lines published from now
+// on.
+func (h *Hub) Subscribe() Subscription {
+	s := Subscription{hub: h, lines: make(chan string, subscriberBuffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[s.lines] = struct{}{}
+	return s
+}
+
+func (h *Hub) unsubscribe(s chan string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, s)
+}
+
+// Subscription implements the methods of df.Log a proxy log shares, only
+// Timestamp depends on the format of the published lines.
+type Subscription struct {
+	hub   *Hub
+	lines chan string
+}
+
+// Tail discards all lines received so far.
+func (s Subscription) Tail() error {
+	for {
+		select {
+		case <-s.lines:
+		default:
+			return nil
+		}
+	}
+}
+
+// Close unsubscribes from the hub. The proxy keeps running.
+func (s Subscription) Close() error {
+	s.hub.unsubscribe(s.lines)
+	return nil
+}
+
+// NextLine waits for the next line. Returns with an empty line and a nil error
+// if the done channel was closed.
+func (s Subscription) NextLine(done chan struct{}) (string, error) {
+	select {
+	case line := <-s.lines:
+		return line, nil
+	case <-done:
+		return "", nil
+	}
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Log subscribes to the statements of a Proxy. Each statement is returned as
+// line of the form
+//
+//	<timestamp in RFC 3339, UTC>\t<connection id>\t<statement>
+type Log struct {
+	Subscription
+	proxy *Proxy
+}
+
+// NewLog subscribes to the proxy described by config and starts the proxy if
+// it isn't running yet.
+func NewLog(protocol string, config df.ProxyConfig) (Log, error) {
+	p, err := Listen(protocol, config)
+	if err != nil {
+		return Log{}, err
+	}
+	return Log{Subscription: p.hub.Subscribe(), proxy: p}, nil
+}
+
+func (l Log) Timestamp(s string) (time.Time, error) {
+	ts, _, found := strings.Cut(s, "\t")
+	if !found {
+		return time.Time{}, errors.New("string contains no valid Timestamp")
+	}
+	return time.Parse(time.RFC3339Nano, ts)
+}
+
+// LogFactory creates logs subscribing to the proxy of a channel.
+type LogFactory struct {
+	Protocol string
+}
+
+func (f LogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewLog(f.Protocol, channel.Proxy)
+}
+
+// Validate ensures that listen and upstream address of channel are set.
+func (f LogFactory) Validate(channel df.Channel) error {
+	if channel.Proxy.Listen == "" || channel.Proxy.Upstream == "" {
+		return errors.New("proxy requires listen and upstream address")
+	}
+	return nil
+}
+
+// Tokenizer tokenizes the statements read by Log.
+type Tokenizer struct {
+}
+
+// Tokenize cuts timestamp and connection id from s and splits the statement by
+// spaces into single tokens.
+func (t Tokenizer) Tokenize(s string, _ []string) []string {
+	parts := strings.SplitN(s, "\t", 3)
+	return df.Tokenize(strings.TrimSuffix(parts[len(parts)-1], "\n"))
+}
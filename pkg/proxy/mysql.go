@@ -0,0 +1,455 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MySQL commands and capabilities, see the MySQL client/server protocol.
+const (
+	comQuery       = 0x03
+	comStmtPrepare = 0x16
+	comStmtExecute = 0x17
+	comStmtClose   = 0x19
+
+	clientSSL             = 0x00000800
+	clientQueryAttributes = 0x08000000
+
+	parameterCountAvailable = 0x08 // COM_STMT_EXECUTE flag
+)
+
+var errShortPacket = errors.New("short packet")
+
+// mysqlStatement is a prepared statement of a MySQL connection.
+type mysqlStatement struct {
+	query  string
+	params int
+	types  []byte // parameter types of the last execution, two bytes per parameter
+}
+
+// mysqlDecoder decodes the MySQL client/server protocol. Decoding stops when
+// the connection switches to TLS.
+type mysqlDecoder struct {
+	emit func(string)
+
+	mu           sync.Mutex
+	client       []byte // undecoded client bytes
+	server       []byte // undecoded server bytes
+	disabled     bool
+	handshake    bool   // true after the client's handshake response
+	capabilities uint32 // capabilities of the client
+	lastCommand  byte
+	prepared     string // query of the last COM_STMT_PREPARE
+	statements   map[uint32]*mysqlStatement
+}
+
+func newMySQLDecoder(emit func(string)) *mysqlDecoder {
+	return &mysqlDecoder{emit: emit, statements: make(map[uint32]*mysqlStatement)}
+}
+
+func (d *mysqlDecoder) fromClient(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.decode(&d.client, b, d.clientPacket)
+}
+
+func (d *mysqlDecoder) fromServer(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.decode(&d.server, b, d.serverPacket)
+}
+
+// decode appends b to buf and passes each complete packet of buf to handle.
+func (d *mysqlDecoder) decode(buf *[]byte, b []byte, handle func(seq byte, payload []byte)) {
+	if d.disabled {
+		return
+	}
+	*buf = append(*buf, b...)
+	for len(*buf) >= 4 && !d.disabled {
+		length := int((*buf)[0]) | int((*buf)[1])<<8 | int((*buf)[2])<<16
+		if len(*buf) < 4+length {
+			break
+		}
+		seq := (*buf)[3]
+		payload := (*buf)[4 : 4+length]
+		*buf = (*buf)[4+length:]
+		handle(seq, payload)
+	}
+	if len(*buf) > maxBuffer {
+		d.disabled = true
+	}
+	if d.disabled {
+		d.client, d.server = nil, nil
+	}
+}
+
+func (d *mysqlDecoder) clientPacket(seq byte, p []byte) {
+	if seq != 0 {
+		// handshake response or authentication data
+		if seq == 1 && !d.handshake && len(p) >= 4 {
+			d.handshake = true
+			d.capabilities = binary.LittleEndian.Uint32(p)
+			if d.capabilities&clientSSL != 0 {
+				d.disabled = true
+			}
+		}
+		return
+	}
+	if len(p) == 0 {
+		return
+	}
+	d.lastCommand = p[0]
+	switch p[0] {
+	case comQuery:
+		if s, err := d.query(p[1:]); err == nil {
+			d.emit(s)
+		}
+	case comStmtPrepare:
+		d.prepared = string(p[1:])
+	case comStmtExecute:
+		if s, err := d.execute(p[1:]); err == nil {
+			d.emit(s)
+		}
+	case comStmtClose:
+		if len(p) >= 5 {
+			delete(d.statements, binary.LittleEndian.Uint32(p[1:]))
+		}
+	}
+}
+
+func (d *mysqlDecoder) serverPacket(seq byte, p []byte) {
+	if seq != 1 || d.lastCommand != comStmtPrepare {
+		return
+	}
+	d.lastCommand = 0
+
+	// COM_STMT_PREPARE_OK: status, statement id, columns, params
+	if len(p) >= 9 && p[0] == 0x00 {
+		id := binary.LittleEndian.Uint32(p[1:])
+		params := int(binary.LittleEndian.Uint16(p[7:]))
+		d.statements[id] = &mysqlStatement{query: d.prepared, params: params}
+	}
+}
+
+// execute returns the query of the prepared statement executed by the
+// COM_STMT_EXECUTE payload p, its placeholders replaced by the bound values.
+func (d *mysqlDecoder) execute(p []byte) (string, error) {
+	r := &reader{b: p}
+	id, err := r.uint32()
+	if err != nil {
+		return "", err
+	}
+	s, ok := d.statements[id]
+	if !ok {
+		return "", fmt.Errorf("unknown statement %d", id)
+	}
+	flags, err := r.next(1)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.next(4); err != nil { // iteration count
+		return "", err
+	}
+	attributes := d.capabilities&clientQueryAttributes != 0
+	if s.params == 0 && !(attributes && flags[0]&parameterCountAvailable != 0) {
+		return s.query, nil
+	}
+	params := s.params
+	if attributes {
+		n, err := r.lenencInt()
+		if err != nil {
+			return "", err
+		}
+		params = int(n)
+	}
+	if params == 0 {
+		return s.query, nil
+	}
+
+	values, types, err := d.values(r, params, s.types)
+	if err != nil {
+		return "", err
+	}
+	s.types = types
+	return bindQuestionMarks(s.query, values), nil
+}
+
+// query returns the query text of the COM_QUERY payload p. If the client
+// sends query attributes, the text follows the attributes, which are skipped.
+func (d *mysqlDecoder) query(p []byte) (string, error) {
+	if d.capabilities&clientQueryAttributes == 0 {
+		return string(p), nil
+	}
+	r := &reader{b: p}
+	params, err := r.lenencInt()
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.lenencInt(); err != nil { // parameter set count, always 1
+		return "", err
+	}
+	if params > 0 {
+		if _, _, err := d.values(r, int(params), nil); err != nil {
+			return "", err
+		}
+	}
+	return string(r.b), nil
+}
+
+// values reads the null bitmap, the types and the values of params binary
+// protocol parameters from r. types are the parameter types bound before, they
+// are replaced if the client binds new types. Returns the values together with
+// the parameter types.
+func (d *mysqlDecoder) values(r *reader, params int, types []byte) ([]string, []byte, error) {
+	nulls, err := r.next((params + 7) / 8)
+	if err != nil {
+		return nil, nil, err
+	}
+	bound, err := r.next(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bound[0] == 1 {
+		types = nil
+		for i := 0; i < params; i++ {
+			t, err := r.next(2)
+			if err != nil {
+				return nil, nil, err
+			}
+			types = append(types, t...)
+			if d.capabilities&clientQueryAttributes != 0 {
+				if _, err := r.lenencString(); err != nil { // parameter name
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	if len(types) < 2*params {
+		return nil, nil, errors.New("missing parameter types")
+	}
+
+	var values []string
+	for i := 0; i < params; i++ {
+		if nulls[i/8]&(1<<(i%8)) != 0 {
+			values = append(values, "NULL")
+			continue
+		}
+		v, err := mysqlValue(r, types[2*i], types[2*i+1]&0x80 != 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, v)
+	}
+	return values, types, nil
+}
+
+// mysqlValue reads a single binary protocol value of type t.
+func mysqlValue(r *reader, t byte, unsigned bool) (string, error) {
+	integer := func(n int) (string, error) {
+		b, err := r.next(n)
+		if err != nil {
+			return "", err
+		}
+		var u uint64
+		for i := n - 1; i >= 0; i-- {
+			u = u<<8 | uint64(b[i])
+		}
+		if unsigned {
+			return strconv.FormatUint(u, 10), nil
+		}
+		shift := 64 - 8*n
+		return strconv.FormatInt(int64(u<<shift)>>shift, 10), nil
+	}
+
+	switch t {
+	case 0x01: // TINY
+		return integer(1)
+	case 0x02, 0x0d: // SHORT, YEAR
+		return integer(2)
+	case 0x03, 0x09: // LONG, INT24
+		return integer(4)
+	case 0x08: // LONGLONG
+		return integer(8)
+	case 0x04: // FLOAT
+		b, err := r.next(4)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32), nil
+	case 0x05: // DOUBLE
+		b, err := r.next(8)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64), nil
+	case 0x06: // NULL
+		return "NULL", nil
+	case 0x07, 0x0a, 0x0c: // TIMESTAMP, DATE, DATETIME
+		n, err := r.next(1)
+		if err != nil {
+			return "", err
+		}
+		b, err := r.next(int(n[0]))
+		if err != nil {
+			return "", err
+		}
+		return quote(mysqlDateTime(b)), nil
+	case 0x0b: // TIME
+		n, err := r.next(1)
+		if err != nil {
+			return "", err
+		}
+		b, err := r.next(int(n[0]))
+		if err != nil {
+			return "", err
+		}
+		return quote(mysqlTime(b)), nil
+	default: // strings, decimals, blobs, json, ...
+		s, err := r.lenencString()
+		if err != nil {
+			return "", err
+		}
+		if t == 0xf6 { // NEWDECIMAL
+			return s, nil
+		}
+		return quote(s), nil
+	}
+}
+
+func mysqlDateTime(b []byte) string {
+	if len(b) < 4 {
+		return "0000-00-00 00:00:00"
+	}
+	s := fmt.Sprintf("%04d-%02d-%02d", binary.LittleEndian.Uint16(b), b[2], b[3])
+	if len(b) >= 7 {
+		s += fmt.Sprintf(" %02d:%02d:%02d", b[4], b[5], b[6])
+	}
+	if len(b) >= 11 {
+		s += fmt.Sprintf(".%06d", binary.LittleEndian.Uint32(b[7:]))
+	}
+	return s
+}
+
+func mysqlTime(b []byte) string {
+	if len(b) < 8 {
+		return "00:00:00"
+	}
+	sign := ""
+	if b[0] == 1 {
+		sign = "-"
+	}
+	hours := binary.LittleEndian.Uint32(b[1:])*24 + uint32(b[5])
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, hours, b[6], b[7])
+	if len(b) >= 12 {
+		s += fmt.Sprintf(".%06d", binary.LittleEndian.Uint32(b[8:]))
+	}
+	return s
+}
+
+// bindQuestionMarks replaces the ? placeholders of query that are not part of
+// a quoted string by values.
+func bindQuestionMarks(query string, values []string) string {
+	var sb strings.Builder
+	var quote rune
+	i := 0
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && i < len(values):
+			sb.WriteString(values[i])
+			i++
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// quote returns s as SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// reader reads the fields of a wire protocol message.
+type reader struct {
+	b []byte
+}
+
+func (r *reader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.b) < n {
+		return nil, errShortPacket
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// lenencInt reads a MySQL length-encoded integer.
+func (r *reader) lenencInt() (uint64, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	switch b[0] {
+	case 0xfc:
+		n = 2
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 8
+	default:
+		return uint64(b[0]), nil
+	}
+	v, err := r.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for i := n - 1; i >= 0; i-- {
+		u = u<<8 | uint64(v[i])
+	}
+	return u, nil
+}
+
+// lenencString reads a MySQL length-encoded string.
+func (r *reader) lenencString() (string, error) {
+	n, err := r.lenencInt()
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(r.b)) {
+		return "", errShortPacket
+	}
+	b, err := r.next(int(n))
+	return string(b), err
+}
+
+// cstring reads a null terminated string.
+func (r *reader) cstring() (string, error) {
+	i := strings.IndexByte(string(r.b), 0)
+	if i < 0 {
+		return "", errShortPacket
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s, nil
+}
@@ -0,0 +1,475 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request codes of untyped PostgreSQL startup messages.
+const (
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+	cancelRequestCode = 80877102
+)
+
+// Type oids of the parameters decoded by the proxy.
+const (
+	oidBool        = 16
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidOid         = 26
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidVarchar     = 1043
+	oidTimestamp   = 1114
+	oidTimestampTZ = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+)
+
+// postgresEpoch is the origin of binary timestamps.
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// postgresStatement is a prepared statement of a PostgreSQL connection.
+type postgresStatement struct {
+	query string
+	oids  []uint32 // parameter types, 0 if unspecified
+}
+
+// describe is a Describe message of a prepared statement that waits for the
+// server's ParameterDescription.
+type describe struct {
+	statement string
+	sync      int // number of client messages answered by ReadyForQuery sent before
+}
+
+// postgresDecoder decodes the PostgreSQL frontend/backend protocol. Decoding
+// stops when the connection switches to TLS or GSS encryption.
+//
+// Drivers like pgx and JDBC parse statements with unspecified parameter types
+// and bind binary parameters of the types the server described. The decoder
+// therefore assigns each ParameterDescription of the server to the Describe
+// message it answers. Both are counted in units of ReadyForQuery, because the
+// server skips all messages up to the next Sync after an error.
+type postgresDecoder struct {
+	emit func(string)
+
+	mu           sync.Mutex
+	client       []byte // undecoded client bytes
+	server       []byte // undecoded server bytes
+	skip         int    // remaining bytes of an irrelevant server message
+	disabled     bool
+	startup      bool // true until the client sent its startup message
+	sslRequested bool // true if the client waits for the server's answer to a SSL or GSS request
+	statements   map[string]postgresStatement
+	portals      map[string]string // bound but not yet executed queries by portal
+	describes    []describe        // describes waiting for their ParameterDescription
+	clientSyncs  int               // client messages answered by ReadyForQuery
+	serverSyncs  int               // ReadyForQuery messages of the server
+}
+
+func newPostgresDecoder(emit func(string)) *postgresDecoder {
+	return &postgresDecoder{
+		emit:       emit,
+		startup:    true,
+		statements: make(map[string]postgresStatement),
+		portals:    make(map[string]string),
+	}
+}
+
+func (d *postgresDecoder) fromClient(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.disabled {
+		return
+	}
+	d.client = append(d.client, b...)
+	for !d.disabled {
+		if d.startup {
+			// untyped message: length, request code or protocol version
+			if len(d.client) < 8 {
+				break
+			}
+			length := int(binary.BigEndian.Uint32(d.client))
+			if length < 8 || len(d.client) < length {
+				break
+			}
+			switch binary.BigEndian.Uint32(d.client[4:]) {
+			case sslRequestCode, gssEncRequestCode:
+				d.sslRequested = true
+			case cancelRequestCode:
+			default:
+				d.startup = false
+				d.clientSyncs++ // answered by ReadyForQuery after authentication
+			}
+			d.client = d.client[length:]
+			continue
+		}
+
+		if len(d.client) < 5 {
+			break
+		}
+		length := int(binary.BigEndian.Uint32(d.client[1:]))
+		if length < 4 || len(d.client) < 1+length {
+			break
+		}
+		d.message(d.client[0], d.client[5:1+length])
+		d.client = d.client[1+length:]
+	}
+	if len(d.client) > maxBuffer {
+		d.disabled = true
+	}
+	if d.disabled {
+		d.client = nil
+	}
+}
+
+// fromServer checks the server's answer to a SSL or GSS request and decodes
+// the ParameterDescription and ReadyForQuery messages. All other server
+// messages, e.g. the rows of a result, are skipped without buffering them.
+func (d *postgresDecoder) fromServer(b []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.disabled || len(b) == 0 {
+		return
+	}
+	if d.sslRequested {
+		d.sslRequested = false
+		if b[0] == 'S' || b[0] == 'G' {
+			d.disabled = true
+			d.client = nil
+			return
+		}
+		b = b[1:] // single byte answer 'N'
+	}
+
+	for len(b) > 0 {
+		if d.skip > 0 {
+			n := min(d.skip, len(b))
+			d.skip -= n
+			b = b[n:]
+			continue
+		}
+		d.server = append(d.server, b...)
+		b = nil
+		for len(d.server) >= 5 {
+			length := int(binary.BigEndian.Uint32(d.server[1:]))
+			if length < 4 {
+				d.disabled = true
+				return
+			}
+			t := d.server[0]
+			if t != 't' && t != 'Z' {
+				// irrelevant message, skip its remaining bytes
+				if len(d.server) < 1+length {
+					d.skip = 1 + length - len(d.server)
+					d.server = nil
+					break
+				}
+				d.server = d.server[1+length:]
+				continue
+			}
+			if len(d.server) < 1+length {
+				break
+			}
+			d.serverMessage(t, d.server[5:1+length])
+			d.server = d.server[1+length:]
+		}
+		if len(d.server) > maxBuffer {
+			d.disabled = true
+			return
+		}
+	}
+}
+
+// serverMessage handles the server message t with payload p.
+func (d *postgresDecoder) serverMessage(t byte, p []byte) {
+	switch t {
+	case 't': // parameter description
+		r := &reader{b: p}
+		n, err := r.int16()
+		if err != nil {
+			return
+		}
+		var oids []uint32
+		for i := 0; i < n; i++ {
+			b, err := r.next(4)
+			if err != nil {
+				return
+			}
+			oids = append(oids, binary.BigEndian.Uint32(b))
+		}
+		// describes of earlier syncs were skipped due to an error
+		for len(d.describes) > 0 && d.describes[0].sync < d.serverSyncs {
+			d.describes = d.describes[1:]
+		}
+		if len(d.describes) == 0 || d.describes[0].sync != d.serverSyncs {
+			return
+		}
+		if s, ok := d.statements[d.describes[0].statement]; ok {
+			s.oids = oids
+			d.statements[d.describes[0].statement] = s
+		}
+		d.describes = d.describes[1:]
+	case 'Z': // ready for query
+		d.serverSyncs++
+	}
+}
+
+func (d *postgresDecoder) message(t byte, p []byte) {
+	r := &reader{b: p}
+	switch t {
+	case 'Q': // simple query
+		d.clientSyncs++
+		if q, err := r.cstring(); err == nil {
+			d.emit(q)
+		}
+	case 'S': // sync
+		d.clientSyncs++
+	case 'D': // describe
+		kind, err := r.next(1)
+		if err != nil || kind[0] != 'S' {
+			return
+		}
+		if name, err := r.cstring(); err == nil {
+			d.describes = append(d.describes, describe{statement: name, sync: d.clientSyncs})
+		}
+	case 'P': // parse
+		s, err := d.parse(r)
+		if err == nil {
+			d.statements[s.name] = s.postgresStatement
+		}
+	case 'B': // bind
+		portal, query, err := d.bind(r)
+		if err == nil {
+			d.portals[portal] = query
+		}
+	case 'E': // execute
+		// a suspended portal is executed again for its next rows, thus each
+		// bound query is only emitted by its first execution
+		if portal, err := r.cstring(); err == nil {
+			if q, ok := d.portals[portal]; ok {
+				delete(d.portals, portal)
+				d.emit(q)
+			}
+		}
+	case 'C': // close
+		kind, err := r.next(1)
+		if err != nil {
+			return
+		}
+		name, _ := r.cstring()
+		if kind[0] == 'S' {
+			delete(d.statements, name)
+		} else {
+			delete(d.portals, name)
+		}
+	}
+}
+
+type namedStatement struct {
+	name string
+	postgresStatement
+}
+
+func (d *postgresDecoder) parse(r *reader) (namedStatement, error) {
+	name, err := r.cstring()
+	if err != nil {
+		return namedStatement{}, err
+	}
+	query, err := r.cstring()
+	if err != nil {
+		return namedStatement{}, err
+	}
+	n, err := r.int16()
+	if err != nil {
+		return namedStatement{}, err
+	}
+	s := namedStatement{name: name, postgresStatement: postgresStatement{query: query}}
+	for i := 0; i < n; i++ {
+		b, err := r.next(4)
+		if err != nil {
+			return namedStatement{}, err
+		}
+		s.oids = append(s.oids, binary.BigEndian.Uint32(b))
+	}
+	return s, nil
+}
+
+// bind returns the portal and the query of the bound statement, its
+// placeholders replaced by the bound values.
+func (d *postgresDecoder) bind(r *reader) (string, string, error) {
+	portal, err := r.cstring()
+	if err != nil {
+		return "", "", err
+	}
+	name, err := r.cstring()
+	if err != nil {
+		return "", "", err
+	}
+	s, ok := d.statements[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown statement '%s'", name)
+	}
+
+	n, err := r.int16()
+	if err != nil {
+		return "", "", err
+	}
+	if n < 0 {
+		return "", "", errShortPacket
+	}
+	formats := make([]int, n)
+	for i := range formats {
+		if formats[i], err = r.int16(); err != nil {
+			return "", "", err
+		}
+	}
+
+	params, err := r.int16()
+	if err != nil {
+		return "", "", err
+	}
+	values := make(map[string]string)
+	for i := 0; i < params; i++ {
+		b, err := r.next(4)
+		if err != nil {
+			return "", "", err
+		}
+		length := int32(binary.BigEndian.Uint32(b))
+		placeholder := "$" + strconv.Itoa(i+1)
+		if length < 0 {
+			values[placeholder] = "NULL"
+			continue
+		}
+		v, err := r.next(int(length))
+		if err != nil {
+			return "", "", err
+		}
+		format := 0
+		switch len(formats) {
+		case 0:
+		case 1:
+			format = formats[0]
+		default:
+			if i < len(formats) {
+				format = formats[i]
+			}
+		}
+		var oid uint32
+		if i < len(s.oids) {
+			oid = s.oids[i]
+		}
+		values[placeholder] = postgresValue(v, format == 1, oid)
+	}
+	return portal, bindDollars(s.query, values), nil
+}
+
+// postgresValue renders the text or binary parameter v of type oid as SQL
+// literal.
+func postgresValue(v []byte, binaryFormat bool, oid uint32) string {
+	if !binaryFormat {
+		switch oid {
+		case oidInt2, oidInt4, oidInt8, oidOid, oidFloat4, oidFloat8, oidNumeric:
+			return string(v)
+		}
+		return quote(string(v))
+	}
+
+	switch {
+	case oid == oidBool && len(v) == 1:
+		return strconv.FormatBool(v[0] != 0)
+	case oid == oidInt2 && len(v) == 2:
+		return strconv.Itoa(int(int16(binary.BigEndian.Uint16(v))))
+	case (oid == oidInt4 || oid == oidOid) && len(v) == 4:
+		return strconv.Itoa(int(int32(binary.BigEndian.Uint32(v))))
+	case oid == oidInt8 && len(v) == 8:
+		return strconv.FormatInt(int64(binary.BigEndian.Uint64(v)), 10)
+	case oid == oidFloat4 && len(v) == 4:
+		return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(v))), 'g', -1, 32)
+	case oid == oidFloat8 && len(v) == 8:
+		return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(v)), 'g', -1, 64)
+	case oid == oidText || oid == oidVarchar:
+		return quote(string(v))
+	case (oid == oidTimestamp || oid == oidTimestampTZ) && len(v) == 8:
+		ts := postgresEpoch.Add(time.Duration(int64(binary.BigEndian.Uint64(v))) * time.Microsecond)
+		return quote(ts.Format("2006-01-02 15:04:05.999999"))
+	case oid == oidUUID && len(v) == 16:
+		h := hex.EncodeToString(v)
+		return quote(h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:])
+	}
+	return quote(`\x` + hex.EncodeToString(v))
+}
+
+var (
+	placeholderRegex = regexp.MustCompile(`^\$\d+`)                         // placeholder of a prepared statement
+	dollarTagRegex   = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`) // opening tag of a dollar-quoted string
+)
+
+// bindDollars replaces the placeholders $1, $2, ... of query by values.
+// Quoted strings and identifiers as well as dollar-quoted strings are copied
+// unchanged.
+func bindDollars(query string, values map[string]string) string {
+	var sb strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end == -1 {
+				sb.WriteString(query[i:])
+				return sb.String()
+			}
+			sb.WriteString(query[i : i+end+2])
+			i += end + 2
+			continue
+		case c == '$' && (i == 0 || !identifier(query[i-1])):
+			if p := placeholderRegex.FindString(query[i:]); p != "" {
+				if v, ok := values[p]; ok {
+					sb.WriteString(v)
+				} else {
+					sb.WriteString(p)
+				}
+				i += len(p)
+				continue
+			}
+			if tag := dollarTagRegex.FindString(query[i:]); tag != "" {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end == -1 {
+					sb.WriteString(query[i:])
+					return sb.String()
+				}
+				sb.WriteString(query[i : i+len(tag)+end+len(tag)])
+				i += len(tag) + end + len(tag)
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String()
+}
+
+// identifier returns true if c may be part of an unquoted identifier, which
+// may contain dollar signs, too.
+func identifier(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// int16 reads a big-endian int16 of the PostgreSQL protocol.
+func (r *reader) int16() (int, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(int16(binary.BigEndian.Uint16(b))), nil
+}
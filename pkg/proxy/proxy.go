@@ -0,0 +1,218 @@
+// Package proxy provides channels that record the statements of a SUT by means
+// of a local TCP proxy between the SUT and its database instead of reading a
+// database log. The proxy decodes the MySQL and PostgreSQL wire protocols,
+// including the parameters of prepared statements, and stamps each statement
+// with the time it was sent and the id of its connection.
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+)
+
+// Wire protocols supported by the proxy.
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+)
+
+// Formats maps the channel formats of proxy channels to their wire protocol.
+var Formats = map[string]string{
+	"mysql-proxy":    MySQL,
+	"postgres-proxy": Postgres,
+}
+
+// maxBuffer limits the number of undecoded bytes per connection and direction.
+// Decoding of a connection stops if a message exceeds this limit.
+const maxBuffer = 64 << 20
+
+// decoder decodes the traffic of a single connection. The proxy passes each
+// chunk of bytes read from the client or server to the decoder before it is
+// forwarded, thus the decoder sees a request before the server can answer it
+// and an answer before the client can act on it.
+type decoder interface {
+	fromClient(b []byte)
+	fromServer(b []byte)
+}
+
+var decoders = map[string]func(emit func(string)) decoder{
+	MySQL:    func(emit func(string)) decoder { return newMySQLDecoder(emit) },
+	Postgres: func(emit func(string)) decoder { return newPostgresDecoder(emit) },
+}
+
+// A Proxy forwards all connections accepted on its listen address to its
+// upstream database and publishes the decoded statements to its subscribers.
+// A proxy keeps running once started because the SUT's connections, e.g. the
+// connections of its pool, must survive the end of a recording or verification
+// session.
+type Proxy struct {
+	protocol string
+	config   df.ProxyConfig
+	listener net.Listener
+	conns    atomic.Int64 // id of the last accepted connection
+	hub      *Hub
+}
+
+var (
+	mu      sync.Mutex
+	proxies = make(map[string]any) // running proxies of any kind by listen address
+)
+
+// Running returns the proxy of type P running on listen, provided check
+// accepts it. Otherwise, start is called with a new listener on listen and the
+// returned proxy is registered until Release is called. Proxies of all kinds,
+// e.g. the http proxy of package rest, share one registry, thus a listen
+// address is only used once.
+func Running[P any](listen string, check func(P) error, start func(net.Listener) P) (P, error) {
+	var zero P
+	mu.Lock()
+	defer mu.Unlock()
+	if r, ok := proxies[listen]; ok {
+		p, ok := r.(P)
+		if !ok {
+			return zero, fmt.Errorf("%s already runs a different kind of proxy", listen)
+		}
+		if err := check(p); err != nil {
+			return zero, err
+		}
+		return p, nil
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return zero, err
+	}
+	p := start(listener)
+	proxies[listen] = p
+	return p, nil
+}
+
+// Release removes the proxy running on listen from the registry.
+func Release(listen string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(proxies, listen)
+}
+
+// Listen returns the running proxy of config.Listen or starts a new one.
+func Listen(protocol string, config df.ProxyConfig) (*Proxy, error) {
+	if _, ok := decoders[protocol]; !ok {
+		return nil, fmt.Errorf("unsupported wire protocol '%s'", protocol)
+	}
+	if config.Listen == "" || config.Upstream == "" {
+		return nil, errors.New("proxy requires listen and upstream address")
+	}
+
+	check := func(p *Proxy) error {
+		if p.protocol != protocol || p.config != config {
+			return fmt.Errorf("%s already proxies %s to %s", config.Listen, p.protocol, p.config.Upstream)
+		}
+		return nil
+	}
+	return Running(config.Listen, check, func(listener net.Listener) *Proxy {
+		p := &Proxy{protocol: protocol, config: config, listener: listener, hub: NewHub(config.Listen)}
+		log.Printf("proxying %s connections from %s to %s", protocol, listener.Addr(), config.Upstream)
+		go p.accept()
+		return p
+	})
+}
+
+// StartAll starts the proxies of all proxy channels. Channels of other formats
+// are ignored.
+func StartAll(channels []df.Channel) error {
+	for _, ch := range channels {
+		protocol, ok := Formats[ch.Format]
+		if !ok {
+			continue
+		}
+		if _, err := Listen(protocol, ch.Proxy); err != nil {
+			return df.ChannelError{Channel: ch.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Addr returns the address the proxy listens on.
+func (p *Proxy) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Close stops accepting new connections. Established connections stay open
+// until closed by the client or server.
+func (p *Proxy) Close() error {
+	Release(p.config.Listen)
+	return p.listener.Close()
+}
+
+func (p *Proxy) accept() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("proxy %s: %v", p.config.Listen, err)
+			}
+			return
+		}
+		go p.handle(conn)
+	}
+}
+
+// handle forwards conn to the upstream database and decodes its traffic.
+func (p *Proxy) handle(client net.Conn) {
+	server, err := net.Dial("tcp", p.config.Upstream)
+	if err != nil {
+		log.Errorf("proxy %s: %v", p.config.Listen, err)
+		_ = client.Close()
+		return
+	}
+	id := p.conns.Add(1)
+	d := decoders[p.protocol](func(statement string) {
+		p.publish(id, statement)
+	})
+
+	closeBoth := func() {
+		_ = client.Close()
+		_ = server.Close()
+	}
+	go func() {
+		defer closeBoth()
+		forward(client, server, d.fromServer)
+	}()
+	defer closeBoth()
+	forward(server, client, d.fromClient)
+}
+
+// forward copies src to dst and passes the bytes to observe before they are
+// copied until src or dst fails.
+func forward(dst net.Conn, src net.Conn, observe func([]byte)) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			observe(buf[:n])
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// publish sends statement as line of the form
+//
+//	<timestamp in RFC 3339, UTC>\t<connection id>\t<statement>
+//
+// to all subscribers.
+func (p *Proxy) publish(conn int64, statement string) {
+	p.hub.Publish(fmt.Sprintf("%s\t%d\t%s\n", time.Now().UTC().Format(time.RFC3339Nano), conn,
+		df.Compact(statement)))
+}
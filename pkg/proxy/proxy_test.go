@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mysqlPacket frames payload as MySQL packet with sequence id seq.
+func mysqlPacket(seq byte, payload ...byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+// postgresMessage frames payload as typed PostgreSQL message.
+func postgresMessage(t byte, payload ...byte) []byte {
+	b := []byte{t, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(len(payload)+4))
+	return append(b, payload...)
+}
+
+func TestMySQLDecoder(t *testing.T) {
+	var statements []string
+	d := newMySQLDecoder(func(s string) { statements = append(statements, s) })
+
+	// handshake response without SSL, split across two reads
+	response := mysqlPacket(1, append([]byte{0x0d, 0xa2, 0x00, 0x00}, make([]byte, 28)...)...)
+	d.fromClient(response[:10])
+	d.fromClient(response[10:])
+
+	d.fromClient(mysqlPacket(0, append([]byte{comQuery}, "select * from job"...)...))
+
+	d.fromClient(mysqlPacket(0, append([]byte{comStmtPrepare}, "insert into job (title, id, tags) values (?, ?, ?)"...)...))
+	d.fromServer(mysqlPacket(1, 0x00, 7, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0))
+
+	execute := []byte{comStmtExecute, 7, 0, 0, 0, 0, 1, 0, 0, 0}
+	execute = append(execute, 0x04, 1)                   // null bitmap: tags, new params bound
+	execute = append(execute, 0xfd, 0, 0x08, 0, 0xfd, 0) // types: varchar, longlong, varchar
+	execute = append(execute, 9)
+	execute = append(execute, "it's done"...)
+	execute = append(execute, 42, 0, 0, 0, 0, 0, 0, 0)
+	d.fromClient(mysqlPacket(0, execute...))
+
+	assert.Equal(t, []string{
+		"select * from job",
+		"insert into job (title, id, tags) values ('it''s done', 42, NULL)",
+	}, statements)
+}
+
+func TestMySQLDecoderQueryAttributes(t *testing.T) {
+	var statements []string
+	d := newMySQLDecoder(func(s string) { statements = append(statements, s) })
+
+	// handshake response with CLIENT_QUERY_ATTRIBUTES
+	d.fromClient(mysqlPacket(1, append([]byte{0x0d, 0xa2, 0x00, 0x08}, make([]byte, 28)...)...))
+
+	// without attributes: parameter count 0, parameter set count 1
+	d.fromClient(mysqlPacket(0, append([]byte{comQuery, 0, 1}, "select * from job"...)...))
+
+	// with attribute trace_id='abc'
+	query := []byte{comQuery, 1, 1}
+	query = append(query, 0x00, 1)       // null bitmap, new params bound
+	query = append(query, 0xfd, 0, 8)    // type varchar, name length
+	query = append(query, "trace_id"...) // name
+	query = append(query, 3)             // value length
+	query = append(query, "abc"...)      // value
+	query = append(query, "delete from job where id=1"...)
+	d.fromClient(mysqlPacket(0, query...))
+
+	assert.Equal(t, []string{"select * from job", "delete from job where id=1"}, statements)
+}
+
+func TestMySQLDecoderSSL(t *testing.T) {
+	var statements []string
+	d := newMySQLDecoder(func(s string) { statements = append(statements, s) })
+	d.fromClient(mysqlPacket(1, append([]byte{0x0d, 0xaa, 0x00, 0x00}, make([]byte, 28)...)...))
+	d.fromClient(mysqlPacket(0, append([]byte{comQuery}, "select 1"...)...))
+	assert.Empty(t, statements)
+}
+
+func TestPostgresDecoder(t *testing.T) {
+	var statements []string
+	d := newPostgresDecoder(func(s string) { statements = append(statements, s) })
+
+	// SSL request denied by the server, followed by the startup message
+	d.fromClient([]byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f})
+	d.fromServer([]byte{'N'})
+	d.fromClient([]byte{0, 0, 0, 8, 0, 3, 0, 0})
+
+	d.fromClient(postgresMessage('Q', append([]byte("select * from job"), 0)...))
+
+	parse := append([]byte("S_1\x00insert into job (title, id) values ($1, $2)\x00"), 0, 2, 0, 0, 0, 25, 0, 0, 0, 23)
+	bind := []byte("\x00S_1\x00")
+	bind = append(bind, 0, 2, 0, 0, 0, 1) // formats: text, binary
+	bind = append(bind, 0, 2)             // parameters
+	bind = append(bind, 0, 0, 0, 9)
+	bind = append(bind, "it's done"...)
+	bind = append(bind, 0, 0, 0, 4, 0, 0, 0, 42)
+	bind = append(bind, 0, 0)
+	messages := append(postgresMessage('P', parse...), postgresMessage('B', bind...)...)
+	messages = append(messages, postgresMessage('E', 0, 0, 0, 0, 0)...)
+	d.fromClient(messages[:7])
+	d.fromClient(messages[7:])
+
+	assert.Equal(t, []string{
+		"select * from job",
+		"insert into job (title, id) values ('it''s done', 42)",
+	}, statements)
+}
+
+// pgx and JDBC parse statements with unspecified parameter types and bind
+// binary parameters of the types described by the server.
+func TestPostgresDecoderDescribedParameters(t *testing.T) {
+	var statements []string
+	d := newPostgresDecoder(func(s string) { statements = append(statements, s) })
+	d.fromClient([]byte{0, 0, 0, 8, 0, 3, 0, 0})
+	d.fromServer(append(postgresMessage('R', 0, 0, 0, 0), postgresMessage('Z', 'I')...))
+
+	// the describe of a statement the server rejects is never answered
+	d.fromClient(postgresMessage('P', append([]byte("S_0\x00selec\x00"), 0, 0)...))
+	d.fromClient(postgresMessage('D', append([]byte("SS_0"), 0)...))
+	d.fromClient(postgresMessage('S'))
+	d.fromServer(append(postgresMessage('E', 0), postgresMessage('Z', 'I')...))
+
+	query := "select * from job where id=$1 and uuid=$2 and title='$1' and body=$b$ $2 $b$ and a$1=1"
+	d.fromClient(postgresMessage('P', append([]byte("S_1\x00"+query+"\x00"), 0, 0)...))
+	d.fromClient(postgresMessage('D', append([]byte("SS_1"), 0)...))
+	d.fromClient(postgresMessage('S'))
+	description := postgresMessage('t', 0, 2, 0, 0, 0, 20, 0, 0, 0x0b, 0x86) // int8, uuid
+	server := append(postgresMessage('1'), description...)
+	server = append(server, postgresMessage('n')...)
+	server = append(server, postgresMessage('Z', 'I')...)
+	d.fromServer(server[:8])
+	d.fromServer(server[8:])
+
+	bind := []byte("P_1\x00S_1\x00")
+	bind = append(bind, 0, 1, 0, 1) // formats: binary
+	bind = append(bind, 0, 2)       // parameters
+	bind = append(bind, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 42)
+	bind = append(bind, 0, 0, 0, 16, 0x02, 0x3a, 0x6a, 0x95, 0x6c, 0x8a, 0x44, 0x83, 0xbc, 0xfd, 0x17, 0xb1, 0xc5, 0x8c, 0x31, 0x7f)
+	bind = append(bind, 0, 0)
+	d.fromClient(postgresMessage('B', bind...))
+
+	// the portal is suspended after the first row and executed again
+	d.fromClient(postgresMessage('E', append([]byte("P_1\x00"), 0, 0, 0, 1)...))
+	row := postgresMessage('D', append([]byte{0, 1, 0, 0, 0, 100}, make([]byte, 100)...)...)
+	d.fromServer(row[:20])
+	d.fromServer(append(row[20:], postgresMessage('s')...))
+	d.fromClient(postgresMessage('E', append([]byte("P_1\x00"), 0, 0, 0, 1)...))
+
+	assert.Equal(t, []string{
+		"select * from job where id=42 and uuid='023a6a95-6c8a-4483-bcfd-17b1c58c317f' and title='$1' and body=$b$ $2 $b$ and a$1=1",
+	}, statements)
+}
+
+func TestBindDollars(t *testing.T) {
+	values := map[string]string{"$1": "42", "$2": "'Go'"}
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"select * from job where id=$1 and title=$2", "select * from job where id=42 and title='Go'"},
+		{"select '$1', \"$2\" from job where id=$1", "select '$1', \"$2\" from job where id=42"},
+		{"select 'it''s $1' where id=$1", "select 'it''s $1' where id=42"},
+		{"do $$ begin perform $1; end $$", "do $$ begin perform $1; end $$"},
+		{"select $fn$ $2 $fn$, $2", "select $fn$ $2 $fn$, 'Go'"},
+		{"select $3, 'open $1", "select $3, 'open $1"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, bindDollars(test.query, values), test.query)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	// upstream echoes everything it receives
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	config := df.ProxyConfig{Listen: "127.0.0.1:0", Upstream: upstream.Addr().String()}
+	l, err := NewLog(Postgres, config)
+	require.NoError(t, err)
+	defer l.proxy.Close()
+	defer l.Close()
+	require.NoError(t, l.Tail())
+
+	_, err = NewLog(MySQL, config)
+	assert.Error(t, err, "listen address is already used by a postgres proxy")
+
+	conn, err := net.Dial("tcp", l.proxy.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	startup := []byte{0, 0, 0, 8, 0, 3, 0, 0}
+	query := postgresMessage('Q', append([]byte("select *\nfrom job"), 0)...)
+	_, err = conn.Write(append(startup, query...))
+	require.NoError(t, err)
+
+	echo := make([]byte, len(startup)+len(query))
+	_, err = io.ReadFull(conn, echo)
+	require.NoError(t, err)
+	assert.Equal(t, append(startup, query...), echo)
+
+	done := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(done) })
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	ts, err := l.Timestamp(line)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Second)
+	assert.Equal(t, []string{"select", "*", "from", "job"}, Tokenizer{}.Tokenize(line, nil))
+}
@@ -1,45 +1,49 @@
 package record
 
 import (
+	"sync"
+
 	"github.com/rwirdemann/datafrog/pkg/df"
 	log "github.com/sirupsen/logrus"
 )
 
-// A Recorder monitors a channel log and records all statements that match one of
-// the patterns specified in the channels pattern list. The recorded output is
-// written back TestRepository.
+// A Recorder monitors the logs of one or more channels and records all
+// statements that match one of the patterns specified in the pattern list of
+// their channel. Each recorded expectation is tagged with the name of its
+// channel. The recorded output is written back TestRepository.
 type Recorder struct {
-	channel        df.Channel
-	tokenizer      df.Tokenizer
-	log            df.Log
+	sources        []df.Source
 	timer          df.Timer
 	testname       string
 	uuidProvider   UUIDProvider
+	mu             sync.Mutex // guards testcase, see Snapshot
 	testcase       df.Testcase
 	testRepository df.TestRepository
+	collapse       bool          // true if repeated statements are recorded as one expectation
+	started        chan struct{} // closed as soon as all logs are tailed
+	err            error         // reason why the recording failed, read after started or stopped is closed
 }
 
 // NewRecorder creates a new Recorder.
-func NewRecorder(channel df.Channel, tokenizer df.Tokenizer,
-	log df.Log, timer df.Timer, testname string,
+func NewRecorder(sources []df.Source, timer df.Timer, testname string,
 	uuidProvider UUIDProvider, repository df.TestRepository) *Recorder {
 
 	return &Recorder{
-		channel:        channel,
-		tokenizer:      tokenizer,
-		log:            log,
+		sources:        sources,
 		timer:          timer,
 		testname:       testname,
 		uuidProvider:   uuidProvider,
-		testcase:       df.Testcase{Name: testname},
+		testcase:       df.Testcase{Name: testname, Change: df.ChangeRecording},
 		testRepository: repository,
+		started:        make(chan struct{}),
 	}
 }
 
-// Start starts the recording process of channel as endless loop. Every log entry
-// that matches one of the patterns specified in the channels pattern list is
-// written to the recording sink. Only log entries that fall in the actual
-// recording period are considered.
+// Start starts the recording process of all channels as endless loop. Every log
+// entry that matches one of the patterns specified in its channels pattern list
+// is written to the recording sink. Only log entries that fall in the actual
+// recording period are considered. The recording fails without writing the
+// testcase if one of the logs fails, see Err.
 func (r *Recorder) Start(done chan struct{}, stopped chan struct{}) {
 	r.timer.Start()
 	log.Printf("Recording started at %v...", r.timer.GetStart())
@@ -47,47 +51,87 @@ func (r *Recorder) Start(done chan struct{}, stopped chan struct{}) {
 	// tell caller that recording has been finished
 	defer close(stopped)
 
-	// called when done channel is closed
-	defer func() {
-		if err := r.testRepository.Write(r.testname, r.testcase); err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	// jump to log file end
-	if err := r.log.Tail(); err != nil {
-		log.Fatal(err)
+	// jump to log file ends
+	if err := df.TailSources(r.sources); err != nil {
+		r.err = err
+		close(r.started)
+		return
 	}
+	close(r.started)
 
+	// the runner closes the logs as soon as stopped is closed
+	lines, stop := df.ReadLines(r.sources, done)
+	defer stop()
 	for {
 		select {
-		default:
-			line, err := r.log.NextLine(done)
-			if err != nil {
-				log.Fatal(err)
+		case line := <-lines:
+			if line.Err != nil {
+				log.Errorf("recorder: %v", line.Err)
+				r.err = line.Err
+				return
 			}
-			ts, err := r.log.Timestamp(line)
+			ts, err := line.Source.Log.Timestamp(line.Text)
 			if err != nil {
 				continue
 			}
 			if r.timer.MatchesRecordingPeriod(ts) {
-				matches, pattern := df.MatchesPattern(r.channel.Patterns, line)
+				matches, pattern := df.MatchesPattern(line.Source.Channel.Patterns, line.Text)
 				if matches {
-					tokens := r.tokenizer.Tokenize(line, r.channel.Patterns)
-					e := df.Expectation{Uuid: r.uuidProvider.NewString(), Tokens: tokens, IgnoreDiffs: []int{}, Pattern: pattern}
-					r.testcase.Expectations = append(r.testcase.Expectations, e)
-					log.Printf("new expectation: %s\n", e.Shorten(8))
+					r.record(line, pattern)
 				}
 			}
 		// check if the caller (web, cli, ...) has closed the done channel to
 		// tell me that recoding has been finished
 		case <-done:
 			log.Println("recorder: done channel closed")
+			r.mu.Lock()
+			r.err = r.testRepository.Write(r.testname, r.testcase)
+			r.mu.Unlock()
 			return
 		}
 	}
 }
 
+// record adds the statement of line as new expectation. If collapsing is
+// enabled, statements that only differ from an already recorded expectation in
+// their literal values increase the count of this expectation instead. The
+// values of a collapsed statement aren't verified, thus collapsing is opt-in.
+func (r *Recorder) record(line df.Line, pattern string) {
+	tokens := line.Tokenize()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.testcase.Expectations {
+		if r.collapse && e.Pattern == pattern && e.Channel == line.Source.Channel.Name && e.NormalizedEqual(tokens) {
+			r.testcase.Expectations[i].Count = max(e.Count, 1) + 1
+			log.Printf("repeated expectation: %s (count: %d)\n", e.Shorten(8), r.testcase.Expectations[i].Count)
+			return
+		}
+	}
+	e := df.Expectation{Uuid: r.uuidProvider.NewString(), Tokens: tokens, IgnoreDiffs: []int{}, Pattern: pattern, Channel: line.Source.Channel.Name}
+	r.testcase.Expectations = append(r.testcase.Expectations, e)
+	log.Printf("new expectation: %s\n", e.Shorten(8))
+}
+
+// Started returns a channel that is closed as soon as the recorder monitors
+// its logs. Statements logged afterward are recorded.
+func (r *Recorder) Started() <-chan struct{} {
+	return r.started
+}
+
+// Err returns the reason why the recording failed or nil. Only valid after the
+// started or stopped channel has been closed.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
 func (r *Recorder) Testcase() df.Testcase {
 	return r.testcase
 }
+
+// Snapshot returns a copy of the testcase recorded so far. Unlike Testcase it
+// may be called while the recording is running.
+func (r *Recorder) Snapshot() df.Testcase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.testcase.Copy()
+}
@@ -1,6 +1,7 @@
 package record
 
 import (
+	"errors"
 	"github.com/rwirdemann/datafrog/pkg/df"
 	"github.com/rwirdemann/datafrog/pkg/mocks"
 	"github.com/rwirdemann/datafrog/pkg/mysql"
@@ -44,6 +45,8 @@ func TestRecord(t *testing.T) {
 		Running:       false,
 		Verifications: 0,
 		Expectations:  []df.Expectation{e1, e2},
+		Revision:      1,
+		Change:        df.ChangeRecording,
 	}
 
 	channel := df.Channel{Patterns: []string{"insert", "select job!publish_trials<1"}}
@@ -52,7 +55,8 @@ func TestRecord(t *testing.T) {
 	databaseLog := mocks.NewMemSQLLog(logs, recordingDone)
 	timer := mocks.Timer{}
 	repository := &mocks.TestRepository{}
-	recorder := NewRecorder(channel, mysql.Tokenizer{}, databaseLog, timer, "create-job", mocks.StaticUUIDProvider{}, repository)
+	sources := []df.Source{{Channel: channel, Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+	recorder := NewRecorder(sources, timer, "create-job", mocks.StaticUUIDProvider{}, repository)
 	go recorder.Start(recordingDone, recordingStopped)
 	<-recordingStopped
 	actual, err := repository.Get("create-job")
@@ -60,3 +64,91 @@ func TestRecord(t *testing.T) {
 	assert.Len(t, actual.Expectations, 2)
 	assert.Equal(t, expectedTestcase, actual)
 }
+
+func TestRecordTagsChannel(t *testing.T) {
+	logs := []string{
+		"2024-04-08T12:50:59.605638Z	 2609 Query	insert into job (description, id) values ('World', 3)",
+		"STOP",
+	}
+	channel := df.Channel{Name: "mysql", Patterns: []string{"insert"}}
+	recordingDone := make(chan struct{})
+	recordingStopped := make(chan struct{})
+	sources := []df.Source{{Channel: channel, Log: mocks.NewMemSQLLog(logs, recordingDone), Tokenizer: mysql.Tokenizer{}}}
+	repository := &mocks.TestRepository{}
+	recorder := NewRecorder(sources, mocks.Timer{}, "create-job", mocks.StaticUUIDProvider{}, repository)
+	go recorder.Start(recordingDone, recordingStopped)
+	<-recordingStopped
+	actual, err := repository.Get("create-job")
+	assert.NoError(t, err)
+	assert.Len(t, actual.Expectations, 1)
+	assert.Equal(t, "mysql", actual.Expectations[0].Channel)
+}
+
+func TestRecordCollapsesRepeatedStatements(t *testing.T) {
+	logs := []string{
+		"2024-04-08T12:50:59.605638Z	 2609 Query	select * from application where job_id=1",
+		"2024-04-08T12:50:59.605638Z	 2609 Query	select * from application where job_id=2",
+		"2024-04-08T12:50:59.605638Z	 2609 Query	select * from application where job_id=3",
+		"2024-04-08T12:50:59.605638Z	 2609 Query	select * from application where job_id is null",
+		"STOP",
+	}
+	channel := df.Channel{Name: "mysql", Patterns: []string{"select"}}
+	recordingDone := make(chan struct{})
+	recordingStopped := make(chan struct{})
+	sources := []df.Source{{Channel: channel, Log: mocks.NewMemSQLLog(logs, recordingDone), Tokenizer: mysql.Tokenizer{}}}
+	repository := &mocks.TestRepository{}
+	recorder := NewRecorder(sources, mocks.Timer{}, "list-jobs", mocks.StaticUUIDProvider{}, repository)
+	recorder.collapse = true
+	go recorder.Start(recordingDone, recordingStopped)
+	<-recordingStopped
+	actual, err := repository.Get("list-jobs")
+	assert.NoError(t, err)
+	assert.Len(t, actual.Expectations, 2)
+	assert.Equal(t, "select * from application where job_id=1", actual.Expectations[0].String())
+	assert.Equal(t, 3, actual.Expectations[0].Count)
+	assert.Equal(t, 0, actual.Expectations[1].Count)
+}
+
+// Statements that only differ in their values are distinct expectations unless
+// collapsing is enabled, thus their values are verified.
+func TestRecordKeepsDistinctStatements(t *testing.T) {
+	logs := []string{
+		"2024-04-08T12:50:59.605638Z	 2609 Query	insert into job (description, id) values ('World', 3)",
+		"2024-04-08T12:50:59.605638Z	 2609 Query	insert into job (description, id) values ('Hello', 4)",
+		"STOP",
+	}
+	channel := df.Channel{Name: "mysql", Patterns: []string{"insert"}}
+	recordingDone := make(chan struct{})
+	recordingStopped := make(chan struct{})
+	sources := []df.Source{{Channel: channel, Log: mocks.NewMemSQLLog(logs, recordingDone), Tokenizer: mysql.Tokenizer{}}}
+	repository := &mocks.TestRepository{}
+	recorder := NewRecorder(sources, mocks.Timer{}, "create-jobs", mocks.StaticUUIDProvider{}, repository)
+	go recorder.Start(recordingDone, recordingStopped)
+	<-recordingStopped
+	actual, err := repository.Get("create-jobs")
+	assert.NoError(t, err)
+	assert.Len(t, actual.Expectations, 2)
+	assert.False(t, actual.Expectations[0].Repeated())
+	assert.False(t, actual.Expectations[1].Repeated())
+}
+
+func TestRecordFailingLog(t *testing.T) {
+	logs := []string{
+		"2024-04-08T12:50:59.605638Z	 2609 Query	insert into job (description, id) values ('World', 3)",
+	}
+	channel := df.Channel{Name: "mysql", Patterns: []string{"insert"}}
+	recordingDone := make(chan struct{})
+	recordingStopped := make(chan struct{})
+	log := mocks.NewMemSQLLog(logs, recordingDone)
+	log.LineError = errors.New("log rotated")
+	sources := []df.Source{{Channel: channel, Log: log, Tokenizer: mysql.Tokenizer{}}}
+	repository := &mocks.TestRepository{}
+	recorder := NewRecorder(sources, mocks.Timer{}, "create-job", mocks.StaticUUIDProvider{}, repository)
+	go recorder.Start(recordingDone, recordingStopped)
+	<-recordingStopped
+
+	var channelErr df.ChannelError
+	assert.True(t, errors.As(recorder.Err(), &channelErr))
+	assert.Equal(t, "mysql", channelErr.Channel)
+	assert.False(t, repository.Exists("create-job"))
+}
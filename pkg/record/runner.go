@@ -1,41 +1,65 @@
 package record
 
 import (
+	"errors"
+
 	"github.com/rwirdemann/datafrog/pkg/df"
-	"github.com/rwirdemann/datafrog/pkg/mysql"
 	log "github.com/sirupsen/logrus"
 )
 
-// Runner runs the recorder for the given channel.
+// Runner runs the recorder for the given channel sources.
 type Runner struct {
 	testname   string
-	channel    df.Channel
+	sources    []df.Source
+	ordering   df.Ordering
+	collapse   bool
 	repository df.TestRepository
-	channelLog df.Log
 	recorder   *Recorder
 	done       chan struct{}
 	stopped    chan struct{}
 }
 
 // NewRunner creates a new runner for recording interactions of the given
-// channel.
-func NewRunner(testname string, channel df.Channel, repository df.TestRepository, logFactory df.LogFactory) *Runner {
-	return &Runner{testname: testname, channel: channel, repository: repository, channelLog: logFactory.Create(channel.Log)}
+// channel sources. All channels are recorded simultaneously into the same
+// testcase. The recorded testcase is verified according to ordering. If
+// collapse is true, repeated statements that only differ in their literal
+// values are recorded as one expectation with their count.
+func NewRunner(testname string, sources []df.Source, ordering df.Ordering, collapse bool, repository df.TestRepository) *Runner {
+	return &Runner{testname: testname, sources: sources, ordering: ordering, collapse: collapse, repository: repository}
 }
 
-// Start starts a new recorder as go routine.
+// Start starts a new recorder as go routine. Returns as soon as the
+// recorder monitors the channel logs or with the error of the recorder if one of
+// the logs couldn't be tailed.
 func (r *Runner) Start() error {
-	r.recorder = NewRecorder(r.channel, mysql.Tokenizer{}, r.channelLog, &df.UTCTimer{}, r.testname, df.GoogleUUIDProvider{}, r.repository)
+	r.recorder = NewRecorder(r.sources, &df.UTCTimer{}, r.testname, df.GoogleUUIDProvider{}, r.repository)
+	r.recorder.testcase.Ordering = r.ordering
+	r.recorder.collapse = r.collapse
 	r.done = make(chan struct{})
 	r.stopped = make(chan struct{})
 	go r.recorder.Start(r.done, r.stopped)
+
+	// make sure that all statements logged after Start returns are considered
+	<-r.recorder.Started()
+	if err := r.recorder.Err(); err != nil {
+		<-r.stopped
+		_ = df.CloseSources(r.sources)
+		return err
+	}
 	return nil
 }
 
+// Discard closes the channel logs of a runner that hasn't been started, e.g.
+// because the test is already being recorded or verified.
+func (r *Runner) Discard() error {
+	return df.CloseSources(r.sources)
+}
+
 // Stop stops the recording by closing the done channel, that is checked by the
-// recorder for its termination. Closes also the channels log file and test
-// writer.
-func (r *Runner) Stop() {
+// recorder for its termination. Closes also the channel log files and test
+// writer. Returns the error of the recorder, e.g. a failed log or write, joined
+// with the errors of closing the log files.
+func (r *Runner) Stop() error {
 	// tell recorder that recording has been finished
 	close(r.done)
 	log.Printf("rrunner: waiting for stopped channel to be closed")
@@ -44,11 +68,17 @@ func (r *Runner) Stop() {
 	<-r.stopped
 	log.Printf("rrunner: stopped channel closed")
 
-	// close log file
-	r.channelLog.Close()
+	// close log files
+	return errors.Join(r.recorder.Err(), df.CloseSources(r.sources))
 }
 
 // Testcase returns the testcase.
 func (r *Runner) Testcase() df.Testcase {
 	return r.recorder.testcase
 }
+
+// Snapshot returns a copy of the testcase recorded so far, see
+// Recorder.Snapshot.
+func (r *Runner) Snapshot() df.Testcase {
+	return r.recorder.Snapshot()
+}
@@ -0,0 +1,164 @@
+// Package regex provides the "regex" channel format. Its log entries are
+// described by the channel configuration, see df.RegexConfig, thus new log
+// sources like MariaDB, SQLite trace output or application logs can be
+// monitored without code change.
+package regex
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+)
+
+// continuationGrace is the time Log waits for continuation lines of an entry
+// before the entry is considered complete.
+const continuationGrace = 100 * time.Millisecond
+
+// Log reads a log whose entries are described by a df.RegexConfig. Each entry
+// is returned as single line of the form
+//
+//	<timestamp in RFC 3339, UTC>\t<session>\t<statement>
+//
+// Lines that neither match the line nor the continuation regex are skipped.
+type Log struct {
+	follower     *df.Follower
+	line         *regexp.Regexp
+	continuation *regexp.Regexp
+	layout       string
+	location     *time.Location
+}
+
+// NewLog opens the log logfileName whose entries are described by config.
+func NewLog(logfileName string, config df.RegexConfig) (Log, error) {
+	l, err := newLog(config)
+	if err != nil {
+		return Log{}, err
+	}
+	l.follower, err = df.NewFollower(logfileName)
+	if err != nil {
+		return Log{}, err
+	}
+	return l, nil
+}
+
+// newLog compiles and validates config.
+func newLog(config df.RegexConfig) (Log, error) {
+	if config.Line == "" || config.TimestampLayout == "" {
+		return Log{}, errors.New("regex format requires line and timestamp_layout")
+	}
+	line, err := regexp.Compile(config.Line)
+	if err != nil {
+		return Log{}, fmt.Errorf("invalid line regex: %w", err)
+	}
+	for _, group := range []string{"ts", "statement"} {
+		if line.SubexpIndex(group) < 0 {
+			return Log{}, fmt.Errorf("line regex lacks named group '%s'", group)
+		}
+	}
+	l := Log{line: line, layout: config.TimestampLayout, location: time.UTC}
+	if config.Continuation != "" {
+		if l.continuation, err = regexp.Compile(config.Continuation); err != nil {
+			return Log{}, fmt.Errorf("invalid continuation regex: %w", err)
+		}
+	}
+	if config.TimeZone != "" {
+		if l.location, err = time.LoadLocation(config.TimeZone); err != nil {
+			return Log{}, fmt.Errorf("invalid time_zone: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// Tail sets the read cursor of the log file to its end.
+func (m Log) Tail() error {
+	log.Printf("tailing %s...", m.follower.Name())
+	return m.follower.Tail()
+}
+
+func (m Log) Close() error {
+	return m.follower.Close()
+}
+
+func (m Log) Timestamp(s string) (time.Time, error) {
+	ts, _, found := strings.Cut(s, "\t")
+	if !found {
+		return time.Time{}, errors.New("string contains no valid Timestamp")
+	}
+	return time.Parse(time.RFC3339Nano, ts)
+}
+
+// NextLine reads the next entry including its continuation lines. Waits until
+// a new entry becomes available. Returns with an empty line and a nil error if
+// the done channel was closed.
+func (m Log) NextLine(done chan struct{}) (string, error) {
+	for {
+		line, err := m.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		match := m.line.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			continue
+		}
+		ts, err := time.ParseInLocation(m.layout, match[m.line.SubexpIndex("ts")], m.location)
+		if err != nil {
+			log.Printf("%s: %v", m.follower.Name(), err)
+			continue
+		}
+		session := ""
+		if i := m.line.SubexpIndex("session"); i >= 0 {
+			session = match[i]
+		}
+		statement := []string{match[m.line.SubexpIndex("statement")]}
+		if m.continuation != nil {
+			continued, err := m.continuationLines(done)
+			if err != nil {
+				return "", err
+			}
+			statement = append(statement, continued...)
+		}
+		return fmt.Sprintf("%s\t%s\t%s\n", ts.UTC().Format(time.RFC3339Nano), session,
+			df.Compact(strings.Join(statement, "\n"))), nil
+	}
+}
+
+// continuationLines reads the continuation lines following an entry. A named
+// group "statement" of the continuation regex selects the part of the line
+// that is appended to the statement. The first line that isn't a continuation
+// line is given back to the follower. Gives up
+// waiting for further lines after continuationGrace.
+func (m Log) continuationLines(done chan struct{}) ([]string, error) {
+	var lines []string
+	for {
+		grace := make(chan struct{})
+		timer := time.AfterFunc(continuationGrace, func() { close(grace) })
+		line, err := m.follower.ReadLine(grace)
+		timer.Stop()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			return lines, nil
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		match := m.continuation.FindStringSubmatch(trimmed)
+		if match == nil || m.line.MatchString(trimmed) {
+			m.follower.Unread(line)
+			return lines, nil
+		}
+		if i := m.continuation.SubexpIndex("statement"); i >= 0 {
+			trimmed = match[i]
+		}
+		lines = append(lines, trimmed)
+		select {
+		case <-done:
+			return lines, nil
+		default:
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package regex
+
+import "github.com/rwirdemann/datafrog/pkg/df"
+
+type LogFactory struct {
+}
+
+func (f LogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewLog(channel.Log, channel.Regex)
+}
+
+// Validate ensures that the regex settings of channel are complete and valid.
+func (f LogFactory) Validate(channel df.Channel) error {
+	_, err := newLog(channel.Regex)
+	return err
+}
@@ -0,0 +1,64 @@
+package regex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mariadb is a MariaDB general log excerpt whose multi-line statements continue
+// on lines indented by a tab.
+const mariadb = `Time		    Id Command	Argument
+240408 14:50:59	    12 Query	insert into job (description, id)
+	values ('Hello  World', 3)
+240408 14:51:00	    12 Query	select * from job
+`
+
+func TestLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "mariadb.log")
+	require.NoError(t, os.WriteFile(name, []byte(mariadb), 0644))
+	l, err := NewLog(name, df.RegexConfig{
+		Line:            `^(?P<ts>\d{6} \d{2}:\d{2}:\d{2})\s+(?P<session>\d+) Query\t(?P<statement>.*)$`,
+		TimestampLayout: "060102 15:04:05",
+		Continuation:    `^\t(?P<statement>.*)$`,
+		TimeZone:        "Europe/Berlin",
+	})
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan struct{})
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-08T12:50:59Z\t12\tinsert into job (description, id) values ('Hello  World', 3)\n", line)
+	ts, err := l.Timestamp(line)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 8, 12, 50, 59, 0, time.UTC), ts)
+	assert.Equal(t, []string{"insert", "into", "job", "(description,", "id)", "values", "(Hello  World,", "3)"},
+		Tokenizer{}.Tokenize(line, []string{"insert"}))
+
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-08T12:51:00Z\t12\tselect * from job\n", line)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		config df.RegexConfig
+		valid  bool
+	}{
+		{df.RegexConfig{Line: `^(?P<ts>\S+) (?P<statement>.*)$`, TimestampLayout: time.RFC3339}, true},
+		{df.RegexConfig{Line: `^(?P<ts>\S+) (?P<statement>.*)$`}, false},
+		{df.RegexConfig{Line: `^(?P<ts>\S+) (.*)$`, TimestampLayout: time.RFC3339}, false},
+		{df.RegexConfig{Line: `^(?P<ts>\S+ (?P<statement>.*)$`, TimestampLayout: time.RFC3339}, false},
+		{df.RegexConfig{Line: `^(?P<ts>\S+) (?P<statement>.*)$`, TimestampLayout: time.RFC3339, TimeZone: "Mars/Olympus"}, false},
+	}
+	for _, test := range tests {
+		err := LogFactory{}.Validate(df.Channel{Regex: test.config})
+		assert.Equal(t, test.valid, err == nil, "%v: %v", test.config, err)
+	}
+}
@@ -0,0 +1,18 @@
+package regex
+
+import (
+	"strings"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Tokenizer tokenizes the statement of entries read by Log.
+type Tokenizer struct {
+}
+
+// Tokenize cuts timestamp and session from s and splits the statement by spaces
+// into single tokens.
+func (t Tokenizer) Tokenize(s string, _ []string) []string {
+	parts := strings.SplitN(s, "\t", 3)
+	return df.Tokenize(strings.TrimSuffix(parts[len(parts)-1], "\n"))
+}
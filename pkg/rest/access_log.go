@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+)
+
+// accessLogTimeLayout is the time layout of the Common Log Format.
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogRegex matches the client, timestamp and request line of an access
+// log entry in Common or Combined Log Format, e.g.
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /jobs?page=2 HTTP/1.1" 200 2326
+var accessLogRegex = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*"`)
+
+// AccessLog reads the calls of an access log in Common or Combined Log Format
+// as written by nginx, Apache httpd and most API gateways. Access logs contain
+// no request bodies. Entries that don't match the format are skipped.
+type AccessLog struct {
+	follower *df.Follower
+}
+
+// NewAccessLog opens the access log logfileName.
+func NewAccessLog(logfileName string) (AccessLog, error) {
+	follower, err := df.NewFollower(logfileName)
+	if err != nil {
+		return AccessLog{}, err
+	}
+	return AccessLog{follower: follower}, nil
+}
+
+// Tail sets the read cursor of the log file to its end.
+func (l AccessLog) Tail() error {
+	log.Printf("tailing %s...", l.follower.Name())
+	return l.follower.Tail()
+}
+
+func (l AccessLog) Close() error {
+	return l.follower.Close()
+}
+
+func (l AccessLog) Timestamp(s string) (time.Time, error) {
+	return timestamp(s)
+}
+
+// NextLine reads the next call from the access log. Waits until a new call
+// becomes available. Returns with an empty line and a nil error if the done
+// channel was closed.
+func (l AccessLog) NextLine(done chan struct{}) (string, error) {
+	for {
+		line, err := l.follower.ReadLine(done)
+		if err != nil || line == "" {
+			return "", err
+		}
+		match := accessLogRegex.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			continue
+		}
+		ts, err := time.Parse(accessLogTimeLayout, match[2])
+		if err != nil {
+			log.Printf("%s: %v", l.follower.Name(), err)
+			continue
+		}
+		return requestLine(ts, match[1], match[3], match[4], nil), nil
+	}
+}
@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// harPollInterval is the time a HARLog waits before it checks its file for
+// changes again.
+const harPollInterval = 250 * time.Millisecond
+
+// har is the subset of a HTTP Archive (HAR 1.2) read by HARLog.
+type har struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	Connection      string `json:"connection"`
+	Request         struct {
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		PostData *struct {
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"postData"`
+	} `json:"request"`
+}
+
+// key identifies e among the entries of a HAR file.
+func (e harEntry) key() string {
+	return e.StartedDateTime + " " + e.Request.Method + " " + e.Request.URL
+}
+
+// HARLog reads the calls of a HTTP Archive as exported by browsers, proxies
+// like mitmproxy or Playwright's recordHar option. HAR files are rewritten as
+// a whole, thus the log rereads the file on each change and returns the entries
+// it hasn't returned yet, ordered by their start time. A missing file is
+// treated as empty archive. The client of a call is the id of its connection.
+type HARLog struct {
+	name     string
+	modified time.Time
+	size     int64
+	seen     map[string]int // number of returned entries by key
+	pending  []string       // lines of new entries not yet returned
+}
+
+// NewHARLog creates a log reading the HAR file name.
+func NewHARLog(name string) (*HARLog, error) {
+	return &HARLog{name: name, seen: make(map[string]int)}, nil
+}
+
+// Tail skips all entries the file contains so far.
+func (l *HARLog) Tail() error {
+	log.Printf("tailing %s...", l.name)
+	l.pending = nil
+	if _, err := l.read(); err != nil {
+		return err
+	}
+	l.pending = nil
+	return nil
+}
+
+func (l *HARLog) Close() error {
+	return nil
+}
+
+func (l *HARLog) Timestamp(s string) (time.Time, error) {
+	return timestamp(s)
+}
+
+// NextLine returns the next new entry of the HAR file. Waits until a new entry
+// becomes available. Returns with an empty line and a nil error if the done
+// channel was closed.
+func (l *HARLog) NextLine(done chan struct{}) (string, error) {
+	for {
+		if len(l.pending) > 0 {
+			line := l.pending[0]
+			l.pending = l.pending[1:]
+			return line, nil
+		}
+		changed, err := l.read()
+		if err != nil {
+			return "", err
+		}
+		if changed {
+			continue
+		}
+		select {
+		case <-done:
+			return "", nil
+		case <-time.After(harPollInterval):
+		}
+	}
+}
+
+// read rereads the file if it has changed since the last read and appends its
+// new entries to pending. Returns true if the file has changed. An incomplete
+// or invalid file is skipped until it changes again.
+func (l *HARLog) read() (bool, error) {
+	info, err := os.Stat(l.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(l.modified) && info.Size() == l.size {
+		return false, nil
+	}
+	l.modified, l.size = info.ModTime(), info.Size()
+
+	b, err := os.ReadFile(l.name)
+	if err != nil {
+		return false, err
+	}
+	var archive har
+	if err := json.Unmarshal(b, &archive); err != nil {
+		log.Printf("%s: invalid HAR file: %v", l.name, err)
+		return true, nil
+	}
+
+	var calls []harCall
+	occurrences := make(map[string]int)
+	for _, e := range archive.Log.Entries {
+		k := e.key()
+		occurrences[k]++
+		if occurrences[k] <= l.seen[k] {
+			continue // -> already returned
+		}
+		l.seen[k]++
+		c, err := e.line()
+		if err != nil {
+			log.Printf("%s: %v", l.name, err)
+			continue
+		}
+		calls = append(calls, c)
+	}
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].ts.Before(calls[j].ts) })
+	for _, c := range calls {
+		l.pending = append(l.pending, c.line)
+	}
+	return true, nil
+}
+
+// harCall is a call read from a HAR file.
+type harCall struct {
+	ts   time.Time
+	line string
+}
+
+// line renders e as line, see requestLine.
+func (e harEntry) line() (harCall, error) {
+	ts, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+	if err != nil {
+		return harCall{}, fmt.Errorf("invalid startedDateTime '%s'", e.StartedDateTime)
+	}
+	var body []byte
+	if d := e.Request.PostData; d != nil {
+		body = []byte(d.Text)
+		if d.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(d.Text); err != nil {
+				return harCall{}, fmt.Errorf("invalid postData: %w", err)
+			}
+		}
+	}
+	return harCall{ts: ts, line: requestLine(ts, e.Connection, e.Request.Method, e.Request.URL, body)}, nil
+}
@@ -0,0 +1,31 @@
+package rest
+
+import "github.com/rwirdemann/datafrog/pkg/df"
+
+// ProxyLogFactory creates logs subscribing to the http proxy of a channel.
+type ProxyLogFactory struct {
+}
+
+func (f ProxyLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewProxyLog(channel.Proxy)
+}
+
+// Validate ensures that listen and upstream address of channel are set.
+func (f ProxyLogFactory) Validate(channel df.Channel) error {
+	_, err := upstreamURL(channel.Proxy)
+	return err
+}
+
+type HARLogFactory struct {
+}
+
+func (f HARLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewHARLog(channel.Log)
+}
+
+type AccessLogFactory struct {
+}
+
+func (f AccessLogFactory) Create(channel df.Channel) (df.Log, error) {
+	return NewAccessLog(channel.Log)
+}
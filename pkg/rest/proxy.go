@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/proxy"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBody limits the size of the request bodies a Proxy buffers in order to
+// publish them.
+var maxBody int64 = 64 << 20
+
+// A Proxy is a reverse proxy that forwards all requests received on its listen
+// address to its upstream service and publishes each request to its
+// subscribers. Like the wire-protocol proxies, a proxy keeps running once
+// started and shares their registry of listen addresses, see proxy.Running.
+type Proxy struct {
+	config   df.ProxyConfig
+	listener net.Listener
+	upstream *httputil.ReverseProxy
+	hub      *proxy.Hub
+}
+
+// Listen returns the running proxy of config.Listen or starts a new one.
+func Listen(config df.ProxyConfig) (*Proxy, error) {
+	target, err := upstreamURL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	check := func(p *Proxy) error {
+		if p.config != config {
+			return fmt.Errorf("%s already proxies to %s", config.Listen, p.config.Upstream)
+		}
+		return nil
+	}
+	return proxy.Running(config.Listen, check, func(listener net.Listener) *Proxy {
+		p := &Proxy{
+			config:   config,
+			listener: listener,
+			upstream: httputil.NewSingleHostReverseProxy(target),
+			hub:      proxy.NewHub(config.Listen),
+		}
+		log.Printf("proxying http requests from %s to %s", listener.Addr(), target)
+		go func() {
+			if err := http.Serve(listener, p); err != nil && !errors.Is(err, net.ErrClosed) {
+				log.Errorf("proxy %s: %v", config.Listen, err)
+			}
+		}()
+		return p
+	})
+}
+
+// upstreamURL parses the upstream of config. An upstream without scheme, e.g.
+// "localhost:9090", is called via http.
+func upstreamURL(config df.ProxyConfig) (*url.URL, error) {
+	if config.Listen == "" || config.Upstream == "" {
+		return nil, errors.New("proxy requires listen and upstream address")
+	}
+	upstream := config.Upstream
+	if !strings.Contains(upstream, "://") {
+		upstream = "http://" + upstream
+	}
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream '%s'", config.Upstream)
+	}
+	return u, nil
+}
+
+// StartAll starts the proxies of all channels with format ProxyFormat.
+// Channels of other formats are ignored.
+func StartAll(channels []df.Channel) error {
+	for _, ch := range channels {
+		if ch.Format != ProxyFormat {
+			continue
+		}
+		if _, err := Listen(ch.Proxy); err != nil {
+			return df.ChannelError{Channel: ch.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Addr returns the address the proxy listens on.
+func (p *Proxy) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// Close stops accepting new connections.
+func (p *Proxy) Close() error {
+	proxy.Release(p.config.Listen)
+	return p.listener.Close()
+}
+
+// ServeHTTP publishes r and forwards it to the upstream service. Requests with
+// a body larger than maxBody are rejected.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	p.hub.Publish(requestLine(time.Now(), r.RemoteAddr, r.Method, r.URL.RequestURI(), body))
+	p.upstream.ServeHTTP(w, r)
+}
+
+// ProxyLog subscribes to the requests of a Proxy. The client of a request is
+// its remote address.
+type ProxyLog struct {
+	proxy.Subscription
+	proxy *Proxy
+}
+
+// NewProxyLog subscribes to the proxy described by config and starts the proxy
+// if it isn't running yet.
+func NewProxyLog(config df.ProxyConfig) (ProxyLog, error) {
+	p, err := Listen(config)
+	if err != nil {
+		return ProxyLog{}, err
+	}
+	return ProxyLog{Subscription: p.hub.Subscribe(), proxy: p}, nil
+}
+
+func (l ProxyLog) Timestamp(s string) (time.Time, error) {
+	return timestamp(s)
+}
@@ -0,0 +1,205 @@
+// Package rest provides channels that record the outgoing REST calls of a SUT
+// instead of its database statements. Calls are recorded by a local reverse
+// proxy between the SUT and the called service or read from a HAR file or an
+// access log. Each call is rendered as line of the form
+//
+//	<timestamp in RFC 3339, UTC>\t<client>\t<method> <path>?<query> <body>
+//
+// The query is sorted by parameter name and JSON bodies are rendered
+// canonically, i.e. compact with sorted keys, thus calls only differ in their
+// values. Tokenizer splits a call into method, path segments, query parameters
+// and JSON fields, so allowed differences are learned per path segment and
+// field.
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+)
+
+// Channel formats of the rest package.
+const (
+	ProxyFormat     = "http-proxy"
+	HARFormat       = "http-har"
+	AccessLogFormat = "http-access-log"
+)
+
+// requestLine renders a call as line, see package documentation. target is
+// the request target or the full URL of the call, its host is dropped.
+func requestLine(ts time.Time, client, method, target string, body []byte) string {
+	request := strings.ToUpper(method) + " " + normalizeTarget(target)
+	if b := normalizeBody(body); b != "" {
+		request += " " + b
+	}
+	return fmt.Sprintf("%s\t%s\t%s\n", ts.UTC().Format(time.RFC3339Nano), client, request)
+}
+
+// normalizeTarget returns path and query of target, the query parameters sorted
+// by name.
+func normalizeTarget(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return strings.Join(strings.Fields(target), "%20")
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if q := u.Query().Encode(); q != "" {
+		return path + "?" + q
+	}
+	return path
+}
+
+// normalizeBody renders JSON bodies compact with sorted keys. Other text
+// bodies are returned with normalized whitespace, binary bodies by their size.
+func normalizeBody(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	if v, err := decodeJSON(string(body)); err == nil {
+		var b bytes.Buffer
+		e := json.NewEncoder(&b)
+		e.SetEscapeHTML(false)
+		if err := e.Encode(v); err == nil {
+			return strings.TrimSuffix(b.String(), "\n")
+		}
+	}
+	if !utf8.Valid(body) {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+	return strings.Join(strings.Fields(string(body)), " ")
+}
+
+// decodeJSON decodes s keeping numbers as written.
+func decodeJSON(s string) (any, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// timestamp returns the timestamp of a line rendered by requestLine.
+func timestamp(s string) (time.Time, error) {
+	ts, _, found := strings.Cut(s, "\t")
+	if !found {
+		return time.Time{}, errors.New("string contains no valid Timestamp")
+	}
+	return time.Parse(time.RFC3339Nano, ts)
+}
+
+// Tokenizer tokenizes the calls read by the logs of the rest package.
+type Tokenizer struct {
+}
+
+// Tokenize cuts timestamp and client from s and splits the call into method,
+// path segments, query parameters and body. Example:
+//
+//	POST /jobs/42?page=2&q=Java+Dev {"owner":{"id":7},"tags":["go"]}
+//
+// becomes "POST", "jobs", "42", "?page=2", "&q=Java Dev", "owner.id=7" and
+// "tags[0]='go'". Path segments are split without their slashes, thus a segment
+// is a plain value like the value of a statement. The root path becomes "/".
+// Bodies that aren't JSON are split by spaces.
+func (t Tokenizer) Tokenize(s string, _ []string) []string {
+	parts := strings.SplitN(strings.TrimSuffix(s, "\n"), "\t", 3)
+	method, rest, _ := strings.Cut(parts[len(parts)-1], " ")
+	target, body, _ := strings.Cut(rest, " ")
+
+	tokens := []string{method}
+	path, query, _ := strings.Cut(target, "?")
+	if path == "/" {
+		tokens = append(tokens, path)
+	} else {
+		tokens = append(tokens, strings.Split(strings.TrimPrefix(path, "/"), "/")...)
+	}
+	if query != "" {
+		for i, p := range strings.Split(query, "&") {
+			separator := "&"
+			if i == 0 {
+				separator = "?"
+			}
+			tokens = append(tokens, separator+unescape(p))
+		}
+	}
+	if body == "" {
+		return tokens
+	}
+	if v, err := decodeJSON(body); err == nil {
+		return flatten(tokens, "", v)
+	}
+	return append(tokens, df.Tokenize(body)...)
+}
+
+// unescape decodes name and value of the query parameter p.
+func unescape(p string) string {
+	name, value, found := strings.Cut(p, "=")
+	if n, err := url.QueryUnescape(name); err == nil {
+		name = n
+	}
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+	if !found {
+		return name
+	}
+	return name + "=" + value
+}
+
+// flatten appends a token "<path>=<value>" for each scalar of the JSON value v
+// to tokens. Nested fields are joined by dots, array elements are indexed.
+// Strings are quoted like SQL literals, thus their shape is learned like the
+// shape of a string column.
+func flatten(tokens []string, path string, v any) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			return append(tokens, path+"={}")
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			tokens = flatten(tokens, p, v[k])
+		}
+		return tokens
+	case []any:
+		if len(v) == 0 {
+			return append(tokens, path+"=[]")
+		}
+		for i, e := range v {
+			tokens = flatten(tokens, fmt.Sprintf("%s[%d]", path, i), e)
+		}
+		return tokens
+	case string:
+		return append(tokens, path+"='"+strings.ReplaceAll(v, "'", "''")+"'")
+	case json.Number:
+		return append(tokens, path+"="+v.String())
+	case bool:
+		return append(tokens, path+"="+strconv.FormatBool(v))
+	}
+	return append(tokens, path+"=null")
+}
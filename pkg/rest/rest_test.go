@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLine(t *testing.T) {
+	ts := time.Date(2024, 4, 17, 15, 55, 56, 0, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		desc   string
+		method string
+		target string
+		body   string
+		line   string
+	}{
+		{desc: "sorted query", method: "get", target: "/jobs?q=Java%20Dev&page=2",
+			line: "2024-04-17T13:55:56Z\tclient\tGET /jobs?page=2&q=Java+Dev\n"},
+		{desc: "full url", method: "DELETE", target: "https://api.example.com/jobs/42",
+			line: "2024-04-17T13:55:56Z\tclient\tDELETE /jobs/42\n"},
+		{desc: "canonical json", method: "POST", target: "/jobs", body: "{\n  \"title\": \"<Go>\",\n  \"id\": 1.50\n}",
+			line: "2024-04-17T13:55:56Z\tclient\tPOST /jobs {\"id\":1.50,\"title\":\"<Go>\"}\n"},
+		{desc: "text", method: "POST", target: "/jobs", body: "title=Go\n\tDev",
+			line: "2024-04-17T13:55:56Z\tclient\tPOST /jobs title=Go Dev\n"},
+		{desc: "binary", method: "PUT", target: "/logo", body: "\xff\xd8\xff",
+			line: "2024-04-17T13:55:56Z\tclient\tPUT /logo <3 bytes>\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.line, requestLine(ts, "client", test.method, test.target, []byte(test.body)))
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		desc   string
+		line   string
+		tokens []string
+	}{
+		{desc: "root", line: "2024-04-17T13:55:56Z\tclient\tGET /\n", tokens: []string{"GET", "/"}},
+		{desc: "path and query", line: "2024-04-17T13:55:56Z\tclient\tGET /jobs/42?page=2&q=Java+Dev\n",
+			tokens: []string{"GET", "jobs", "42", "?page=2", "&q=Java Dev"}},
+		{desc: "json body", line: "2024-04-17T13:55:56Z\tclient\tPOST /jobs {\"owner\":{\"id\":7},\"tags\":[\"go\",\"it's\"],\"draft\":false,\"meta\":{},\"salary\":null}\n",
+			tokens: []string{"POST", "jobs", "draft=false", "meta={}", "owner.id=7", "salary=null", "tags[0]='go'", "tags[1]='it''s'"}},
+		{desc: "text body", line: "2024-04-17T13:55:56Z\tclient\tPOST /jobs title=Go Dev\n",
+			tokens: []string{"POST", "jobs", "title=Go", "Dev"}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.tokens, Tokenizer{}.Tokenize(test.line, nil))
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = r.Method + " " + r.URL.RequestURI() + " " + string(b)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	l, err := NewProxyLog(df.ProxyConfig{Listen: "127.0.0.1:0", Upstream: upstream.URL})
+	require.NoError(t, err)
+	defer l.proxy.Close()
+	defer l.Close()
+	require.NoError(t, l.Tail())
+
+	res, err := http.Post("http://"+l.proxy.Addr().String()+"/jobs?b=2&a=1", "application/json",
+		strings.NewReader(`{"title": "Go", "id": 42}`))
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, `POST /jobs?b=2&a=1 {"title": "Go", "id": 42}`, received)
+
+	done := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(done) })
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	ts, err := l.Timestamp(line)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Second)
+	assert.Equal(t, []string{"POST", "jobs", "?a=1", "&b=2", "id=42", "title='Go'"}, Tokenizer{}.Tokenize(line, nil))
+}
+
+func TestProxyRejectsLargeBodies(t *testing.T) {
+	defer func(n int64) { maxBody = n }(maxBody)
+	maxBody = 4
+	p := &Proxy{hub: proxy.NewHub("test")}
+	s := p.hub.Subscribe()
+	defer s.Close()
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"id": 42}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	done := make(chan struct{})
+	close(done)
+	line, err := s.NextLine(done)
+	assert.NoError(t, err)
+	assert.Empty(t, line)
+}
+
+func TestProxySharesListenAddresses(t *testing.T) {
+	config := df.ProxyConfig{Listen: "127.0.0.1:0", Upstream: "localhost:3306"}
+	p, err := proxy.Listen(proxy.MySQL, config)
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, err = Listen(config)
+	assert.EqualError(t, err, "127.0.0.1:0 already runs a different kind of proxy")
+}
+
+// Path segments are plain values, thus their shape is learned like the shape
+// of a column value.
+func TestTokenizeLearnsPathSegments(t *testing.T) {
+	e := df.Expectation{Tokens: Tokenizer{}.Tokenize("2024-04-17T13:55:56Z\tclient\tGET /jobs/023a6a95-6c8a-4483-bcfb-17b1c58c317f\n", nil)}
+	actual := Tokenizer{}.Tokenize("2024-04-17T13:55:57Z\tclient\tGET /jobs/8f6c2d4e-1b7a-4c3e-9f0d-2a5b6c7d8e9f\n", nil)
+	diff, err := e.Diff(actual)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, diff)
+	assert.Equal(t, []df.IgnoreRule{{Index: 2, Shape: df.ShapeUUID}}, e.InferRules(actual, diff))
+}
+
+func TestHARLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "calls.har")
+	entry := func(ts, method, url, body string) string {
+		e := `{"startedDateTime": "` + ts + `", "connection": "7", "request": {"method": "` + method + `", "url": "` + url + `"`
+		if body != "" {
+			e += `, "postData": {"mimeType": "application/json", "text": ` + body + `}`
+		}
+		return e + `}}`
+	}
+	write := func(entries ...string) {
+		require.NoError(t, os.WriteFile(name, []byte(`{"log": {"entries": [`+strings.Join(entries, ",")+`]}}`), 0644))
+	}
+
+	l, err := NewHARLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Tail()) // missing file
+
+	first := entry("2024-04-17T15:55:56.100+02:00", "GET", "https://api.example.com/jobs?page=1", "")
+	write(first)
+	done := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(done) })
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-17T13:55:56.1Z\t7\tGET /jobs?page=1\n", line)
+
+	// the rewritten file contains the first entry again, it isn't returned twice
+	time.Sleep(10 * time.Millisecond)
+	write(first,
+		entry("2024-04-17T15:55:58+02:00", "DELETE", "https://api.example.com/jobs/42", ""),
+		entry("2024-04-17T15:55:57+02:00", "POST", "https://api.example.com/jobs", `"{\"title\":\"Go\"}"`))
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-17T13:55:57Z\t7\tPOST /jobs {\"title\":\"Go\"}\n", line)
+	line, err = l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-17T13:55:58Z\t7\tDELETE /jobs/42\n", line)
+}
+
+func TestAccessLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(name, nil, 0644))
+	l, err := NewAccessLog(name)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Tail())
+
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("no access log entry\n" +
+		`127.0.0.1 - frank [17/Apr/2024:15:55:56 +0200] "GET /jobs?q=Go&page=2 HTTP/1.1" 200 2326 "-" "curl/8.4.0"` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	done := make(chan struct{})
+	time.AfterFunc(2*time.Second, func() { close(done) })
+	line, err := l.NextLine(done)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-17T13:55:56Z\t127.0.0.1\tGET /jobs?page=2&q=Go\n", line)
+}
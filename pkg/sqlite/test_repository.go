@@ -0,0 +1,349 @@
+// Package sqlite provides a TestRepository on an embedded SQLite database. A
+// test is stored as row of the table testcase, its expectations and runs as
+// rows of the tables expectation and run. Besides the columns used for
+// querying, each row keeps the JSON encoding of its entity, thus new fields
+// don't require schema changes. The table revision keeps the JSON encoding of
+// each written revision of a test.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables of the repository unless they exist.
+const schema = `
+create table if not exists testcase (
+	name           text primary key,
+	running        integer not null,
+	verifications  integer not null,
+	last_execution text not null,
+	data           text not null
+);
+create table if not exists expectation (
+	testcase text not null,
+	position integer not null,
+	uuid     text not null,
+	channel  text not null,
+	pattern  text not null,
+	data     text not null,
+	primary key (testcase, position)
+);
+create table if not exists run (
+	testcase text not null,
+	position integer not null,
+	start    text not null,
+	passed   integer not null,
+	data     text not null,
+	primary key (testcase, position)
+);
+create table if not exists revision (
+	testcase text not null,
+	number   integer not null,
+	written  text not null,
+	change   text not null,
+	data     text not null,
+	primary key (testcase, number)
+);
+create index if not exists expectation_uuid on expectation (uuid);
+`
+
+// SQLiteTestRepository stores tests in a SQLite database.
+type SQLiteTestRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTestRepository opens the database filename and creates its tables
+// if necessary.
+func NewSQLiteTestRepository(filename string) (SQLiteTestRepository, error) {
+	db, err := sql.Open("sqlite", filename+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return SQLiteTestRepository{}, fmt.Errorf("NewSQLiteTestRepository failed: %w", err)
+	}
+	// a single connection serializes the writes of concurrent runs
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return SQLiteTestRepository{}, fmt.Errorf("NewSQLiteTestRepository failed: %w", err)
+	}
+	log.Printf("using test database '%s'", filename)
+	return SQLiteTestRepository{db: db}, nil
+}
+
+// Close closes the database.
+func (r SQLiteTestRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r SQLiteTestRepository) All() ([]df.Testcase, error) {
+	rows, err := r.db.Query("select name from testcase order by name")
+	if err != nil {
+		return nil, fmt.Errorf("SQLiteTestRepository.All failed: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("SQLiteTestRepository.All failed: %w", err)
+		}
+		names = append(names, name)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SQLiteTestRepository.All failed: %w", err)
+	}
+
+	var all []df.Testcase
+	for _, name := range names {
+		tc, err := r.Get(name)
+		if err != nil {
+			log.Errorf("SQLiteTestRepository.Get failed: %v", err)
+			continue
+		}
+		all = append(all, tc)
+	}
+	return all, nil
+}
+
+// Get reads the test and its expectations and runs within a single read-only
+// transaction, thus a concurrent write is never seen partially.
+func (r SQLiteTestRepository) Get(testname string) (df.Testcase, error) {
+	testname = strings.TrimSuffix(testname, ".json")
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.Get failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	var data string
+	err = tx.QueryRow("select data from testcase where name = ?", testname).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return df.Testcase{}, fmt.Errorf("%w: %s", df.ErrTestNotFound, testname)
+	}
+	if err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.Get failed: %w", err)
+	}
+	var tc df.Testcase
+	if err := json.Unmarshal([]byte(data), &tc); err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.Get failed: %w", err)
+	}
+
+	if err := scan(tx, "select data from expectation where testcase = ? order by position", testname, func(data []byte) error {
+		var e df.Expectation
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		tc.Expectations = append(tc.Expectations, e)
+		return nil
+	}); err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.Get failed: %w", err)
+	}
+	if err := scan(tx, "select data from run where testcase = ? order by position", testname, func(data []byte) error {
+		var run df.Run
+		if err := json.Unmarshal(data, &run); err != nil {
+			return err
+		}
+		tc.Runs = append(tc.Runs, run)
+		return nil
+	}); err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.Get failed: %w", err)
+	}
+	return tc, nil
+}
+
+// scan passes the data column of each row selected by query within tx to f.
+func scan(tx *sql.Tx, query string, testname string, f func(data []byte) error) error {
+	rows, err := tx.Query(query, testname)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := f(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r SQLiteTestRepository) Exists(testname string) bool {
+	var n int
+	err := r.db.QueryRow("select count(*) from testcase where name = ?", strings.TrimSuffix(testname, ".json")).Scan(&n)
+	return err == nil && n > 0
+}
+
+// Write replaces the stored test testname by testcase within a single
+// transaction, see df.TestRepository.
+func (r SQLiteTestRepository) Write(testname string, testcase df.Testcase) error {
+	revision, err := r.write(testname, testcase)
+	if err != nil {
+		return fmt.Errorf("SQLiteTestRepository.Write failed: %w", err)
+	}
+	log.Printf("successfully wrote %s (revision %d)\n", testname, revision)
+	return nil
+}
+
+// write stores testcase and returns its new revision.
+func (r SQLiteTestRepository) write(testname string, testcase df.Testcase) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var stored int
+	err = tx.QueryRow("select coalesce(json_extract(data, '$.revision'), 0) from testcase where name = ?", testname).Scan(&stored)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	exists := err == nil
+	if err := df.CheckRevision(testcase, stored, exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		// drop stale revisions of a test that has been deleted outside the repository
+		if _, err := tx.Exec("delete from revision where testcase = ?", testname); err != nil {
+			return 0, err
+		}
+	}
+
+	testcase.Revision++
+	metadata := testcase
+	metadata.Expectations, metadata.Runs = nil, nil
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, err
+	}
+	if err := deleteTest(tx, testname); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("insert into testcase (name, running, verifications, last_execution, data) values (?, ?, ?, ?, ?)",
+		testname, testcase.Running, testcase.Verifications, testcase.LastExecution.UTC().Format(time.RFC3339Nano), string(data)); err != nil {
+		return 0, err
+	}
+	for i, e := range testcase.Expectations {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("insert into expectation (testcase, position, uuid, channel, pattern, data) values (?, ?, ?, ?, ?, ?)",
+			testname, i, e.Uuid, e.Channel, e.Pattern, string(data)); err != nil {
+			return 0, err
+		}
+	}
+	for i, run := range testcase.Runs {
+		data, err := json.Marshal(run)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("insert into run (testcase, position, start, passed, data) values (?, ?, ?, ?, ?)",
+			testname, i, run.Start.UTC().Format(time.RFC3339Nano), run.Passed, string(data)); err != nil {
+			return 0, err
+		}
+	}
+	if err := writeRevision(tx, testname, testcase); err != nil {
+		return 0, err
+	}
+	return testcase.Revision, tx.Commit()
+}
+
+// writeRevision keeps testcase as revision of testname and drops the revisions
+// that exceed df.MaxRevisions.
+func writeRevision(tx *sql.Tx, testname string, testcase df.Testcase) error {
+	data, err := json.Marshal(testcase)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("insert or replace into revision (testcase, number, written, change, data) values (?, ?, ?, ?, ?)",
+		testname, testcase.Revision, time.Now().UTC().Format(time.RFC3339Nano), testcase.Change, string(data)); err != nil {
+		return err
+	}
+	_, err = tx.Exec("delete from revision where testcase = ? and number <= ?", testname, testcase.Revision-df.MaxRevisions)
+	return err
+}
+
+func (r SQLiteTestRepository) Revisions(testname string) ([]df.Revision, error) {
+	testname = strings.TrimSuffix(testname, ".json")
+	if !r.Exists(testname) {
+		return nil, fmt.Errorf("%w: %s", df.ErrTestNotFound, testname)
+	}
+	rows, err := r.db.Query("select number, written, change, coalesce(json_array_length(data, '$.expectation'), 0) from revision where testcase = ? order by number", testname)
+	if err != nil {
+		return nil, fmt.Errorf("SQLiteTestRepository.Revisions failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var revisions []df.Revision
+	for rows.Next() {
+		var rev df.Revision
+		var written string
+		if err := rows.Scan(&rev.Number, &written, &rev.Change, &rev.Expectations); err != nil {
+			return nil, fmt.Errorf("SQLiteTestRepository.Revisions failed: %w", err)
+		}
+		if rev.Written, err = time.Parse(time.RFC3339Nano, written); err != nil {
+			return nil, fmt.Errorf("SQLiteTestRepository.Revisions failed: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SQLiteTestRepository.Revisions failed: %w", err)
+	}
+	return revisions, nil
+}
+
+func (r SQLiteTestRepository) GetRevision(testname string, revision int) (df.Testcase, error) {
+	testname = strings.TrimSuffix(testname, ".json")
+	var data string
+	err := r.db.QueryRow("select data from revision where testcase = ? and number = ?", testname, revision).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return df.Testcase{}, fmt.Errorf("%w: %s revision %d", df.ErrRevisionNotFound, testname, revision)
+	}
+	if err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.GetRevision failed: %w", err)
+	}
+	var tc df.Testcase
+	if err := json.Unmarshal([]byte(data), &tc); err != nil {
+		return df.Testcase{}, fmt.Errorf("SQLiteTestRepository.GetRevision failed: %w", err)
+	}
+	return tc, nil
+}
+
+func (r SQLiteTestRepository) Delete(testname string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("SQLiteTestRepository.Delete failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := deleteTest(tx, testname); err != nil {
+		return fmt.Errorf("SQLiteTestRepository.Delete failed: %w", err)
+	}
+	if _, err := tx.Exec("delete from revision where testcase = ?", testname); err != nil {
+		return fmt.Errorf("SQLiteTestRepository.Delete failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// deleteTest deletes the test testname including its expectations and runs.
+func deleteTest(tx *sql.Tx, testname string) error {
+	for _, table := range []string{"testcase", "expectation", "run"} {
+		column := "testcase"
+		if table == "testcase" {
+			column = "name"
+		}
+		if _, err := tx.Exec(fmt.Sprintf("delete from %s where %s = ?", table, column), testname); err != nil {
+			return err
+		}
+	}
+	return nil
+}
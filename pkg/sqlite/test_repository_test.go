@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteTestRepository(t *testing.T) {
+	r, err := NewSQLiteTestRepository(filepath.Join(t.TempDir(), "datafrog.db"))
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, err = r.Get("create-job")
+	assert.ErrorIs(t, err, df.ErrTestNotFound)
+	assert.False(t, r.Exists("create-job"))
+
+	tc := df.Testcase{
+		Name:          "create-job",
+		Verifications: 2,
+		LastExecution: time.Date(2024, 4, 17, 13, 55, 56, 0, time.UTC),
+		Ordering:      df.OrderingStrict,
+		Expectations: []df.Expectation{
+			{Uuid: "1", Tokens: []string{"insert", "into", "job"}, Pattern: "insert", Channel: "mysql", IgnoreDiffs: []int{}},
+			{Uuid: "2", Tokens: []string{"update", "job", "id=3"}, Pattern: "update", IgnoreDiffs: []int{2},
+				IgnoreRules: []df.IgnoreRule{{Index: 2, Shape: df.ShapeInteger}}},
+		},
+		Forbidden: []df.Forbidden{{Pattern: "delete"}},
+		Runs:      []df.Run{{Start: time.Date(2024, 4, 17, 13, 55, 56, 0, time.UTC), Passed: true, Fulfilled: []string{"1", "2"}}},
+	}
+	require.NoError(t, r.Write(tc.Name, tc))
+	assert.True(t, r.Exists("create-job"))
+	actual, err := r.Get("create-job")
+	require.NoError(t, err)
+	tc.Revision = 1
+	assert.Equal(t, tc, actual)
+
+	// a writer of an outdated revision gets a conflict
+	outdated := tc
+	outdated.Revision = 0
+	assert.ErrorIs(t, r.Write(tc.Name, outdated), df.ErrConflict)
+
+	// writing replaces expectations and runs
+	tc.Expectations = tc.Expectations[:1]
+	tc.Runs = nil
+	require.NoError(t, r.Write(tc.Name, tc))
+	require.NoError(t, r.Write("delete-job", df.Testcase{Name: "delete-job"}))
+	all, err := r.All()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	tc.Revision = 2
+	assert.Equal(t, tc, all[0])
+	assert.Equal(t, "delete-job", all[1].Name)
+
+	require.NoError(t, r.Delete("create-job"))
+	assert.False(t, r.Exists("create-job"))
+	all, err = r.All()
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestSQLiteTestRepositoryRevisions(t *testing.T) {
+	r, err := NewSQLiteTestRepository(filepath.Join(t.TempDir(), "datafrog.db"))
+	require.NoError(t, err)
+	defer r.Close()
+
+	tc := df.Testcase{Name: "create-job", Change: df.ChangeRecording,
+		Expectations: []df.Expectation{{Uuid: "1", Tokens: []string{"insert"}, Pattern: "insert", IgnoreDiffs: []int{}}}}
+	require.NoError(t, r.Write(tc.Name, tc))
+	tc.Revision, tc.Change, tc.Expectations = 1, df.ChangeVerification, nil
+	require.NoError(t, r.Write(tc.Name, tc))
+
+	revisions, err := r.Revisions(tc.Name)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[0].Number)
+	assert.Equal(t, df.ChangeRecording, revisions[0].Change)
+	assert.Equal(t, 1, revisions[0].Expectations)
+	assert.WithinDuration(t, time.Now(), revisions[0].Written, time.Minute)
+	assert.Equal(t, df.ChangeVerification, revisions[1].Change)
+	assert.Equal(t, 0, revisions[1].Expectations)
+
+	first, err := r.GetRevision(tc.Name, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Revision)
+	assert.Equal(t, []df.Expectation{{Uuid: "1", Tokens: []string{"insert"}, Pattern: "insert", IgnoreDiffs: []int{}}}, first.Expectations)
+	_, err = r.GetRevision(tc.Name, 3)
+	assert.ErrorIs(t, err, df.ErrRevisionNotFound)
+
+	// only the last df.MaxRevisions revisions are kept
+	for i := 2; i < df.MaxRevisions+3; i++ {
+		tc.Revision = i
+		require.NoError(t, r.Write(tc.Name, tc))
+	}
+	revisions, err = r.Revisions(tc.Name)
+	require.NoError(t, err)
+	assert.Len(t, revisions, df.MaxRevisions)
+	assert.Equal(t, 4, revisions[0].Number)
+
+	require.NoError(t, r.Delete(tc.Name))
+	_, err = r.GetRevision(tc.Name, 50)
+	assert.ErrorIs(t, err, df.ErrRevisionNotFound)
+}
@@ -0,0 +1,35 @@
+// Package storage creates the TestRepository described by the storage settings
+// of the config.
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rwirdemann/datafrog/pkg/df"
+	"github.com/rwirdemann/datafrog/pkg/file"
+	"github.com/rwirdemann/datafrog/pkg/sqlite"
+)
+
+// NewTestRepository creates the repository described by config, see
+// df.StorageConfig.
+func NewTestRepository(config df.StorageConfig) (df.TestRepository, error) {
+	switch config.Type {
+	case "", df.StorageJSON:
+		if config.Dir == "" {
+			return file.JSONTestRepository{}, nil
+		}
+		return file.NewJSONTestRepository(filepath.Join(config.Dir, "tests"))
+	case df.StorageSQLite:
+		dir := config.Dir
+		if dir == "" {
+			dir = "."
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return sqlite.NewSQLiteTestRepository(filepath.Join(dir, "datafrog.db"))
+	}
+	return nil, fmt.Errorf("unsupported storage type '%s'", config.Type)
+}
@@ -0,0 +1,94 @@
+package verify
+
+// infeasible is the cost of an assignment that must not be made. It exceeds the
+// sum of all feasible costs, thus an optimal assignment always contains as many
+// feasible pairs as possible.
+const infeasible = int64(1) << 40
+
+// assign solves the assignment problem for the given cost matrix by using the
+// hungarian algorithm. cost[r][c] is the cost of assigning row r to column c.
+// Returns the assigned column for each row or -1 if the row was left
+// unassigned or was only assignable at infeasible cost.
+func assign(cost [][]int64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(cost[0])
+	n := max(rows, cols)
+
+	// c returns the cost of the square matrix padded with infeasible entries
+	c := func(r, col int) int64 {
+		if r < rows && col < cols {
+			return cost[r][col]
+		}
+		return infeasible
+	}
+
+	// potentials u and v, p[j] is the row assigned to column j (1-based)
+	u := make([]int64, n+1)
+	v := make([]int64, n+1)
+	p := make([]int, n+1)
+	way := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]int64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = 1<<62 - 1
+		}
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := int64(1<<62 - 1)
+			j1 := 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				cur := c(i0-1, j-1) - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+			if j0 == 0 {
+				break
+			}
+		}
+	}
+
+	result := make([]int, rows)
+	for i := range result {
+		result[i] = -1
+	}
+	for j := 1; j <= n; j++ {
+		r, col := p[j]-1, j-1
+		if r < rows && col < cols && cost[r][col] < infeasible {
+			result[r] = col
+		}
+	}
+	return result
+}
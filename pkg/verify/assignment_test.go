@@ -0,0 +1,46 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		desc     string
+		cost     [][]int64
+		expected []int
+	}{
+		{
+			desc:     "greedy choice is not optimal",
+			cost:     [][]int64{{1, 2}, {1, infeasible}},
+			expected: []int{1, 0},
+		},
+		{
+			desc:     "more rows than columns",
+			cost:     [][]int64{{3}, {1}, {2}},
+			expected: []int{-1, 0, -1},
+		},
+		{
+			desc:     "more columns than rows",
+			cost:     [][]int64{{5, 1, 3}},
+			expected: []int{1},
+		},
+		{
+			desc:     "infeasible only",
+			cost:     [][]int64{{infeasible}},
+			expected: []int{-1},
+		},
+		{
+			desc:     "empty",
+			cost:     nil,
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, assign(test.cost))
+		})
+	}
+}
@@ -1,17 +1,17 @@
 package verify
 
 import (
+	"errors"
+
 	"github.com/rwirdemann/datafrog/pkg/df"
-	"github.com/rwirdemann/datafrog/pkg/mysql"
 	log "github.com/sirupsen/logrus"
 )
 
-// Runner runs the verifier for the given channel.
+// Runner runs the verifier for the given channel sources.
 type Runner struct {
 	testname   string
-	channel    df.Channel
+	sources    []df.Source
 	config     df.Config
-	channelLog df.Log
 	repository df.TestRepository
 	verifier   *Verifier
 	done       chan struct{}
@@ -19,28 +19,46 @@ type Runner struct {
 }
 
 // NewRunner creates a new runner for verifying interactions of the given
-// channel.
-func NewRunner(testname string, channel df.Channel, config df.Config, logFactory df.LogFactory, repository df.TestRepository) *Runner {
-	return &Runner{testname: testname, channel: channel, config: config, channelLog: logFactory.Create(channel.Log), repository: repository}
+// channel sources. All channels are verified within the same run.
+func NewRunner(testname string, sources []df.Source, config df.Config, repository df.TestRepository) *Runner {
+	return &Runner{testname: testname, sources: sources, config: config, repository: repository}
 }
 
-// Start starts a new verifier as go routine.
+// Start starts a new verifier as go routine. Returns as soon as the
+// verifier monitors the channel logs or with the error of the verifier if one of
+// the logs couldn't be tailed.
 func (r *Runner) Start() error {
 	tc, err := r.repository.Get(r.testname)
 	if err != nil {
-		return nil
+		_ = df.CloseSources(r.sources)
+		return err
 	}
 
-	r.verifier = NewVerifier(r.config, r.channel, r.repository, mysql.Tokenizer{}, r.channelLog, tc, &df.UTCTimer{}, r.testname)
+	r.verifier = NewVerifier(r.config, r.sources, r.repository, tc, &df.UTCTimer{}, r.testname)
 	r.done = make(chan struct{})
 	r.stopped = make(chan struct{})
 	go r.verifier.Start(r.done, r.stopped)
+
+	// make sure that all statements logged after Start returns are considered
+	<-r.verifier.Started()
+	if err := r.verifier.Err(); err != nil {
+		<-r.stopped
+		_ = df.CloseSources(r.sources)
+		return err
+	}
 	return nil
 }
 
+// Discard closes the channel logs of a runner that hasn't been started, e.g.
+// because the test is already being recorded or verified.
+func (r *Runner) Discard() error {
+	return df.CloseSources(r.sources)
+}
+
 // Stop stops the verification by closing the done channel, that is checked by the
-// verifier for its termination. Closes also the channels log file and test
-// writer.
+// verifier for its termination. Closes also the channel log files and test
+// writer. Returns the error of the verifier, e.g. a failed log or write, joined
+// with the errors of closing the log files.
 func (r *Runner) Stop() error {
 	// tell verifier that verification has been finished
 	close(r.done)
@@ -50,13 +68,28 @@ func (r *Runner) Stop() error {
 	<-r.stopped
 	log.Printf("vrunner: stopped channel closed")
 
-	// close log file
-	r.channelLog.Close()
-
-	return nil
+	// close log files
+	return errors.Join(r.verifier.Err(), df.CloseSources(r.sources))
 }
 
 // Testcase returns the testcase.
 func (r *Runner) Testcase() df.Testcase {
 	return r.verifier.testcase
 }
+
+// Snapshot returns a copy of the testcase verified so far, see
+// Verifier.Snapshot.
+func (r *Runner) Snapshot() df.Testcase {
+	return r.verifier.Snapshot()
+}
+
+// Report returns the report of the verification run.
+func (r *Runner) Report() df.Report {
+	return r.verifier.ReportResults()
+}
+
+// SetDriverOutcome sets the outcome of the UI driver that executes the use case
+// of the verification run.
+func (r *Runner) SetDriverOutcome(outcome string) {
+	r.verifier.SetDriverOutcome(outcome)
+}
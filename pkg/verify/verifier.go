@@ -2,192 +2,674 @@ package verify
 
 import (
 	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/rwirdemann/datafrog/pkg/df"
 )
 
 // The Verifier verifies the expectations of the given testcase. It monitors the
-// channels log for these expectations and increases their verify count if
-// matched. The updated expectation list is written back via the given writer
-// after the verification run is done.
+// logs of all channel sources for these expectations and increases their verify
+// count if matched. Each expectation is only verified against the log of the
+// channel it was recorded from. The updated expectation list is written back via
+// the given writer after the verification run is done.
 type Verifier struct {
 	config     df.Config
-	channel    df.Channel
+	sources    []df.Source
 	repository df.TestRepository
-	tokenizer  df.Tokenizer
-	log        df.Log
 	testcase   df.Testcase
 	timer      df.Timer
 	name       string
+
+	position   int              // number of pattern matching statements in the current run
+	statements []statement      // pattern matching statements of the current run
+	matches    []match          // verified expectations in order of their verifying statements
+	baseline   []df.Expectation // expectations as they were before the current run
+	disabled   []disabled       // disabled expectations, skipped by the current run
+
+	progress sync.Mutex // guards testcase while the run is in progress, see Snapshot
+
+	started chan struct{} // closed as soon as all logs are tailed
+	err     error         // reason why the verification failed, read after started or stopped is closed
+
+	mu     sync.Mutex
+	driver string // outcome of the UI driver that executed the use case
+}
+
+// statement represents a pattern matching statement of the current run.
+type statement struct {
+	channel  string
+	pattern  string
+	tokens   []string
+	position int // position of the statement within the run
+}
+
+// disabled keeps a disabled expectation together with its position within the
+// testcase's expectations.
+type disabled struct {
+	expectation df.Expectation
+	position    int
+}
+
+// match assigns a verifying statement to the expectation it verified.
+type match struct {
+	expectation int      // index of the verified expectation
+	position    int      // position of the verifying statement within the run
+	tokens      []string // tokens of the verifying statement
+	reference   bool     // true if the statement became the expectation's reference
 }
 
 // NewVerifier creates a new Verifier.
 func NewVerifier(
 	config df.Config,
-	channel df.Channel,
+	sources []df.Source,
 	repository df.TestRepository,
-	tokenizer df.Tokenizer,
-	log df.Log,
 	tc df.Testcase,
 	t df.Timer,
 	name string) *Verifier {
 	return &Verifier{
 		config:     config,
-		channel:    channel,
+		sources:    sources,
 		repository: repository,
-		tokenizer:  tokenizer,
-		log:        log,
 		testcase:   tc,
 		timer:      t,
 		name:       name,
+		started:    make(chan struct{}),
 	}
 }
+
+// Started returns a channel that is closed as soon as the verifier monitors its
+// logs. Statements logged afterward are verified.
+func (verifier *Verifier) Started() <-chan struct{} {
+	return verifier.started
+}
+
+// Err returns the reason why the verification failed or nil. Only valid after
+// the started or stopped channel has been closed.
+func (verifier *Verifier) Err() error {
+	return verifier.err
+}
+
 func (verifier *Verifier) Testcase() df.Testcase {
 	return verifier.testcase
 }
 
+// Snapshot returns a copy of the testcase verified so far. Unlike Testcase it
+// may be called while the verification is running.
+func (verifier *Verifier) Snapshot() df.Testcase {
+	verifier.progress.Lock()
+	defer verifier.progress.Unlock()
+	return verifier.testcase.Copy()
+}
+
 // Start runs the verification loop. Stops when done channel was closed. Closes
 // stopped channel afterward in order to tell its caller (web, cli, ...) that
-// verification has been finished.
+// verification has been finished. The verification fails without writing the
+// testcase if one of the logs fails, see Err.
 func (verifier *Verifier) Start(done chan struct{}, stopped chan struct{}) {
 	verifier.timer.Start()
 	log.Printf("verification started at %v...", verifier.timer.GetStart())
 	verifier.testcase.Verifications = verifier.testcase.Verifications + 1
 	verifier.testcase.LastExecution = time.Now()
+	verifier.disable()
 	for i := range verifier.testcase.Expectations {
 		verifier.testcase.Expectations[i].Fulfilled = false
+		verifier.testcase.Expectations[i].Occurrences = 0
+		verifier.testcase.Expectations[i].Closest = ""
 	}
+	verifier.testcase.AdditionalExpectations = nil
+	verifier.testcase.OrderViolations = nil
+	verifier.testcase.BrokenCorrelations = nil
+	verifier.testcase.ForbiddenViolations = nil
+	verifier.baseline = make([]df.Expectation, len(verifier.testcase.Expectations))
+	copy(verifier.baseline, verifier.testcase.Expectations)
 
 	// tell caller that verification has been finished
 	defer close(stopped)
 
+	// jump to log file ends
+	if err := df.TailSources(verifier.sources); err != nil {
+		verifier.err = err
+		close(verifier.started)
+		return
+	}
+	close(verifier.started)
+
 	// called when done channel is closed
 	defer func() {
-		// create a write copy of the testcase to make sure no additional expectations
-		// are saved but kept for reporting reasons
-		tc := verifier.testcase
+		if verifier.err != nil {
+			return // -> don't record a run with missing statements
+		}
+		verifier.progress.Lock()
+		defer verifier.progress.Unlock()
+		verifier.reconcile()
+		verifier.findClosest()
+		verifier.checkOrder()
+		verifier.checkCorrelations()
+		verifier.learnCorrelations()
+		verifier.testcase.AddRun(verifier.run())
 
-		// don't write additional expectations
-		tc.AdditionalExpectations = nil
+		// the additional expectations of the run are written, thus they can be
+		// reviewed and accepted as new baseline, see df.Testcase.Rebaseline
+		tc := verifier.testcase
+		tc.Expectations = verifier.enable()
+		tc.Change = df.ChangeVerification
 
-		if err := verifier.repository.Write(tc.Name, tc); err != nil {
-			log.Fatal(err)
-		}
-		//verifier.write()
+		verifier.err = verifier.repository.Write(tc.Name, tc)
 	}()
 
-	// jump to log file end
-	if err := verifier.log.Tail(); err != nil {
-		log.Fatal(err)
-	}
-
+	// the runner closes the logs as soon as stopped is closed
+	lines, stop := df.ReadLines(verifier.sources, done)
+	defer stop()
 	for {
 		select {
-		default:
-			v, err := verifier.log.NextLine(done)
-			if err != nil {
-				log.Fatal(err)
+		case v := <-lines:
+			if v.Err != nil {
+				log.Errorf("verifier: %v", v.Err)
+				verifier.err = v.Err
+				return
 			}
+			verifier.progress.Lock()
+			verifier.process(v)
+			verifier.progress.Unlock()
+		case <-done:
+			log.Printf("verifier: done channel closed")
+			return
+		}
+	}
+}
 
-			ts, err := verifier.log.Timestamp(v)
-			if err != nil {
-				continue
-			}
-			if verifier.timer.MatchesRecordingPeriod(ts) {
-				matches, vPattern := df.MatchesPattern(verifier.channel.Patterns, v)
-				if !matches {
-					continue
-				}
+// process verifies the statement of v if it was logged within the recording
+// period and matches one of the patterns of its channel.
+func (verifier *Verifier) process(v df.Line) {
+	ts, err := v.Source.Log.Timestamp(v.Text)
+	if err != nil || !verifier.timer.MatchesRecordingPeriod(ts) {
+		return
+	}
+	verifier.checkForbidden(v)
 
-				verified := verifier.verify(v, vPattern)
+	matches, vPattern := df.MatchesPattern(v.Source.Channel.Patterns, v.Text)
+	if !matches {
+		return
+	}
+	verifier.position++
 
-				if !verified && verifier.config.Expectations.ReportAdditional {
+	verified := verifier.verify(v, vPattern)
 
-					// v matches pattern but no matching expectation was found
-					expectation := df.Expectation{
-						Tokens: verifier.tokenizer.Tokenize(v, verifier.channel.Patterns), Pattern: vPattern,
-					}
-					log.Printf("additional expectation found: %s\n", expectation.Shorten(6))
-					verifier.testcase.AdditionalExpectations = append(verifier.testcase.AdditionalExpectations, expectation)
-				}
-			}
-		case <-done:
-			log.Printf("verifier: done channel closed")
-			return
+	if !verified && verifier.config.Expectations.ReportAdditional {
+
+		// v matches pattern but no matching expectation was found
+		expectation := df.Expectation{
+			Tokens: v.Tokenize(), Pattern: vPattern, Channel: v.Source.Channel.Name,
 		}
+		log.Printf("additional expectation found: %s\n", expectation.Shorten(6))
+		verifier.testcase.AdditionalExpectations = append(verifier.testcase.AdditionalExpectations, expectation)
 	}
 }
 
-// verify tries to verify one of the testcases expectations. Returns true if an
-// expectation was verified and false otherwise.
-func (verifier *Verifier) verify(v string, vPattern string) bool {
+// disable moves the disabled expectations out of the testcase, thus they are
+// neither verified nor reported. Statements they would have matched become
+// additional expectations.
+func (verifier *Verifier) disable() {
+	var enabled []df.Expectation
 	for i, e := range verifier.testcase.Expectations {
-		if e.Fulfilled || e.Pattern != vPattern {
+		if e.Disabled {
+			verifier.disabled = append(verifier.disabled, disabled{expectation: e, position: i})
+			continue
+		}
+		enabled = append(enabled, e)
+	}
+	verifier.testcase.Expectations = enabled
+}
+
+// enable returns the testcase's expectations with the disabled expectations
+// reinserted at their original positions.
+func (verifier *Verifier) enable() []df.Expectation {
+	all := make([]df.Expectation, 0, len(verifier.testcase.Expectations)+len(verifier.disabled))
+	all = append(all, verifier.testcase.Expectations...)
+	for _, d := range verifier.disabled {
+		all = append(all[:d.position], append([]df.Expectation{d.expectation}, all[d.position:]...)...)
+	}
+	return all
+}
+
+// verify tries to verify one of the testcases expectations that belong to the
+// channel of v. Among all candidates the expectation with the fewest differing
+// tokens is chosen. Returns true if an expectation was verified and false
+// otherwise. The choice is provisional and revised by reconcile at the end of
+// the run. Statements of repeated expectations are counted as occurrences of
+// their expectation.
+func (verifier *Verifier) verify(v df.Line, vPattern string) bool {
+	vTokens := v.Tokenize()
+	s := statement{
+		channel:  v.Source.Channel.Name,
+		pattern:  vPattern,
+		tokens:   vTokens,
+		position: verifier.position,
+	}
+	verifier.statements = append(verifier.statements, s)
+
+	if i, ok := verifier.repeated(s); ok {
+		verifier.occur(i, vTokens, verifier.position)
+		e := verifier.testcase.Expectations[i]
+		log.Printf("repeated expectation verified by: %s (%s)\n", df.Expectation{Tokens: vTokens}.Shorten(6), e.Cardinality())
+		return true
+	}
+
+	best, bestCost := -1, 0
+	for i, e := range verifier.testcase.Expectations {
+		if e.Fulfilled || e.Repeated() || e.Pattern != vPattern || !e.BelongsTo(v.Source.Channel.Name) {
 			continue // -> continue with next e
 		}
+		if cost, ok := candidate(e, vTokens); ok && (best == -1 || cost < bestCost) {
+			best, bestCost = i, cost
+		}
+	}
+	if best == -1 {
+		return false // -> expectation not verified
+	}
 
-		vTokens := verifier.tokenizer.Tokenize(v, verifier.channel.Patterns)
+	if verifier.testcase.Expectations[best].Verified == 0 {
+		log.Printf("reference expectation found: %s\n", df.Expectation{Tokens: vTokens}.Shorten(6))
+	} else {
+		log.Printf("expectation verified by: %s\n", df.Expectation{Tokens: vTokens}.Shorten(6))
+	}
+	verifier.fulfill(best, vTokens, verifier.position)
+	return true
+}
 
-		// Handle already verified expectations (reference expectation)
-		if e.Verified > 0 && e.Equal(vTokens) {
-			log.Printf("expectation verified by: %s\n", df.Expectation{Tokens: vTokens}.Shorten(6))
-			verifier.testcase.Expectations[i].Fulfilled = true
-			verifier.testcase.Expectations[i].Verified = e.Verified + 1
-			return true // -> continue with next v
+// candidate returns the number of tokens that differ between e and tokens and
+// true if tokens are able to verify e. Already verified expectations must be
+// equal to tokens. Not yet verified expectations only require the same number
+// of tokens, they take tokens as their reference.
+func candidate(e df.Expectation, tokens []string) (int, bool) {
+	diff, err := e.Diff(tokens)
+	if err != nil {
+		return 0, false
+	}
+	if e.Verified > 0 && !e.Equal(tokens) {
+		return 0, false
+	}
+	return len(diff), true
+}
+
+// fulfill marks expectation i as fulfilled by the statement tokens found at
+// position. A not yet verified expectation learns its allowed differences from
+// tokens.
+func (verifier *Verifier) fulfill(i int, tokens []string, position int) {
+	e := &verifier.testcase.Expectations[i]
+	reference := e.Verified == 0
+	if reference {
+		diff, _ := e.Diff(tokens)
+		e.IgnoreDiffs = diff
+		e.IgnoreRules = e.InferRules(tokens, diff)
+	}
+	e.Fulfilled = true
+	e.Verified = e.Verified + 1
+	verifier.matches = append(verifier.matches, match{expectation: i, position: position, tokens: tokens, reference: reference})
+}
+
+// repeated returns the index of the repeated expectation s is an occurrence of.
+func (verifier *Verifier) repeated(s statement) (int, bool) {
+	for i, e := range verifier.testcase.Expectations {
+		if e.Repeated() && e.Pattern == s.pattern && e.BelongsTo(s.channel) && e.NormalizedEqual(s.tokens) {
+			return i, true
 		}
+	}
+	return -1, false
+}
 
-		if len(e.Tokens) != len(vTokens) {
-			continue // -> continue with next e
+// occur counts the statement tokens found at position as occurrence of the
+// repeated expectation i. The expectation is fulfilled as long as the number of
+// its occurrences is within its accepted range.
+func (verifier *Verifier) occur(i int, tokens []string, position int) {
+	e := &verifier.testcase.Expectations[i]
+	e.Occurrences++
+	e.Fulfilled = e.AcceptsCount(e.Occurrences)
+	if e.Occurrences == 1 {
+		verifier.matches = append(verifier.matches, match{expectation: i, position: position, tokens: tokens})
+	}
+}
+
+// reconcile revises the assignments made during the run. Statements are
+// assigned to expectations one by one as they appear, thus a statement may take
+// an expectation that is needed by a later statement or pair with a similar but
+// wrong expectation. reconcile computes the minimum cost assignment between all
+// statements and expectations of each pattern, where the cost of a pair is the
+// number of differing tokens. Ties are broken in favour of the recorded order.
+// The expectations are then reset to their state before the run and fulfilled
+// according to the final assignment. Occurrences of repeated expectations are
+// not part of the assignment.
+func (verifier *Verifier) reconcile() {
+	copy(verifier.testcase.Expectations, verifier.baseline)
+	verifier.matches = nil
+	verifier.testcase.AdditionalExpectations = nil
+	assigned := make([]bool, len(verifier.statements))
+
+	patterns := make(map[string][]int) // pattern -> statement indices
+	var order []string
+	for i, s := range verifier.statements {
+		if e, ok := verifier.repeated(s); ok {
+			verifier.occur(e, s.tokens, s.position)
+			assigned[i] = true
+			continue
+		}
+		if _, ok := patterns[s.pattern]; !ok {
+			order = append(order, s.pattern)
+		}
+		patterns[s.pattern] = append(patterns[s.pattern], i)
+	}
+	for i, e := range verifier.testcase.Expectations {
+		if e.Repeated() && e.Fulfilled {
+			verifier.testcase.Expectations[i].Verified++
 		}
+	}
 
-		if e.Verified == 0 {
-			// Not yet verified expectation e with same token lengths as v
-			// found. This expectation e becomes our reference expectation.
-			if diff, err := e.Diff(vTokens); err == nil {
-				log.Printf("reference expectation found: %s\n", df.Expectation{Tokens: vTokens}.Shorten(6))
-				verifier.testcase.Expectations[i].IgnoreDiffs = diff
-				verifier.testcase.Expectations[i].Fulfilled = true
-				verifier.testcase.Expectations[i].Verified = 1
-				return true // -> continue with next v
+	for _, pattern := range order {
+		statements := patterns[pattern]
+		var expectations []int
+		for i, e := range verifier.baseline {
+			if e.Pattern == pattern && !e.Repeated() {
+				expectations = append(expectations, i)
+			}
+		}
+		if len(expectations) == 0 {
+			continue
+		}
+
+		// the positional distances of an assignment sum up to less than n*n+1,
+		// thus a single differing token outweighs any order of the statements
+		n := int64(max(len(statements), len(expectations)))
+		cost := make([][]int64, len(statements))
+		for r, si := range statements {
+			s := verifier.statements[si]
+			cost[r] = make([]int64, len(expectations))
+			for c, ei := range expectations {
+				e := verifier.baseline[ei]
+				diff, ok := candidate(e, s.tokens)
+				if !ok || !e.BelongsTo(s.channel) {
+					cost[r][c] = infeasible
+					continue
+				}
+				distance := int64(r - c)
+				if distance < 0 {
+					distance = -distance
+				}
+				cost[r][c] = int64(diff)*(n*n+1) + distance
+			}
+		}
+
+		for r, c := range assign(cost) {
+			if c == -1 {
+				continue
+			}
+			s := verifier.statements[statements[r]]
+			verifier.fulfill(expectations[c], s.tokens, s.position)
+			assigned[statements[r]] = true
+		}
+	}
+
+	sort.Slice(verifier.matches, func(i, j int) bool { return verifier.matches[i].position < verifier.matches[j].position })
+
+	if verifier.config.Expectations.ReportAdditional {
+		for i, s := range verifier.statements {
+			if !assigned[i] {
+				verifier.testcase.AdditionalExpectations = append(verifier.testcase.AdditionalExpectations,
+					df.Expectation{Tokens: s.tokens, Pattern: s.pattern, Channel: s.channel})
 			}
 		}
 	}
-	return false // -> expectation not verified
 }
 
-// ReportResults creates a [domain.Report] of the verification results.
-func (verifier *Verifier) ReportResults() df.Report {
-	fulfilled := 0
-	verifiedSum := 0
+// SetDriverOutcome sets the outcome of the UI driver that executes the use
+// case of the current verification run.
+func (verifier *Verifier) SetDriverOutcome(outcome string) {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	verifier.driver = outcome
+}
+
+// run creates the history entry of the current verification run.
+func (verifier *Verifier) run() df.Run {
+	report := verifier.ReportResults()
+	r := df.Run{
+		Start:      verifier.testcase.LastExecution,
+		End:        time.Now(),
+		Additional: report.AdditionalExpectations,
+		Passed:     report.Passed,
+	}
+	r.Duration = r.End.Sub(r.Start)
 	for _, e := range verifier.testcase.Expectations {
-		verifiedSum += e.Verified
 		if e.Fulfilled {
-			fulfilled = fulfilled + 1
+			r.Fulfilled = append(r.Fulfilled, e.Uuid)
+		} else {
+			r.Unfulfilled = append(r.Unfulfilled, e.Uuid)
 		}
 	}
-	report := df.Report{
-		Testname:         verifier.name,
-		LastExecution:    time.Now(),
-		Expectations:     len(verifier.testcase.Expectations),
-		Verifications:    verifier.testcase.Verifications,
-		Fulfilled:        fulfilled,
-		VerificationMean: verificationMean(float32(verifiedSum), float32(len(verifier.testcase.Expectations))),
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+	r.Driver = verifier.driver
+	return r
+}
+
+// findClosest determines the closest actual statement of each unfulfilled
+// expectation that didn't occur at all. The closest statement is the statement
+// of the same pattern and channel that requires the fewest token changes to
+// become the expectation.
+func (verifier *Verifier) findClosest() {
+	for i, e := range verifier.testcase.Expectations {
+		if e.Fulfilled || e.Occurrences > 0 {
+			continue
+		}
+		closest, distance := -1, 0
+		for j, s := range verifier.statements {
+			if s.pattern != e.Pattern || !e.BelongsTo(s.channel) {
+				continue
+			}
+			if d := df.Distance(e.Tokens, s.tokens); closest == -1 || d < distance {
+				closest, distance = j, d
+			}
+		}
+		if closest > -1 {
+			verifier.testcase.Expectations[i].Closest = df.Expectation{Tokens: verifier.statements[closest].tokens}.String()
+		}
 	}
-	for _, e := range verifier.testcase.Expectations {
-		if !e.Fulfilled {
-			report.Unfulfilled = append(report.Unfulfilled, e)
+}
+
+// checkForbidden records a violation for each forbidden pattern that matches v.
+// Forbidden statements are checked regardless of the channel patterns.
+func (verifier *Verifier) checkForbidden(v df.Line) {
+	for _, f := range verifier.testcase.Forbidden {
+		if !f.Matches(v.Source.Channel.Name, v.Text) {
+			continue
+		}
+		violation := df.ForbiddenViolation{
+			Forbidden: f,
+			Statement: df.Expectation{Tokens: v.Source.Tokenizer.Tokenize(v.Text, []string{f.Pattern})}.String(),
 		}
+		log.Printf("forbidden statement found: %s", violation)
+		verifier.testcase.ForbiddenViolations = append(verifier.testcase.ForbiddenViolations, violation)
 	}
-	for _, e := range verifier.testcase.AdditionalExpectations {
-		report.AdditionalExpectations = append(report.AdditionalExpectations, e.Shorten(6))
+}
+
+// checkOrder compares the recorded order of the verified expectations with the
+// order of their verifying statements according to the testcase's ordering.
+// The statements of each group that keep their recorded order are the longest
+// increasing subsequence of their expectations, ties are broken in favour of the
+// earlier statements. Each other statement verified its expectation out of order
+// and is added to the testcase's order violations.
+func (verifier *Verifier) checkOrder() {
+	groups := make(map[string][]match) // group -> matches in order of their statements
+	var order []string
+	for _, m := range verifier.matches {
+		group, ok := verifier.testcase.Ordering.Group(verifier.testcase.Expectations[m.expectation])
+		if !ok {
+			continue
+		}
+		if _, found := groups[group]; !found {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], m)
 	}
-	return report
+
+	var violations []match
+	for _, group := range order {
+		matches := groups[group]
+		ordered := increasing(matches)
+		for i, m := range matches {
+			if !ordered[i] {
+				violations = append(violations, m)
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].position < violations[j].position })
+
+	for _, m := range violations {
+		e := verifier.testcase.Expectations[m.expectation]
+		v := df.OrderViolation{
+			Uuid:      e.Uuid,
+			Statement: df.Expectation{Tokens: m.tokens}.Shorten(6),
+			Expected:  verifier.stored(m.expectation) + 1,
+			Actual:    m.position,
+		}
+		log.Printf("order violation: %s", v)
+		verifier.testcase.OrderViolations = append(verifier.testcase.OrderViolations, v)
+	}
+}
+
+// increasing marks the matches that form the longest subsequence of matches
+// with increasing expectation indices. Among several longest subsequences the
+// one with the earliest matches is chosen.
+func increasing(matches []match) []bool {
+	length := make([]int, len(matches)) // length of the longest subsequence starting at i
+	longest := 0
+	for i := len(matches) - 1; i >= 0; i-- {
+		length[i] = 1
+		for j := i + 1; j < len(matches); j++ {
+			if matches[j].expectation > matches[i].expectation && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+			}
+		}
+		longest = max(longest, length[i])
+	}
+
+	ordered := make([]bool, len(matches))
+	last := -1
+	for i, m := range matches {
+		if longest > 0 && length[i] == longest && (last == -1 || m.expectation > matches[last].expectation) {
+			ordered[i] = true
+			last = i
+			longest--
+		}
+	}
+	return ordered
+}
+
+// stored returns the position of the enabled expectation i within the stored
+// testcase, which still contains the disabled expectations.
+func (verifier *Verifier) stored(i int) int {
+	for _, d := range verifier.disabled {
+		if d.position <= i {
+			i++
+		}
+	}
+	return i
+}
+
+// learnCorrelations learns the data flows between all expectations that got
+// their reference statement within this run. Two dynamic tokens, i.e. tokens
+// whose index is contained in IgnoreDiffs, are correlated if they had the same
+// value during the recording and have the same value again in the verifying
+// statements. Each token is correlated with its first occurrence only.
+func (verifier *Verifier) learnCorrelations() {
+	type values struct{ recorded, actual string }
+	first := make(map[values]df.TokenRef)
+	for _, m := range verifier.byRecordedOrder() {
+		e := verifier.testcase.Expectations[m.expectation]
+		if !m.reference || e.Uuid == "" {
+			continue
+		}
+		for _, i := range e.IgnoreDiffs {
+			v := values{recorded: df.Value(e.Tokens[i]), actual: df.Value(m.tokens[i])}
+			if v.recorded == "" || v.actual == "" {
+				continue
+			}
+			ref := df.TokenRef{Uuid: e.Uuid, Token: i}
+			source, ok := first[v]
+			if !ok {
+				first[v] = ref
+				continue
+			}
+			c := df.Correlation{Source: source, Target: ref}
+			log.Printf("correlation learned: %v", c)
+			verifier.testcase.Correlations = append(verifier.testcase.Correlations, c)
+		}
+	}
+}
+
+// checkCorrelations ensures that the source and target values of each known
+// correlation are equal within the verifying statements. Correlations with an
+// unverified source or target are skipped. Each broken correlation is added to
+// the testcase's broken correlations.
+func (verifier *Verifier) checkCorrelations() {
+	matches := make(map[string]match)
+	for _, m := range verifier.matches {
+		matches[verifier.testcase.Expectations[m.expectation].Uuid] = m
+	}
+	for _, c := range verifier.testcase.Correlations {
+		source, sourceOK := matches[c.Source.Uuid]
+		target, targetOK := matches[c.Target.Uuid]
+		if !sourceOK || !targetOK || c.Source.Token >= len(source.tokens) || c.Target.Token >= len(target.tokens) {
+			continue
+		}
+		sourceValue := df.Value(source.tokens[c.Source.Token])
+		targetValue := df.Value(target.tokens[c.Target.Token])
+		if sourceValue != targetValue {
+			v := df.CorrelationViolation{
+				Correlation: c,
+				Source:      df.Expectation{Tokens: source.tokens}.Shorten(6),
+				SourceValue: sourceValue,
+				Target:      df.Expectation{Tokens: target.tokens}.Shorten(6),
+				TargetValue: targetValue,
+			}
+			log.Printf("broken data flow: %s", v)
+			verifier.testcase.BrokenCorrelations = append(verifier.testcase.BrokenCorrelations, v)
+		}
+	}
+}
+
+// byRecordedOrder returns the matches sorted by the recorded order of their
+// expectations.
+func (verifier *Verifier) byRecordedOrder() []match {
+	sorted := make([]match, len(verifier.matches))
+	copy(sorted, verifier.matches)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].expectation < sorted[j].expectation })
+	return sorted
 }
 
-func verificationMean(sum, expectationCount float32) float32 {
-	if expectationCount > 0 {
-		return sum / expectationCount
+// ReportResults creates a [df.Report] of the verification results. The report
+// lists all channels of the verifier, including channels without expectations.
+func (verifier *Verifier) ReportResults() df.Report {
+	report := df.NewReport(verifier.testcase)
+	report.Testname = verifier.name
+	report.LastExecution = time.Now()
+	report.Channels = nil
+	for _, s := range verifier.sources {
+		cr := df.ChannelReport{Name: s.Channel.Name}
+		for _, e := range verifier.testcase.Expectations {
+			if e.Channel == s.Channel.Name {
+				cr.Expectations++
+				if e.Fulfilled {
+					cr.Fulfilled++
+				}
+			}
+		}
+		report.Channels = append(report.Channels, cr)
 	}
-	return 0
+	report.AdditionalExpectations = nil
+	for _, e := range verifier.testcase.AdditionalExpectations {
+		report.AdditionalExpectations = append(report.AdditionalExpectations, e.String())
+	}
+	return report
 }
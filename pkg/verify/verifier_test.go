@@ -1,6 +1,7 @@
 package verify
 
 import (
+	"fmt"
 	"github.com/rwirdemann/datafrog/pkg/df"
 	"github.com/rwirdemann/datafrog/pkg/mocks"
 	"github.com/rwirdemann/datafrog/pkg/mysql"
@@ -301,7 +302,8 @@ func TestVerify(t *testing.T) {
 			tc := df.Testcase{Name: "create-job", Expectations: tC.initialExpectations}
 			repository := &mocks.TestRepository{}
 			timer := mocks.Timer{}
-			verifier := NewVerifier(c, c.Channels[0], repository, mysql.Tokenizer{}, databaseLog, tc, timer, "")
+			sources := []df.Source{{Channel: c.Channels[0], Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+			verifier := NewVerifier(c, sources, repository, tc, timer, "")
 			go verifier.Start(doneChannel, stoppedChannel)
 			<-stoppedChannel // wait till verifier is done
 			for i, e := range verifier.Testcase().Expectations {
@@ -314,11 +316,357 @@ func TestVerify(t *testing.T) {
 			// check if additional expectations are expected and added
 			assert.Equal(t, tC.additionalExpectations, verifier.Testcase().AdditionalExpectations)
 
-			// check if the updated testcase was written back (without eventually added expectations)
+			// check if the updated testcase was written back including the additional
+			// expectations of the run
 			actual, err := repository.Get("create-job")
 			assert.NoError(t, err)
 			assert.Equal(t, 1, actual.Verifications)
-			assert.Nil(t, actual.AdditionalExpectations)
+			assert.Equal(t, tC.additionalExpectations, actual.AdditionalExpectations)
 		})
 	}
 }
+
+func TestVerifyMultipleChannels(t *testing.T) {
+	statement := "insert into job (description, id) values ('Developer', 4)"
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Tokens: df.Tokenize(statement), Pattern: "insert", Channel: "mysql"},
+		{Tokens: df.Tokenize(statement), Pattern: "insert", Channel: "postgres"},
+	}}
+	mysqlSource := df.Source{Channel: df.Channel{Name: "mysql", Patterns: []string{"insert"}}, Tokenizer: mysql.Tokenizer{}}
+	postgresSource := df.Source{Channel: df.Channel{Name: "postgres", Patterns: []string{"insert"}}, Tokenizer: mysql.Tokenizer{}}
+	verifier := NewVerifier(df.Config{}, []df.Source{mysqlSource, postgresSource}, &mocks.TestRepository{}, tc, mocks.Timer{}, "create-job")
+
+	assert.True(t, verifier.verify(df.Line{Source: postgresSource, Text: statement}, "insert"))
+	assert.False(t, verifier.Testcase().Expectations[0].Fulfilled)
+	assert.True(t, verifier.Testcase().Expectations[1].Fulfilled)
+
+	// the postgres expectation is already fulfilled
+	assert.False(t, verifier.verify(df.Line{Source: postgresSource, Text: statement}, "insert"))
+
+	report := verifier.ReportResults()
+	assert.Equal(t, []df.ChannelReport{
+		{Name: "mysql", Expectations: 1, Fulfilled: 0},
+		{Name: "postgres", Expectations: 1, Fulfilled: 1},
+	}, report.Channels)
+}
+
+func TestVerifyOrdering(t *testing.T) {
+	insertJob := "insert into job (description, id) values ('Developer', 4)"
+	updateJob := "update job set description='Tester' where id=4"
+	insertApplication := "insert into application (name, job_id, id) values ('Ralf', 4, 1)"
+	testCases := []struct {
+		desc       string
+		ordering   df.Ordering
+		violations []df.OrderViolation
+	}{
+		{
+			desc:     "no ordering",
+			ordering: df.OrderingNone,
+		},
+		{
+			desc:     "strict ordering",
+			ordering: df.OrderingStrict,
+			violations: []df.OrderViolation{
+				{Uuid: "e2", Statement: df.Expectation{Tokens: df.Tokenize(updateJob)}.Shorten(6), Expected: 2, Actual: 2},
+				{Uuid: "e1", Statement: df.Expectation{Tokens: df.Tokenize(insertJob)}.Shorten(6), Expected: 1, Actual: 3},
+			},
+		},
+		{
+			desc:     "table ordering",
+			ordering: df.OrderingTable,
+			violations: []df.OrderViolation{
+				{Uuid: "e1", Statement: df.Expectation{Tokens: df.Tokenize(insertJob)}.Shorten(6), Expected: 1, Actual: 3},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := df.Config{}
+			c.Channels = []df.Channel{{Patterns: []string{"insert", "update"}}}
+			doneChannel := make(chan struct{})
+			stoppedChannel := make(chan struct{})
+			databaseLog := mocks.NewMemSQLLog([]string{
+				"2024-04-08T09:39:15.070009Z	 2549 Query	" + insertApplication,
+				"2024-04-08T09:39:15.070009Z	 2549 Query	" + updateJob,
+				"2024-04-08T09:39:15.070009Z	 2549 Query	" + insertJob,
+				"STOP",
+			}, doneChannel)
+			tc := df.Testcase{Name: "create-job", Ordering: tC.ordering, Expectations: []df.Expectation{
+				{Uuid: "e1", Tokens: df.Tokenize(insertJob), Pattern: "insert"},
+				{Uuid: "e2", Tokens: df.Tokenize(updateJob), Pattern: "update"},
+				{Uuid: "e3", Tokens: df.Tokenize(insertApplication), Pattern: "insert"},
+			}}
+			sources := []df.Source{{Channel: c.Channels[0], Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+			repository := &mocks.TestRepository{}
+			verifier := NewVerifier(c, sources, repository, tc, mocks.Timer{}, "create-job")
+			go verifier.Start(doneChannel, stoppedChannel)
+			<-stoppedChannel
+			assert.Len(t, verifier.Testcase().Fulfilled(), 3)
+			assert.Equal(t, tC.violations, verifier.Testcase().OrderViolations)
+			assert.Equal(t, tC.violations, verifier.ReportResults().OutOfOrder)
+		})
+	}
+}
+
+// A single statement out of place is the only order violation, even if it
+// precedes all other statements. Expected refers to the position within the
+// stored test, which includes disabled expectations.
+func TestVerifyOrderViolations(t *testing.T) {
+	statements := []string{
+		"insert into job (id) values (1)",
+		"insert into application (id) values (2)",
+		"insert into skill (id) values (3)",
+		"insert into tag (id) values (4)",
+	}
+	tc := df.Testcase{Name: "create-job", Ordering: df.OrderingStrict, Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize(statements[0]), Pattern: "insert"},
+		{Uuid: "d1", Tokens: df.Tokenize("select * from job"), Pattern: "select", Disabled: true},
+		{Uuid: "e2", Tokens: df.Tokenize(statements[1]), Pattern: "insert"},
+		{Uuid: "e3", Tokens: df.Tokenize(statements[2]), Pattern: "insert"},
+		{Uuid: "e4", Tokens: df.Tokenize(statements[3]), Pattern: "insert"},
+	}}
+	c := df.Config{}
+	c.Channels = []df.Channel{{Patterns: []string{"insert", "select"}}}
+	doneChannel := make(chan struct{})
+	stoppedChannel := make(chan struct{})
+	var lines []string
+	for _, s := range []string{statements[3], statements[0], statements[1], statements[2]} {
+		lines = append(lines, "2024-04-08T09:39:15.070009Z	 2549 Query	"+s)
+	}
+	databaseLog := mocks.NewMemSQLLog(append(lines, "STOP"), doneChannel)
+	sources := []df.Source{{Channel: c.Channels[0], Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+	verifier := NewVerifier(c, sources, &mocks.TestRepository{}, tc, mocks.Timer{}, tc.Name)
+	go verifier.Start(doneChannel, stoppedChannel)
+	<-stoppedChannel
+
+	assert.Len(t, verifier.Testcase().Fulfilled(), 4)
+	assert.Equal(t, []df.OrderViolation{
+		{Uuid: "e4", Statement: df.Expectation{Tokens: df.Tokenize(statements[3])}.Shorten(6), Expected: 5, Actual: 1},
+	}, verifier.Testcase().OrderViolations)
+}
+
+func TestVerifyCorrelations(t *testing.T) {
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("insert into job (description, id) values ('Developer', 4)"), Pattern: "insert"},
+		{Uuid: "e2", Tokens: df.Tokenize("update job set description='Tester' where id=4"), Pattern: "update"},
+	}}
+
+	// first verification learns the correlation between the generated id and the update
+	verifier := runVerifier(tc, []string{"insert", "update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (description, id) values ('Developer', 5)",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set description='Tester' where id=5",
+	})
+	expected := []df.Correlation{{Source: df.TokenRef{Uuid: "e1", Token: 7}, Target: df.TokenRef{Uuid: "e2", Token: 5}}}
+	assert.Equal(t, expected, verifier.Testcase().Correlations)
+	assert.Empty(t, verifier.Testcase().BrokenCorrelations)
+
+	// succeeding verification keeps the data flow
+	verifier = runVerifier(verifier.Testcase(), []string{"insert", "update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (description, id) values ('Developer', 6)",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set description='Tester' where id=6",
+	})
+	assert.Equal(t, expected, verifier.Testcase().Correlations)
+	assert.Empty(t, verifier.Testcase().BrokenCorrelations)
+
+	// succeeding verification breaks the data flow
+	verifier = runVerifier(verifier.Testcase(), []string{"insert", "update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (description, id) values ('Developer', 7)",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set description='Tester' where id=17",
+	})
+	assert.Len(t, verifier.Testcase().Fulfilled(), 2)
+	assert.Len(t, verifier.Testcase().BrokenCorrelations, 1)
+	assert.Equal(t, "7", verifier.Testcase().BrokenCorrelations[0].SourceValue)
+	assert.Equal(t, "17", verifier.Testcase().BrokenCorrelations[0].TargetValue)
+	assert.Equal(t, verifier.Testcase().BrokenCorrelations, verifier.ReportResults().BrokenDataFlow)
+}
+
+// runVerifier runs a complete verification of tc against logs and returns the
+// finished verifier.
+func runVerifier(tc df.Testcase, patterns []string, logs []string) *Verifier {
+	c := df.Config{}
+	c.Channels = []df.Channel{{Patterns: patterns}}
+	doneChannel := make(chan struct{})
+	stoppedChannel := make(chan struct{})
+	databaseLog := mocks.NewMemSQLLog(append(logs, "STOP"), doneChannel)
+	sources := []df.Source{{Channel: c.Channels[0], Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+	verifier := NewVerifier(c, sources, &mocks.TestRepository{}, tc, mocks.Timer{}, tc.Name)
+	go verifier.Start(doneChannel, stoppedChannel)
+	<-stoppedChannel
+	return verifier
+}
+
+func TestVerifyLearnsIgnoreRules(t *testing.T) {
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("update job set published_timestamp='2024-04-17 15:55:58' where id=3"), Pattern: "update"},
+	}}
+	verifier := runVerifier(tc, []string{"update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set published_timestamp='2024-04-18 10:00:00' where id=4",
+	})
+	e := verifier.Testcase().Expectations[0]
+	assert.True(t, e.Fulfilled)
+	assert.Equal(t, []df.IgnoreRule{{Index: 3, Shape: df.ShapeTimestamp}, {Index: 5, Shape: df.ShapeInteger}}, e.IgnoreRules)
+
+	// a timestamp that becomes null doesn't fulfill the expectation anymore
+	verifier = runVerifier(verifier.Testcase(), []string{"update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set published_timestamp=null where id=5",
+	})
+	assert.False(t, verifier.Testcase().Expectations[0].Fulfilled)
+}
+
+func TestVerifyChoosesBestMatch(t *testing.T) {
+	tc := df.Testcase{Name: "create-jobs", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("insert into job (id, name, salary) values (1, 'a', 10)"), Pattern: "insert"},
+		{Uuid: "e2", Tokens: df.Tokenize("insert into job (id, name, salary) values (2, 'b', 20)"), Pattern: "insert"},
+	}}
+
+	// the first statement is closest to e2 but assigning it to e2 leaves e1 for
+	// the second statement which differs from e1 in every value
+	verifier := runVerifier(tc, []string{"insert"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (id, name, salary) values (2, 'a', 20)",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (id, name, salary) values (2, 'b', 99)",
+	})
+
+	expectations := verifier.Testcase().Expectations
+	assert.True(t, expectations[0].Fulfilled)
+	assert.True(t, expectations[1].Fulfilled)
+	assert.Equal(t, []int{7, 9}, expectations[0].IgnoreDiffs)
+	assert.Equal(t, []int{9}, expectations[1].IgnoreDiffs)
+	assert.Empty(t, verifier.Testcase().AdditionalExpectations)
+}
+
+// The number of differing tokens outweighs the distance of the statements to
+// their recorded positions. Keeping each statement at its position costs six
+// differing tokens, the best assignment only four.
+func TestVerifyPrefersFewerDiffsToRecordedOrder(t *testing.T) {
+	statement := func(values string) string {
+		return "select * from job where a=" + values[0:1] + " and b=" + values[1:2] + " and c=" + values[2:3]
+	}
+	var expectations []df.Expectation
+	for i, values := range []string{"010", "001", "111", "111", "000"} {
+		expectations = append(expectations, df.Expectation{Uuid: fmt.Sprintf("e%d", i+1), Tokens: df.Tokenize(statement(values)), Pattern: "select"})
+	}
+	var lines []string
+	for _, values := range []string{"110", "100", "110", "101", "010"} {
+		lines = append(lines, "2024-04-08T09:39:15.070009Z	 2549 Query	"+statement(values))
+	}
+
+	verifier := runVerifier(df.Testcase{Name: "list-jobs", Expectations: expectations}, []string{"select"}, lines)
+
+	diffs := 0
+	for _, e := range verifier.Testcase().Expectations {
+		assert.True(t, e.Fulfilled)
+		diffs += len(e.IgnoreDiffs)
+	}
+	assert.Equal(t, 4, diffs)
+}
+
+func TestVerifyForbidden(t *testing.T) {
+	tc := df.Testcase{Name: "create-job",
+		Expectations: []df.Expectation{
+			{Uuid: "e1", Tokens: df.Tokenize("insert into job (id, name) values (1, 'a')"), Pattern: "insert"},
+		},
+		Forbidden: []df.Forbidden{{Pattern: "delete from job"}, {Pattern: "select!from application", Channel: "other"}},
+	}
+	verifier := runVerifier(tc, []string{"insert"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (id, name) values (2, 'a')",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	select * from job",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	delete from job where id=2",
+	})
+
+	assert.True(t, verifier.Testcase().Expectations[0].Fulfilled)
+	assert.Equal(t, []df.ForbiddenViolation{
+		{Forbidden: df.Forbidden{Pattern: "delete from job"}, Statement: "delete from job where id=2"},
+	}, verifier.Testcase().ForbiddenViolations)
+
+	report := verifier.ReportResults()
+	assert.Len(t, report.Forbidden, 1)
+	assert.False(t, report.Passed)
+}
+
+func TestVerifyCardinality(t *testing.T) {
+	tc := df.Testcase{Name: "list-jobs", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("select * from application where job_id=1"), Pattern: "select", Count: 2},
+	}}
+	statements := []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	select * from application where job_id=4",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	select * from application where job_id=5",
+	}
+
+	verifier := runVerifier(tc, []string{"select"}, statements)
+	e := verifier.Testcase().Expectations[0]
+	assert.True(t, e.Fulfilled)
+	assert.Equal(t, 2, e.Occurrences)
+	assert.Equal(t, 1, e.Verified)
+
+	verifier = runVerifier(verifier.Testcase(), []string{"select"}, append(statements, statements[0]))
+	e = verifier.Testcase().Expectations[0]
+	assert.False(t, e.Fulfilled)
+	assert.Equal(t, "expected 2, got 3", e.Cardinality())
+	assert.Empty(t, verifier.Testcase().AdditionalExpectations)
+	assert.Contains(t, verifier.ReportResults().String(), "(expected 2, got 3)")
+}
+
+func TestVerifyAddsRun(t *testing.T) {
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("insert into job (id, name) values (1, 'a')"), Pattern: "insert"},
+		{Uuid: "e2", Tokens: df.Tokenize("update job set name='b' where id=1"), Pattern: "update"},
+	}}
+	verifier := runVerifier(tc, []string{"insert", "update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (id, name) values (2, 'a')",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set name='b', title='c' where id=2",
+	})
+	runs := verifier.Testcase().Runs
+	assert.Len(t, runs, 1)
+	assert.Equal(t, []string{"e1"}, runs[0].Fulfilled)
+	assert.Equal(t, []string{"e2"}, runs[0].Unfulfilled)
+	assert.False(t, runs[0].Passed)
+	assert.False(t, runs[0].End.Before(runs[0].Start))
+}
+
+func TestVerifyFindsClosestStatement(t *testing.T) {
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("update job set name='a' where id=1"), Pattern: "update", Verified: 1},
+	}}
+	verifier := runVerifier(tc, []string{"update"}, []string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set name='a', title='b', salary=3 where id=1",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	update job set title='b' where id=1",
+	})
+	e := verifier.Testcase().Expectations[0]
+	assert.False(t, e.Fulfilled)
+	assert.Equal(t, "update job set title=b where id=1", e.Closest)
+}
+
+func TestVerifySkipsDisabledExpectations(t *testing.T) {
+	tc := df.Testcase{Name: "create-job", Expectations: []df.Expectation{
+		{Uuid: "e1", Tokens: df.Tokenize("insert into job (id, name) values (1, 'a')"), Pattern: "insert"},
+		{Uuid: "e2", Tokens: df.Tokenize("select * from job"), Pattern: "select", Disabled: true},
+		{Uuid: "e3", Tokens: df.Tokenize("update job set name='b' where id=1"), Pattern: "update"},
+	}}
+	c := df.Config{}
+	c.Channels = []df.Channel{{Patterns: []string{"insert", "select", "update"}}}
+	c.Expectations.ReportAdditional = true
+	doneChannel := make(chan struct{})
+	stoppedChannel := make(chan struct{})
+	databaseLog := mocks.NewMemSQLLog([]string{
+		"2024-04-08T09:39:15.070009Z	 2549 Query	insert into job (id, name) values (1, 'a')",
+		"2024-04-08T09:39:15.070009Z	 2549 Query	select * from job",
+		"STOP",
+	}, doneChannel)
+	sources := []df.Source{{Channel: c.Channels[0], Log: databaseLog, Tokenizer: mysql.Tokenizer{}}}
+	repository := &mocks.TestRepository{}
+	verifier := NewVerifier(c, sources, repository, tc, mocks.Timer{}, tc.Name)
+	go verifier.Start(doneChannel, stoppedChannel)
+	<-stoppedChannel
+
+	report := verifier.ReportResults()
+	assert.Equal(t, 2, report.Expectations)
+	assert.Equal(t, []string{"select * from job"}, report.AdditionalExpectations)
+	assert.Equal(t, []string{"e1"}, verifier.Testcase().Runs[0].Fulfilled)
+	assert.Equal(t, []string{"e3"}, verifier.Testcase().Runs[0].Unfulfilled)
+
+	// the disabled expectation is written unchanged at its position
+	written := repository.Testcases[0].Expectations
+	assert.Len(t, written, 3)
+	assert.Equal(t, tc.Expectations[1], written[1])
+	assert.Equal(t, "e3", written[2].Uuid)
+}
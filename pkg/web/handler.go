@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -79,6 +81,40 @@ func RegisterHandler(c df.Config) {
 	// remove expectation from test
 	simpleweb.Register("/remove-expectation", RemoveExpectationHandler, "GET")
 
+	// disable or enable expectation
+	simpleweb.Register("/disable-expectation", DisableExpectationHandler, "GET")
+
+	// move expectation up or down
+	simpleweb.Register("/move-expectation", MoveExpectationHandler, "GET")
+
+	// edit pattern and ignored tokens of expectation
+	simpleweb.Register("/edit-expectation", EditExpectationHandler, "POST")
+
+	// promote additional statement to expectation
+	simpleweb.Register("/add-expectation", AddExpectationHandler, "POST")
+
+	// review the last run and accept its statements as new baseline
+	simpleweb.Register("/rebaseline", RebaselineHandler, "GET")
+	simpleweb.Register("/rebaseline", AcceptBaselineHandler, "POST")
+
+	// restore the former baseline
+	simpleweb.Register("/rollback-baseline", RollbackBaselineHandler, "GET")
+
+	// list and diff revisions of test
+	simpleweb.Register("/revisions", RevisionsHandler, "GET")
+
+	// roll back to revision
+	simpleweb.Register("/rollback-revision", RollbackRevisionHandler, "GET")
+
+	// forbid statements in test
+	simpleweb.Register("/forbid", ForbidHandler, "POST")
+
+	// allow formerly forbidden statements in test
+	simpleweb.Register("/allow", AllowHandler, "GET")
+
+	// verification run history
+	simpleweb.Register("/history", HistoryHandler, "GET")
+
 	simpleweb.Register("/noise", NoiseHandler, "GET")
 }
 
@@ -259,7 +295,13 @@ func StartRecording(runner driver.PlaywrightRunner) http.HandlerFunc {
 			simpleweb.RedirectE(w, request, "/", err)
 			return
 		}
-		res, err := Post(fmt.Sprintf("%s/tests/%s/recordings", apiBaseURL, testname))
+		ordering, err := simpleweb.FormValue(request, "ordering")
+		if err != nil {
+			simpleweb.RedirectE(w, request, "/", err)
+			return
+		}
+		collapse := request.FormValue("collapse") == "on"
+		res, err := Post(fmt.Sprintf("%s/tests/%s/recordings?ordering=%s&collapse=%t", apiBaseURL, testname, ordering, collapse))
 		if err != nil {
 			simpleweb.RedirectE(w, request, "/", err)
 			return
@@ -294,11 +336,15 @@ func StopRecording(w http.ResponseWriter, request *http.Request) {
 		simpleweb.RedirectE(w, request, "/", err)
 		return
 	}
-	_, err = client.Do(r)
+	response, err := client.Do(r)
 	if err != nil {
 		simpleweb.RedirectE(w, request, "/", err)
 		return
 	}
+	if err := responseError(response); err != nil {
+		simpleweb.RedirectE(w, request, "/", fmt.Errorf("recording of '%s' failed: %w", testname, err))
+		return
+	}
 
 	http.Redirect(w, request, fmt.Sprintf("/run?testname=%s.json", testname), http.StatusSeeOther)
 }
@@ -348,7 +394,7 @@ func StartVerification(runner driver.PlaywrightRunner) http.HandlerFunc {
 		// start test via driver if configured
 		if config.UIDriver == "Playwright" {
 			if runner.Exists(testname) {
-				go runner.Run(testname)
+				go runDriver(runner, testname)
 			} else {
 				d := fmt.Sprintf("%s/%s", config.Playwright.BaseDir, config.Playwright.TestDir)
 				n := runner.ToPlaywright(testname)
@@ -376,6 +422,25 @@ func StartVerification(runner driver.PlaywrightRunner) http.HandlerFunc {
 	}
 }
 
+// runDriver runs the playwright test of testname and reports its outcome to the
+// running verification.
+func runDriver(runner driver.PlaywrightRunner, testname string) {
+	outcome := df.DriverPassed
+	if err := runner.Run(testname); err != nil {
+		outcome = df.DriverFailed
+	}
+	url := fmt.Sprintf("%s/tests/%s/verifications/driver", apiBaseURL, testname)
+	res, err := SendJSON(http.MethodPut, url, struct {
+		Outcome string `json:"outcome"`
+	}{Outcome: outcome})
+	if err == nil {
+		err = responseError(res)
+	}
+	if err != nil {
+		log.Errorf("Error reporting driver outcome: %v", err)
+	}
+}
+
 // ProgressVerificationHandler renders the partial progress-verification.html
 // that shows the progress of the current verification run.
 func ProgressVerificationHandler(w http.ResponseWriter, r *http.Request) {
@@ -416,10 +481,8 @@ func StopHandler(w http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	statusOK := response.StatusCode >= 200 && response.StatusCode < 300
-	if !statusOK {
-		simpleweb.Error("Something went wrong. Please reload page and click on the test to show test results.")
-		http.Redirect(w, request, "/", http.StatusSeeOther)
+	if err := responseError(response); err != nil {
+		simpleweb.RedirectE(w, request, "/", fmt.Errorf("verification of '%s' failed: %w", testname, err))
 		return
 	}
 	http.Redirect(w, request, "/show?testname="+testname, http.StatusSeeOther)
@@ -439,7 +502,315 @@ func NoiseHandler(w http.ResponseWriter, r *http.Request) {
 	}{Title: "Noise Sample: " + testname, Noise: buildNoiseData(tc)})
 }
 
-func RemoveExpectationHandler(http.ResponseWriter, *http.Request) {
+// ForbidHandler forbids the statements given by form["pattern"] and the
+// optional form["channel"] in test form["testname"].
+func ForbidHandler(w http.ResponseWriter, request *http.Request) {
+	testname, err := simpleweb.FormValue(request, "testname")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/", err)
+		return
+	}
+	pattern, err := simpleweb.FormValue(request, "pattern")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	f := df.Forbidden{Pattern: pattern, Channel: request.FormValue("channel")}
+	res, err := SendEdit(http.MethodPost, fmt.Sprintf("%s/tests/%s/forbidden", apiBaseURL, testname), f, request.FormValue("revision"))
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	if err := responseError(res); err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	http.Redirect(w, request, "/show?testname="+testname, http.StatusSeeOther)
+}
+
+// AllowHandler removes the forbidden statements given by the query params
+// "pattern" and "channel" from test "testname".
+func AllowHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	params := url.Values{}
+	params.Set("pattern", request.URL.Query().Get("pattern"))
+	params.Set("channel", request.URL.Query().Get("channel"))
+	url := fmt.Sprintf("%s/tests/%s/forbidden?%s", apiBaseURL, testname, params.Encode())
+	res, err := SendEdit(http.MethodDelete, url, nil, request.URL.Query().Get("revision"))
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	if err := responseError(res); err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	http.Redirect(w, request, "/show?testname="+testname, http.StatusSeeOther)
+}
+
+// HistoryHandler renders the verification run history of test "testname"
+// together with the trend and flakiness of each expectation.
+func HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	testname := trimSuffix(r.URL.Query().Get("testname"))
+	var history struct {
+		Runs         []df.Run                `json:"runs"`
+		Expectations []df.ExpectationHistory `json:"expectations"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/tests/%s/runs", apiBaseURL, testname), &history); err != nil {
+		simpleweb.RedirectE(w, r, "/", err)
+		return
+	}
+
+	// show latest runs first
+	sort.SliceStable(history.Runs, func(i, j int) bool { return history.Runs[i].Start.After(history.Runs[j].Start) })
+
+	passed := 0
+	for _, run := range history.Runs {
+		if run.Passed {
+			passed++
+		}
+	}
+	simpleweb.Render("templates/history.html", w, struct {
+		Title        string
+		Testname     string
+		Passed       int
+		Runs         []df.Run
+		Expectations []df.ExpectationHistory
+	}{Title: "History: " + testname, Testname: testname, Passed: passed, Runs: history.Runs, Expectations: history.Expectations})
+}
+
+// RemoveExpectationHandler removes the expectation given by the query param
+// "expectation" from test "testname".
+func RemoveExpectationHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	url := fmt.Sprintf("%s/tests/%s/expectations/%s", apiBaseURL, testname, request.URL.Query().Get("expectation"))
+	editExpectations(w, request, testname, http.MethodDelete, url, nil)
+}
+
+// DisableExpectationHandler disables the expectation given by the query param
+// "expectation" of test "testname" or enables it again if the query param
+// "disabled" is false.
+func DisableExpectationHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	disabled := request.URL.Query().Get("disabled") != "false"
+	url := fmt.Sprintf("%s/tests/%s/expectations/%s", apiBaseURL, testname, request.URL.Query().Get("expectation"))
+	editExpectations(w, request, testname, http.MethodPatch, url, df.ExpectationEdit{Disabled: &disabled})
+}
+
+// MoveExpectationHandler swaps the expectation given by the query param
+// "expectation" of test "testname" with its predecessor or, if the query param
+// "direction" is "down", with its successor.
+func MoveExpectationHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	tc, err := getTestcase(fmt.Sprintf("%s/tests/%s", apiBaseURL, testname))
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	var order struct {
+		Uuids []string `json:"uuids"`
+	}
+	for _, e := range tc.Expectations {
+		order.Uuids = append(order.Uuids, e.Uuid)
+	}
+	for i, uuid := range order.Uuids {
+		if uuid != request.URL.Query().Get("expectation") {
+			continue
+		}
+		j := i - 1
+		if request.URL.Query().Get("direction") == "down" {
+			j = i + 1
+		}
+		if j >= 0 && j < len(order.Uuids) {
+			order.Uuids[i], order.Uuids[j] = order.Uuids[j], order.Uuids[i]
+		}
+		break
+	}
+	url := fmt.Sprintf("%s/tests/%s/expectations/order", apiBaseURL, testname)
+	editExpectations(w, request, testname, http.MethodPut, url, order)
+}
+
+// EditExpectationHandler sets form["pattern"] and the comma separated token
+// indizes form["ignoreDiffs"] of the expectation form["expectation"] of test
+// form["testname"].
+func EditExpectationHandler(w http.ResponseWriter, request *http.Request) {
+	testname, err := simpleweb.FormValue(request, "testname")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/", err)
+		return
+	}
+	pattern, err := simpleweb.FormValue(request, "pattern")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	ignoreDiffs := []int{}
+	for _, s := range strings.Split(request.FormValue("ignoreDiffs"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			simpleweb.RedirectE(w, request, "/show?testname="+testname, fmt.Errorf("invalid token index '%s'", s))
+			return
+		}
+		ignoreDiffs = append(ignoreDiffs, i)
+	}
+	url := fmt.Sprintf("%s/tests/%s/expectations/%s", apiBaseURL, testname, request.FormValue("expectation"))
+	editExpectations(w, request, testname, http.MethodPatch, url, df.ExpectationEdit{Pattern: &pattern, IgnoreDiffs: &ignoreDiffs})
+}
+
+// AddExpectationHandler promotes the additional statement given by the tokens
+// form["token"], form["pattern"] and form["channel"] to an expectation of test
+// form["testname"].
+func AddExpectationHandler(w http.ResponseWriter, request *http.Request) {
+	testname, err := simpleweb.FormValue(request, "testname")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/", err)
+		return
+	}
+	e := df.Expectation{
+		Tokens:  request.Form["token"],
+		Pattern: request.FormValue("pattern"),
+		Channel: request.FormValue("channel"),
+	}
+	url := fmt.Sprintf("%s/tests/%s/expectations", apiBaseURL, testname)
+	editExpectations(w, request, testname, http.MethodPost, url, e)
+}
+
+// RebaselineHandler renders the unfulfilled expectations and the additional
+// statements of the last verification run of test "testname" side by side.
+// An unfulfilled expectation is preselected for replacement by the additional
+// statement that came closest to it.
+func RebaselineHandler(w http.ResponseWriter, r *http.Request) {
+	testname := r.URL.Query().Get("testname")
+	tc, err := getTestcase(fmt.Sprintf("%s/tests/%s", apiBaseURL, testname))
+	if err != nil {
+		simpleweb.RedirectE(w, r, "/", err)
+		return
+	}
+	simpleweb.Render("templates/rebaseline.html", w, struct {
+		Title    string
+		Testcase df.Testcase
+	}{Title: "Re-baseline: " + testname, Testcase: tc})
+}
+
+// AcceptBaselineHandler saves a new baseline of test form["testname"]. The
+// form field "action-<uuid>" of each unfulfilled expectation either keeps,
+// removes or replaces it by the additional statement with the given index,
+// the form fields "add" list the indizes of additional statements to add. The
+// indizes refer to the additional statements of form["revision"].
+func AcceptBaselineHandler(w http.ResponseWriter, request *http.Request) {
+	testname, err := simpleweb.FormValue(request, "testname")
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/", err)
+		return
+	}
+	revision, err := strconv.Atoi(request.FormValue("revision"))
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/rebaseline?testname="+testname, fmt.Errorf("invalid revision '%s'", request.FormValue("revision")))
+		return
+	}
+	rebaseline := df.Rebaseline{Revision: revision}
+	for key, values := range request.PostForm {
+		uuid, ok := strings.CutPrefix(key, "action-")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		switch action := values[0]; action {
+		case "keep":
+		case "remove":
+			rebaseline.Remove = append(rebaseline.Remove, uuid)
+		default:
+			i, err := strconv.Atoi(action)
+			if err != nil {
+				simpleweb.RedirectE(w, request, "/rebaseline?testname="+testname, fmt.Errorf("invalid action '%s'", action))
+				return
+			}
+			rebaseline.Replace = append(rebaseline.Replace, df.Replacement{Uuid: uuid, Additional: i})
+		}
+	}
+	for _, s := range request.PostForm["add"] {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			simpleweb.RedirectE(w, request, "/rebaseline?testname="+testname, fmt.Errorf("invalid statement index '%s'", s))
+			return
+		}
+		rebaseline.Add = append(rebaseline.Add, i)
+	}
+	sort.Ints(rebaseline.Add)
+	sort.Slice(rebaseline.Replace, func(i, j int) bool { return rebaseline.Replace[i].Additional < rebaseline.Replace[j].Additional })
+	url := fmt.Sprintf("%s/tests/%s/baselines", apiBaseURL, testname)
+	editExpectations(w, request, testname, http.MethodPost, url, rebaseline)
+}
+
+// RollbackBaselineHandler restores the former baseline of test "testname".
+func RollbackBaselineHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	url := fmt.Sprintf("%s/tests/%s/baselines/rollback", apiBaseURL, testname)
+	editExpectations(w, request, testname, http.MethodPost, url, nil)
+}
+
+// RevisionsHandler renders the revisions of test "testname". The optional
+// query param "diff" selects a revision whose changes up to the current
+// revision are shown.
+func RevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	testname := trimSuffix(r.URL.Query().Get("testname"))
+	tc, err := getTestcase(fmt.Sprintf("%s/tests/%s", apiBaseURL, testname))
+	if err != nil {
+		simpleweb.RedirectE(w, r, "/", err)
+		return
+	}
+	var revisions struct {
+		Revisions []df.Revision `json:"revisions"`
+	}
+	if err := getJSON(fmt.Sprintf("%s/tests/%s/revisions", apiBaseURL, testname), &revisions); err != nil {
+		simpleweb.RedirectE(w, r, "/show?testname="+testname, err)
+		return
+	}
+	// show latest revisions first
+	sort.SliceStable(revisions.Revisions, func(i, j int) bool { return revisions.Revisions[i].Number > revisions.Revisions[j].Number })
+
+	var diff *df.RevisionDiff
+	if from := r.URL.Query().Get("diff"); from != "" {
+		diff = &df.RevisionDiff{}
+		if err := getJSON(fmt.Sprintf("%s/tests/%s/revisions/%s/diff", apiBaseURL, testname, url.PathEscape(from)), diff); err != nil {
+			simpleweb.RedirectE(w, r, "/revisions?testname="+testname, err)
+			return
+		}
+	}
+	simpleweb.Render("templates/revisions.html", w, struct {
+		Title     string
+		Testname  string
+		Current   int
+		Revisions []df.Revision
+		Diff      *df.RevisionDiff
+	}{Title: "Revisions: " + testname, Testname: testname, Current: tc.Revision, Revisions: revisions.Revisions, Diff: diff})
+}
+
+// RollbackRevisionHandler restores the revision given by the query param "to"
+// of test "testname".
+func RollbackRevisionHandler(w http.ResponseWriter, request *http.Request) {
+	testname := request.URL.Query().Get("testname")
+	url := fmt.Sprintf("%s/tests/%s/revisions/%s/rollback", apiBaseURL, testname, url.PathEscape(request.URL.Query().Get("to")))
+	editExpectations(w, request, testname, http.MethodPost, url, nil)
+}
+
+// editExpectations sends the json-encoded v to the expectations endpoint url
+// of the API and redirects to the show page of testname. A nil v is sent
+// without body. The request value "revision", the revision of the test the
+// user has seen, is sent as precondition.
+func editExpectations(w http.ResponseWriter, request *http.Request, testname string, method string, url string, v any) {
+	res, err := SendEdit(method, url, v, request.FormValue("revision"))
+	if err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	if err := responseError(res); err != nil {
+		simpleweb.RedirectE(w, request, "/show?testname="+testname, err)
+		return
+	}
+	http.Redirect(w, request, "/show?testname="+testname, http.StatusSeeOther)
 }
 
 type noise struct {
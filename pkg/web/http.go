@@ -1,7 +1,14 @@
 package web
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 func Post(url string) (*http.Response, error) {
@@ -15,3 +22,77 @@ func Post(url string) (*http.Response, error) {
 	}
 	return res, nil
 }
+
+// PostJSON posts the json-encoded v to url.
+func PostJSON(url string, v any) (*http.Response, error) {
+	return SendJSON(http.MethodPost, url, v)
+}
+
+// SendJSON sends the json-encoded v to url using the given http method.
+func SendJSON(method string, url string, v any) (*http.Response, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequest(method, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	return client.Do(r)
+}
+
+// SendEdit sends the json-encoded v, or an empty body if v is nil, to url
+// using the given http method. A non-empty revision is sent as If-Match header,
+// thus the backend rejects the edit if the test has been changed since the user
+// loaded this revision.
+func SendEdit(method string, url string, v any, revision string) (*http.Response, error) {
+	var body io.Reader = http.NoBody
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	r, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if revision != "" {
+		r.Header.Set("If-Match", strconv.Quote(revision))
+	}
+	return client.Do(r)
+}
+
+// responseError returns an error containing the body of res if res doesn't
+// report success, e.g. the reason why the backend failed to stop a run.
+func responseError(res *http.Response) error {
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(res.Body)
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(res.Body)
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("HTTP Status: %d", res.StatusCode)
+}
+
+// getJSON gets url and decodes the json-encoded response into v.
+func getJSON(url string, v any) error {
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return responseError(res)
+	}
+	defer func() { _ = res.Body.Close() }()
+	return json.NewDecoder(res.Body).Decode(v)
+}